@@ -0,0 +1,105 @@
+package aoc2019
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AnswerStatus is the result of checking an answer against the answers
+// file of its input
+type AnswerStatus int
+
+const (
+	// AnswerUnverified is reported when no answer is known for the part
+	AnswerUnverified AnswerStatus = iota
+	AnswerCorrect
+	AnswerWrong
+)
+
+func (a AnswerStatus) String() string {
+	switch a {
+	case AnswerCorrect:
+		return "correct"
+	case AnswerWrong:
+		return "wrong"
+	}
+	return "unverified"
+}
+
+// Answers contains the known answers of one input keyed by day and part
+// as formatted by Answer.String
+type Answers map[int]map[int]string
+
+// AnswersFile returns the name of the answers file belonging to the
+// input file: dayXX_input.txt has its answers in dayXX_answers.json,
+// other inputs in a .answers.json file next to them
+func AnswersFile(inFile string) string {
+	if strings.HasSuffix(inFile, "_input.txt") {
+		return strings.TrimSuffix(inFile, "_input.txt") + "_answers.json"
+	}
+	return strings.TrimSuffix(inFile, filepath.Ext(inFile)) + ".answers.json"
+}
+
+// LoadAnswers reads an answers file, a missing file yields no answers
+func LoadAnswers(fileName string) (Answers, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return Answers{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read answers file")
+	}
+
+	var a = Answers{}
+	return a, errors.Wrap(json.Unmarshal(raw, &a), "Unable to parse answers file")
+}
+
+// Save writes the answers into the file
+func (a Answers) Save(fileName string) error {
+	raw, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Unable to encode answers")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(fileName, append(raw, '\n'), 0644), "Unable to write answers file")
+}
+
+// Get returns the known answer of the part
+func (a Answers) Get(day, part int) (string, bool) {
+	s, ok := a[day][part]
+	return s, ok
+}
+
+// IsImage reports whether the known answer of the part is an image
+// drawn as ASCII art: its letters are submitted as text and cannot be
+// compared with it
+func (a Answers) IsImage(day, part int) bool {
+	s, ok := a.Get(day, part)
+	return ok && strings.Contains(s, "\n")
+}
+
+// Set stores the answer of the part
+func (a Answers) Set(day, part int, answer Answer) {
+	if a[day] == nil {
+		a[day] = map[int]string{}
+	}
+	a[day][part] = answer.String()
+}
+
+// Check compares the answer with the known answer of the part
+func (a Answers) Check(day, part int, answer Answer) AnswerStatus {
+	exp, ok := a.Get(day, part)
+	switch {
+	case !ok:
+		return AnswerUnverified
+	case exp == answer.String():
+		return AnswerCorrect
+	default:
+		return AnswerWrong
+	}
+}
@@ -0,0 +1,87 @@
+package aoc2019
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnswersFile(t *testing.T) {
+	for in, exp := range map[string]string{
+		"day01_input.txt":     "day01_answers.json",
+		"inputs/day14.txt":    "inputs/day14.answers.json",
+		"/tmp/aoc/day14_part": "/tmp/aoc/day14_part.answers.json",
+	} {
+		if f := AnswersFile(in); f != exp {
+			t.Errorf("Unexpected answers file for %q: exp=%q got=%q", in, exp, f)
+		}
+	}
+}
+
+func TestAnswers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aoc2019-answers-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var fileName = filepath.Join(dir, "answers.json")
+
+	answers, err := LoadAnswers(fileName)
+	if err != nil || len(answers) != 0 {
+		t.Fatalf("Missing answers file did not yield empty answers: %v %s", answers, err)
+	}
+
+	answers.Set(16, 1, TextAnswer("01234567"))
+	answers.Set(16, 2, TextAnswer("76543210"))
+	answers.Set(3, 1, NumberAnswer(42))
+
+	if err = answers.Save(fileName); err != nil {
+		t.Fatalf("Unable to save answers: %s", err)
+	}
+
+	if answers, err = LoadAnswers(fileName); err != nil {
+		t.Fatalf("Unable to load answers: %s", err)
+	}
+
+	for _, tc := range []struct {
+		Day, Part int
+		Answer    Answer
+		Status    AnswerStatus
+	}{
+		{16, 1, TextAnswer("01234567"), AnswerCorrect},
+		{16, 2, TextAnswer("01234567"), AnswerWrong},
+		{3, 1, NumberAnswer(42), AnswerCorrect},
+		{3, 1, NumberAnswer(43), AnswerWrong},
+		{3, 2, NumberAnswer(42), AnswerUnverified},
+		{4, 1, NumberAnswer(42), AnswerUnverified},
+	} {
+		if s := answers.Check(tc.Day, tc.Part, tc.Answer); s != tc.Status {
+			t.Errorf("Unexpected status for day %d part %d answer %s: exp=%s got=%s", tc.Day, tc.Part, tc.Answer, tc.Status, s)
+		}
+	}
+
+	if err = ioutil.WriteFile(fileName, []byte("{"), 0644); err != nil {
+		t.Fatalf("Unable to write answers: %s", err)
+	}
+
+	if _, err = LoadAnswers(fileName); err == nil {
+		t.Error("Loading invalid answers did not fail")
+	}
+}
+
+func TestAnswersKnownForAllDays(t *testing.T) {
+	for _, day := range SolvedDays() {
+		answers, err := LoadAnswers(AnswersFile(InputFile(day)))
+		if err != nil {
+			t.Fatalf("Unable to load answers of day %d: %s", day, err)
+		}
+
+		for part := 1; part <= 2; part++ {
+			if _, ok := answers.Get(day, part); !ok {
+				t.Errorf("No answer known for day %d part %d", day, part)
+			}
+		}
+	}
+}
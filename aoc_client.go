@@ -0,0 +1,168 @@
+package aoc2019
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	aocDefaultBaseURL     = "https://adventofcode.com"
+	aocDefaultMinInterval = 5 * time.Second
+	aocDefaultUserAgent   = "github.com/Luzifer/aoc2019 (puzzle input and answer client)"
+	aocYear               = 2019
+)
+
+// AOCClient talks to the Advent of Code website using the session token
+// of a logged in user. Requests are spaced by at least MinInterval.
+type AOCClient struct {
+	// File submitted answers and their results are recorded in
+	AttemptsFile string
+	BaseURL      string
+	HTTPClient   *http.Client
+	// Directory downloaded inputs are cached in as dayXX_input.txt
+	InputDir    string
+	MinInterval time.Duration
+	Session     string
+	UserAgent   string
+
+	fetched     map[int]bool
+	fetchLock   sync.Mutex
+	lastRequest time.Time
+	lock        sync.Mutex
+}
+
+// NewAOCClient creates a client with the default settings storing the
+// inputs in the current directory
+func NewAOCClient(session string) *AOCClient {
+	return &AOCClient{
+		AttemptsFile: "aoc_attempts.json",
+		BaseURL:      aocDefaultBaseURL,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		InputDir:     ".",
+		MinInterval:  aocDefaultMinInterval,
+		Session:      session,
+		UserAgent:    aocDefaultUserAgent,
+	}
+}
+
+// LoadAOCSession reads the session token from the AOC_SESSION
+// environment variable or the file named in AOC_SESSION_FILE, defaulting
+// to ~/.config/aoc/session
+func LoadAOCSession() (string, error) {
+	if s := strings.TrimSpace(os.Getenv("AOC_SESSION")); s != "" {
+		return s, nil
+	}
+
+	fileName := os.Getenv("AOC_SESSION_FILE")
+	if fileName == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "Unable to determine home directory")
+		}
+		fileName = filepath.Join(home, ".config", "aoc", "session")
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to read session file (set AOC_SESSION or AOC_SESSION_FILE)")
+	}
+
+	s := strings.TrimSpace(string(raw))
+	if s == "" {
+		return "", errors.Errorf("Session file %s is empty", fileName)
+	}
+	return s, nil
+}
+
+// FetchInput returns the puzzle input of the day. Inputs already in
+// the input directory are never downloaded again, neither are inputs
+// this client downloaded before.
+func (a *AOCClient) FetchInput(day int) ([]byte, error) {
+	if day < 1 || day > 25 {
+		return nil, errors.Errorf("Invalid day %d", day)
+	}
+
+	// Concurrent fetches of the same day must not both download it
+	a.fetchLock.Lock()
+	defer a.fetchLock.Unlock()
+
+	var fileName = filepath.Join(a.InputDir, InputFile(day))
+
+	raw, err := ioutil.ReadFile(fileName)
+	switch {
+	case err == nil:
+		return raw, nil
+	case !os.IsNotExist(err):
+		return nil, errors.Wrap(err, "Unable to read cached input")
+	case a.fetched[day]:
+		return nil, errors.Errorf("Input for day %d was already downloaded but is missing in %s", day, a.InputDir)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d/day/%d/input", a.BaseURL, aocYear, day), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create request")
+	}
+
+	resp, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if raw, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, errors.Wrap(err, "Unable to read input")
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errors.Errorf("Input for day %d is not available yet", day)
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError:
+		// Invalid sessions are answered with 400 or 500 depending on the token
+		return nil, errors.Errorf("Input for day %d was refused (HTTP %d), session token might be invalid", day, resp.StatusCode)
+	default:
+		return nil, errors.Errorf("Unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	if a.fetched == nil {
+		a.fetched = map[int]bool{}
+	}
+	a.fetched[day] = true
+	if err = ioutil.WriteFile(fileName, raw, 0644); err != nil {
+		return nil, errors.Wrap(err, "Unable to cache input")
+	}
+
+	return raw, nil
+}
+
+// do sends the request with the session and user agent set after
+// waiting for the rate limit
+func (a *AOCClient) do(req *http.Request) (*http.Response, error) {
+	if a.Session == "" {
+		return nil, errors.New("No session token set")
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if wait := a.MinInterval - time.Since(a.lastRequest); !a.lastRequest.IsZero() && wait > 0 {
+		time.Sleep(wait)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "session", Value: a.Session})
+	req.Header.Set("User-Agent", a.UserAgent)
+
+	resp, err := a.HTTPClient.Do(req)
+	// Measure the interval from the response as the time the request
+	// reached the server is unknown
+	a.lastRequest = time.Now()
+	return resp, errors.Wrap(err, "Request failed")
+}
@@ -0,0 +1,271 @@
+package aoc2019
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAOCServer serves inputs for the days in the map, answers
+// submissions for day 1 part 1 with the solution 42 and for day 8 part 2
+// with EBZUR and counts the requests per path
+type fakeAOCServer struct {
+	*httptest.Server
+
+	inputs      map[int]string
+	lock        sync.Mutex
+	requests    map[string]int
+	submissions []string
+	times       []time.Time
+	tooRecent   bool
+}
+
+func newFakeAOCServer(t *testing.T, session string, inputs map[int]string) *fakeAOCServer {
+	f := &fakeAOCServer{inputs: inputs, requests: map[string]int{}}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.lock.Lock()
+		f.requests[r.URL.Path]++
+		f.times = append(f.times, time.Now())
+		f.lock.Unlock()
+
+		if !strings.Contains(r.UserAgent(), "github.com/Luzifer/aoc2019") {
+			t.Errorf("Request without descriptive user agent: %q", r.UserAgent())
+		}
+
+		if c, err := r.Cookie("session"); err != nil || c.Value != session {
+			http.Error(w, "Puzzle inputs differ by user.  Please log in to get your puzzle input.", http.StatusBadRequest)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/answer") {
+			f.answer(t, w, r)
+			return
+		}
+
+		var day int
+		if _, err := fmt.Sscanf(r.URL.Path, "/2019/day/%d/input", &day); err != nil || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+
+		input, ok := f.inputs[day]
+		if !ok {
+			http.Error(w, "Please don't repeatedly request this endpoint before it unlocks!", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, input)
+	}))
+
+	return f
+}
+
+func (f *fakeAOCServer) count(path string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.requests[path]
+}
+
+func (f *fakeAOCServer) answer(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	// Day 1 part 1 has a numeric answer, day 8 part 2 an image
+	var level = map[string]string{"/2019/day/1/answer": "1", "/2019/day/8/answer": "2"}[r.URL.Path]
+	if r.Method != http.MethodPost || level == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("level") != level {
+		t.Errorf("Unexpected form: %v %v", r.PostForm, err)
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.submissions = append(f.submissions, r.PostForm.Get("answer"))
+
+	var body string
+	switch answer := r.PostForm.Get("answer"); {
+	case f.tooRecent:
+		body = "You gave an answer too recently; you have to wait after submitting an answer before trying again.  You have 30s left to wait."
+	case answer == "42", answer == "EBZUR":
+		body = "That's the right answer!  You are one gold star closer to rescuing Santa."
+	case answer == "50":
+		body = "That's not the right answer; your answer is too high.  Please wait one minute before trying again."
+	case answer == "30":
+		body = "That's not the right answer; your answer is too low."
+	default:
+		body = "That's not the right answer."
+	}
+	fmt.Fprintf(w, aocTestPage, body)
+}
+
+func (f *fakeAOCServer) submitted() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.submissions)
+}
+
+func newTestAOCClient(t *testing.T, server *httptest.Server, session string) (*AOCClient, func()) {
+	dir, err := ioutil.TempDir("", "aoc2019-client-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+
+	c := NewAOCClient(session)
+	c.BaseURL = server.URL
+	c.InputDir = dir
+	c.MinInterval = 0
+
+	return c, func() { os.RemoveAll(dir) }
+}
+
+func TestAOCClientFetchInput(t *testing.T) {
+	server := newFakeAOCServer(t, "s3cr3t", map[int]string{1: "12\n14\n", 2: "1,0,0,99\n"})
+	defer server.Close()
+
+	c, cleanup := newTestAOCClient(t, server.Server, "s3cr3t")
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		raw, err := c.FetchInput(1)
+		if err != nil {
+			t.Fatalf("Unable to fetch input: %s", err)
+		}
+
+		if string(raw) != "12\n14\n" {
+			t.Errorf("Unexpected input: %q", raw)
+		}
+	}
+
+	if n := server.count("/2019/day/1/input"); n != 1 {
+		t.Errorf("Input was downloaded %d times", n)
+	}
+
+	cached, err := ioutil.ReadFile(filepath.Join(c.InputDir, "day01_input.txt"))
+	if err != nil || string(cached) != "12\n14\n" {
+		t.Errorf("Input was not cached: %q %v", cached, err)
+	}
+
+	// A removed cache must not cause a second download
+	os.Remove(filepath.Join(c.InputDir, "day01_input.txt"))
+	if _, err = c.FetchInput(1); err == nil || !strings.Contains(err.Error(), "already downloaded") {
+		t.Errorf("Download of removed input was not refused: %v", err)
+	}
+
+	if n := server.count("/2019/day/1/input"); n != 1 {
+		t.Errorf("Input was downloaded %d times", n)
+	}
+
+	// Existing inputs are never requested
+	if err = ioutil.WriteFile(filepath.Join(c.InputDir, "day03_input.txt"), []byte("R8,U5"), 0644); err != nil {
+		t.Fatalf("Unable to write input: %s", err)
+	}
+
+	if raw, err := c.FetchInput(3); err != nil || string(raw) != "R8,U5" {
+		t.Errorf("Unexpected cached input: %q %v", raw, err)
+	}
+
+	if n := server.count("/2019/day/3/input"); n != 0 {
+		t.Errorf("Cached input was downloaded %d times", n)
+	}
+
+	for day, msg := range map[int]string{
+		0:  "Invalid day",
+		26: "Invalid day",
+		7:  "not available yet",
+	} {
+		if _, err := c.FetchInput(day); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("Unexpected error for day %d: exp=%q got=%v", day, msg, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(c.InputDir, "day07_input.txt")); !os.IsNotExist(err) {
+		t.Errorf("Failed download was cached: %v", err)
+	}
+}
+
+func TestAOCClientSession(t *testing.T) {
+	server := newFakeAOCServer(t, "s3cr3t", map[int]string{1: "12\n"})
+	defer server.Close()
+
+	c, cleanup := newTestAOCClient(t, server.Server, "wrong")
+	defer cleanup()
+
+	if _, err := c.FetchInput(1); err == nil || !strings.Contains(err.Error(), "session token might be invalid") {
+		t.Errorf("Invalid session was not reported: %v", err)
+	}
+
+	c.Session = ""
+	if _, err := c.FetchInput(1); err == nil || !strings.Contains(err.Error(), "No session token") {
+		t.Errorf("Missing session was not reported: %v", err)
+	}
+}
+
+func TestAOCClientRateLimit(t *testing.T) {
+	server := newFakeAOCServer(t, "s3cr3t", map[int]string{1: "1", 2: "2", 3: "3"})
+	defer server.Close()
+
+	c, cleanup := newTestAOCClient(t, server.Server, "s3cr3t")
+	defer cleanup()
+	c.MinInterval = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for day := 1; day <= 3; day++ {
+		wg.Add(1)
+		go func(day int) {
+			defer wg.Done()
+			if _, err := c.FetchInput(day); err != nil {
+				t.Errorf("Unable to fetch input: %s", err)
+			}
+		}(day)
+	}
+	wg.Wait()
+
+	if len(server.times) != 3 {
+		t.Fatalf("Unexpected number of requests: %d", len(server.times))
+	}
+
+	for i := 1; i < len(server.times); i++ {
+		if d := server.times[i].Sub(server.times[i-1]); d < c.MinInterval {
+			t.Errorf("Requests were only %s apart", d)
+		}
+	}
+}
+
+func TestLoadAOCSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aoc2019-session-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("AOC_SESSION", os.Getenv("AOC_SESSION"))
+	defer os.Setenv("AOC_SESSION_FILE", os.Getenv("AOC_SESSION_FILE"))
+
+	var fileName = filepath.Join(dir, "session")
+	if err = ioutil.WriteFile(fileName, []byte("fromfile\n"), 0600); err != nil {
+		t.Fatalf("Unable to write session file: %s", err)
+	}
+
+	os.Setenv("AOC_SESSION", "fromenv")
+	os.Setenv("AOC_SESSION_FILE", fileName)
+	if s, err := LoadAOCSession(); err != nil || s != "fromenv" {
+		t.Errorf("Session was not taken from environment: %q %v", s, err)
+	}
+
+	os.Setenv("AOC_SESSION", "")
+	if s, err := LoadAOCSession(); err != nil || s != "fromfile" {
+		t.Errorf("Session was not taken from file: %q %v", s, err)
+	}
+
+	os.Setenv("AOC_SESSION_FILE", filepath.Join(dir, "missing"))
+	if _, err := LoadAOCSession(); err == nil {
+		t.Error("Missing session file did not fail")
+	}
+}
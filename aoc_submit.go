@@ -0,0 +1,288 @@
+package aoc2019
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SubmitStatus is the verdict of the website on a submitted answer
+type SubmitStatus int
+
+const (
+	// SubmitUnknown is used for responses which could not be parsed
+	SubmitUnknown SubmitStatus = iota
+	// SubmitCorrect marks the accepted solution of the part
+	SubmitCorrect
+	// SubmitWrong is a wrong answer without a hint
+	SubmitWrong
+	// SubmitTooHigh is a wrong answer above the solution
+	SubmitTooHigh
+	// SubmitTooLow is a wrong answer below the solution
+	SubmitTooLow
+	// SubmitWait means the answer was submitted too early after the
+	// previous one and was not checked
+	SubmitWait
+	// SubmitAlreadySolved means the part was solved before
+	SubmitAlreadySolved
+)
+
+var submitStatusNames = [...]string{"unknown", "correct", "wrong", "too high", "too low", "wait", "already solved"}
+
+func (s SubmitStatus) String() string {
+	if s < 0 || int(s) >= len(submitStatusNames) {
+		// Status of a corrupt or newer attempts file
+		return fmt.Sprintf("SubmitStatus(%d)", int(s))
+	}
+	return submitStatusNames[s]
+}
+
+// SubmitResult is the parsed response to a submitted answer
+type SubmitResult struct {
+	Status SubmitStatus
+	// Time to wait before the next answer can be submitted
+	Wait time.Duration
+	// Text of the response or the reason the answer was not submitted
+	Message string
+	// Answer was not sent as the result is known from earlier attempts
+	Local bool
+}
+
+// aocAttempt is an answer submitted earlier as stored in the attempts
+// file
+type aocAttempt struct {
+	Day     int          `json:"day"`
+	Part    int          `json:"part"`
+	Answer  string       `json:"answer"`
+	Status  SubmitStatus `json:"status"`
+	Time    time.Time    `json:"time"`
+	Wait    aocDuration  `json:"wait,omitempty"`
+	Message string       `json:"message"`
+}
+
+// aocDuration is a time.Duration encoded as string in JSON
+type aocDuration time.Duration
+
+func (d aocDuration) MarshalJSON() ([]byte, error) { return json.Marshal(time.Duration(d).String()) }
+
+func (d *aocDuration) UnmarshalJSON(raw []byte) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	*d = aocDuration(v)
+	return err
+}
+
+var (
+	aocArticleRegexp = regexp.MustCompile(`(?s)<article[^>]*>(.*?)</article>`)
+	aocTagRegexp     = regexp.MustCompile(`<[^>]*>`)
+	aocLeftRegexp    = regexp.MustCompile(`You have (?:(\d+)m ?)?(?:(\d+)s )?left to wait`)
+	aocWaitRegexp    = regexp.MustCompile(`wait (\w+) minutes? before trying again`)
+	aocNumberWords   = map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10}
+)
+
+// parseSubmitResponse extracts the verdict from the HTML page returned
+// for a submitted answer
+func parseSubmitResponse(page string) SubmitResult {
+	var text = page
+	if m := aocArticleRegexp.FindStringSubmatch(page); m != nil {
+		text = m[1]
+	}
+	text = strings.Join(strings.Fields(html.UnescapeString(aocTagRegexp.ReplaceAllString(text, " "))), " ")
+
+	var res = SubmitResult{Message: text}
+
+	switch {
+	case strings.Contains(text, "That's the right answer"):
+		res.Status = SubmitCorrect
+	case strings.Contains(text, "You gave an answer too recently"):
+		res.Status = SubmitWait
+	case strings.Contains(text, "Did you already complete it"):
+		res.Status = SubmitAlreadySolved
+	case strings.Contains(text, "your answer is too high"):
+		res.Status = SubmitTooHigh
+	case strings.Contains(text, "your answer is too low"):
+		res.Status = SubmitTooLow
+	case strings.Contains(text, "That's not the right answer"):
+		res.Status = SubmitWrong
+	}
+
+	if m := aocLeftRegexp.FindStringSubmatch(text); m != nil {
+		min, _ := strconv.Atoi(m[1])
+		sec, _ := strconv.Atoi(m[2])
+		res.Wait = time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+	} else if m := aocWaitRegexp.FindStringSubmatch(text); m != nil {
+		n, ok := aocNumberWords[m[1]]
+		if !ok {
+			n, _ = strconv.Atoi(m[1])
+		}
+		res.Wait = time.Duration(n) * time.Minute
+	}
+
+	return res
+}
+
+// SubmitAnswer posts the answer for the part of the day. Answers are
+// checked against the answers of the input and the earlier attempts
+// stored in AttemptsFile first: known results are returned without
+// sending the answer. Correct answers are stored in the answers, saving
+// them is left to the caller. Parts with a known image answer are only
+// checked against the attempts and keep their image.
+func (a *AOCClient) SubmitAnswer(day, part int, answer Answer, answers Answers) (SubmitResult, error) {
+	if day < 1 || day > 25 || part < 1 || part > 2 {
+		return SubmitResult{}, errors.Errorf("Invalid day %d / part %d", day, part)
+	}
+
+	if answer.Kind == AnswerImage {
+		return SubmitResult{}, errors.New("Image answers need to be submitted as text")
+	}
+
+	var (
+		value = answer.String()
+		image = answers.IsImage(day, part)
+	)
+
+	if exp, ok := answers.Get(day, part); ok && !image {
+		res := SubmitResult{Status: SubmitAlreadySolved, Local: true, Message: fmt.Sprintf("Answer %s is already known to be correct", exp)}
+		if exp != value {
+			res.Status = SubmitWrong
+			res.Message = fmt.Sprintf("Answer differs from the known correct answer %s", exp)
+		}
+		return res, nil
+	}
+
+	attempts, err := a.loadAttempts()
+	if err != nil {
+		return SubmitResult{}, err
+	}
+
+	if res, ok := checkAttempts(attempts, day, part, answer, time.Now()); ok {
+		return res, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%d/day/%d/answer", a.BaseURL, aocYear, day), strings.NewReader(url.Values{
+		"level":  {strconv.Itoa(part)},
+		"answer": {value},
+	}.Encode()))
+	if err != nil {
+		return SubmitResult{}, errors.Wrap(err, "Unable to create request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.do(req)
+	if err != nil {
+		return SubmitResult{}, err
+	}
+	defer resp.Body.Close()
+
+	page, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return SubmitResult{}, errors.Wrap(err, "Unable to read response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return SubmitResult{}, errors.Errorf("Unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	res := parseSubmitResponse(string(page))
+
+	if res.Status == SubmitCorrect && !image {
+		answers.Set(day, part, answer)
+	}
+
+	if res.Status != SubmitUnknown && res.Status != SubmitAlreadySolved {
+		attempts = append(attempts, aocAttempt{
+			Day:     day,
+			Part:    part,
+			Answer:  value,
+			Status:  res.Status,
+			Time:    time.Now(),
+			Wait:    aocDuration(res.Wait),
+			Message: res.Message,
+		})
+		if err = a.saveAttempts(attempts); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// checkAttempts determines the result of an answer from the earlier
+// attempts: a running wait time, the same answer submitted before or a
+// number beyond a bound reported as too high or too low
+func checkAttempts(attempts []aocAttempt, day, part int, answer Answer, now time.Time) (SubmitResult, bool) {
+	var value = answer.String()
+
+	for _, at := range attempts {
+		if until := at.Time.Add(time.Duration(at.Wait)); until.After(now) {
+			return SubmitResult{
+				Status:  SubmitWait,
+				Wait:    until.Sub(now),
+				Message: fmt.Sprintf("Previous answer was submitted at %s, wait before trying again", at.Time.Format(time.RFC3339)),
+				Local:   true,
+			}, true
+		}
+	}
+
+	for _, at := range attempts {
+		if at.Day != day || at.Part != part || at.Status == SubmitWait {
+			// Answers submitted too early were not checked
+			continue
+		}
+
+		if at.Answer == value {
+			return SubmitResult{Status: at.Status, Message: fmt.Sprintf("Answer was submitted before: %s", at.Message), Local: true}, true
+		}
+
+		if answer.Kind != AnswerNumber {
+			continue
+		}
+
+		bound, err := strconv.ParseInt(at.Answer, 10, 64)
+		switch {
+		case err != nil:
+		case at.Status == SubmitTooHigh && answer.Number >= bound:
+			return SubmitResult{Status: SubmitTooHigh, Message: fmt.Sprintf("Answer %s was already too high", at.Answer), Local: true}, true
+		case at.Status == SubmitTooLow && answer.Number <= bound:
+			return SubmitResult{Status: SubmitTooLow, Message: fmt.Sprintf("Answer %s was already too low", at.Answer), Local: true}, true
+		}
+	}
+
+	return SubmitResult{}, false
+}
+
+func (a *AOCClient) loadAttempts() ([]aocAttempt, error) {
+	raw, err := ioutil.ReadFile(a.AttemptsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read attempts file")
+	}
+
+	var attempts []aocAttempt
+	return attempts, errors.Wrap(json.Unmarshal(raw, &attempts), "Unable to parse attempts file")
+}
+
+func (a *AOCClient) saveAttempts(attempts []aocAttempt) error {
+	raw, err := json.MarshalIndent(attempts, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Unable to encode attempts")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(a.AttemptsFile, append(raw, '\n'), 0644), "Unable to write attempts file")
+}
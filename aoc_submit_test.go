@@ -0,0 +1,248 @@
+package aoc2019
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const aocTestPage = `<!DOCTYPE html>
+<html lang="en-us"><head><title>Day 1 - Advent of Code 2019</title></head><body>
+<header><h1 class="title-global"><a href="/">Advent of Code</a></h1></header>
+<main>
+<article><p>%s</p></article>
+</main>
+</body></html>`
+
+func TestParseSubmitResponse(t *testing.T) {
+	for _, tc := range []struct {
+		Body   string
+		Status SubmitStatus
+		Wait   time.Duration
+	}{
+		{
+			Body:   `That's the right answer!  You are one gold star closer to rescuing Santa. <a href="/2019/day/1#part2">[Continue to Part Two]</a>`,
+			Status: SubmitCorrect,
+		},
+		{
+			Body:   `That's not the right answer; your answer is too high.  If you're stuck, make sure you're using the full input data; there are also some general tips on the <a href="/2019/about">about page</a>, or you can ask for hints on the <a href="https://www.reddit.com/r/adventofcode/" target="_blank">subreddit</a>.  Please wait one minute before trying again. (You guessed <span style="white-space:nowrap;"><code>5000</code>.)</span> <a href="/2019/day/1">[Return to Day 1]</a>`,
+			Status: SubmitTooHigh,
+			Wait:   time.Minute,
+		},
+		{
+			Body:   `That's not the right answer; your answer is too low.  Please wait 5 minutes before trying again.`,
+			Status: SubmitTooLow,
+			Wait:   5 * time.Minute,
+		},
+		{
+			Body:   `That's not the right answer.  If you're stuck, make sure you're using the full input data.  Please wait one minute before trying again.`,
+			Status: SubmitWrong,
+			Wait:   time.Minute,
+		},
+		{
+			Body:   `You gave an answer too recently; you have to wait after submitting an answer before trying again.  You have 39s left to wait. <a href="/2019/day/1">[Return to Day 1]</a>`,
+			Status: SubmitWait,
+			Wait:   39 * time.Second,
+		},
+		{
+			Body:   `You gave an answer too recently; you have to wait after submitting an answer before trying again.  You have 4m 12s left to wait.`,
+			Status: SubmitWait,
+			Wait:   4*time.Minute + 12*time.Second,
+		},
+		{
+			Body:   `You don't seem to be solving the right level.  Did you already complete it? <a href="/2019/day/1">[Return to Day 1]</a>`,
+			Status: SubmitAlreadySolved,
+		},
+		{
+			Body:   `Something &amp; nothing`,
+			Status: SubmitUnknown,
+		},
+	} {
+		res := parseSubmitResponse(fmt.Sprintf(aocTestPage, tc.Body))
+		if res.Status != tc.Status || res.Wait != tc.Wait {
+			t.Errorf("Unexpected result for %q: exp=%s/%s got=%s/%s", tc.Body, tc.Status, tc.Wait, res.Status, res.Wait)
+		}
+
+		if strings.Contains(res.Message, "<") || strings.Contains(res.Message, "Advent of Code") {
+			t.Errorf("Message contains more than the article text: %q", res.Message)
+		}
+	}
+}
+
+func TestSubmitStatusString(t *testing.T) {
+	for s, exp := range map[SubmitStatus]string{
+		SubmitTooLow:            "too low",
+		SubmitAlreadySolved:     "already solved",
+		SubmitAlreadySolved + 1: "SubmitStatus(7)",
+		-1:                      "SubmitStatus(-1)",
+	} {
+		if got := s.String(); got != exp {
+			t.Errorf("Unexpected name of status %d: exp=%q got=%q", int(s), exp, got)
+		}
+	}
+}
+
+func TestAOCClientSubmitAnswer(t *testing.T) {
+	server := newFakeAOCServer(t, "s3cr3t", nil)
+	defer server.Close()
+
+	c, cleanup := newTestAOCClient(t, server.Server, "s3cr3t")
+	defer cleanup()
+	c.AttemptsFile = filepath.Join(c.InputDir, "attempts.json")
+
+	var (
+		answers = Answers{}
+		now     = time.Now()
+	)
+
+	for i, tc := range []struct {
+		Answer Answer
+		Status SubmitStatus
+		Local  bool
+		Sent   int
+	}{
+		{NumberAnswer(50), SubmitTooHigh, false, 1},
+		// Wait time of the wrong answer is running
+		{NumberAnswer(45), SubmitWait, true, 1},
+	} {
+		res, err := c.SubmitAnswer(1, 1, tc.Answer, answers)
+		if err != nil {
+			t.Fatalf("Submission %d failed: %s", i, err)
+		}
+
+		if res.Status != tc.Status || res.Local != tc.Local || server.submitted() != tc.Sent {
+			t.Errorf("Unexpected result for submission %d (%s): exp=%s/%v/%d got=%s/%v/%d", i, tc.Answer, tc.Status, tc.Local, tc.Sent, res.Status, res.Local, server.submitted())
+		}
+	}
+
+	// Let the wait time pass
+	attempts, err := c.loadAttempts()
+	if err != nil || len(attempts) != 1 || attempts[0].Wait != aocDuration(time.Minute) {
+		t.Fatalf("Unexpected attempts: %+v %v", attempts, err)
+	}
+	attempts[0].Time = now.Add(-2 * time.Minute)
+	if err = c.saveAttempts(attempts); err != nil {
+		t.Fatalf("Unable to save attempts: %s", err)
+	}
+
+	for i, tc := range []struct {
+		Answer Answer
+		Status SubmitStatus
+		Local  bool
+		Sent   int
+	}{
+		{NumberAnswer(50), SubmitTooHigh, true, 1},
+		{NumberAnswer(60), SubmitTooHigh, true, 1},
+		{NumberAnswer(30), SubmitTooLow, false, 2},
+		{NumberAnswer(30), SubmitTooLow, true, 2},
+		{NumberAnswer(12), SubmitTooLow, true, 2},
+		{NumberAnswer(41), SubmitWrong, false, 3},
+		{NumberAnswer(41), SubmitWrong, true, 3},
+		{NumberAnswer(42), SubmitCorrect, false, 4},
+		{NumberAnswer(42), SubmitAlreadySolved, true, 4},
+		{NumberAnswer(43), SubmitWrong, true, 4},
+	} {
+		res, err := c.SubmitAnswer(1, 1, tc.Answer, answers)
+		if err != nil {
+			t.Fatalf("Submission %d failed: %s", i, err)
+		}
+
+		if res.Status != tc.Status || res.Local != tc.Local || server.submitted() != tc.Sent {
+			t.Errorf("Unexpected result for submission %d (%s): exp=%s/%v/%d got=%s/%v/%d", i, tc.Answer, tc.Status, tc.Local, tc.Sent, res.Status, res.Local, server.submitted())
+		}
+	}
+
+	if exp, ok := answers.Get(1, 1); !ok || exp != "42" {
+		t.Errorf("Correct answer was not stored: %q", exp)
+	}
+
+	if _, err = c.SubmitAnswer(1, 2, ImageAnswer(nil), answers); err == nil {
+		t.Error("Submitting image did not fail")
+	}
+
+	if _, err = c.SubmitAnswer(1, 3, NumberAnswer(1), answers); err == nil {
+		t.Error("Submitting invalid part did not fail")
+	}
+}
+
+func TestAOCClientSubmitImageAnswer(t *testing.T) {
+	server := newFakeAOCServer(t, "s3cr3t", nil)
+	defer server.Close()
+
+	c, cleanup := newTestAOCClient(t, server.Server, "s3cr3t")
+	defer cleanup()
+	c.AttemptsFile = filepath.Join(c.InputDir, "attempts.json")
+
+	answers, err := LoadAnswers("day08_answers.json")
+	if err != nil {
+		t.Fatalf("Unable to load answers: %s", err)
+	}
+	image, _ := answers.Get(8, 2)
+
+	for i, tc := range []struct {
+		Answer Answer
+		Status SubmitStatus
+		Local  bool
+		Sent   int
+	}{
+		// The known image is not compared with the letters
+		{TextAnswer("ABCDE"), SubmitWrong, false, 1},
+		{TextAnswer("EBZUR"), SubmitCorrect, false, 2},
+		{TextAnswer("EBZUR"), SubmitCorrect, true, 2},
+	} {
+		res, err := c.SubmitAnswer(8, 2, tc.Answer, answers)
+		if err != nil {
+			t.Fatalf("Submission %d failed: %s", i, err)
+		}
+
+		if res.Status != tc.Status || res.Local != tc.Local || server.submitted() != tc.Sent {
+			t.Errorf("Unexpected result for submission %d (%s): exp=%s/%v/%d got=%s/%v/%d", i, tc.Answer, tc.Status, tc.Local, tc.Sent, res.Status, res.Local, server.submitted())
+		}
+	}
+
+	if exp, _ := answers.Get(8, 2); exp != image {
+		t.Errorf("Known image answer was replaced by %q", exp)
+	}
+}
+
+func TestAOCClientSubmitTooRecent(t *testing.T) {
+	server := newFakeAOCServer(t, "s3cr3t", nil)
+	defer server.Close()
+	server.tooRecent = true
+
+	c, cleanup := newTestAOCClient(t, server.Server, "s3cr3t")
+	defer cleanup()
+	c.AttemptsFile = filepath.Join(c.InputDir, "attempts.json")
+
+	res, err := c.SubmitAnswer(1, 1, NumberAnswer(42), Answers{})
+	if err != nil || res.Status != SubmitWait || res.Wait != 30*time.Second {
+		t.Fatalf("Unexpected result: %+v %v", res, err)
+	}
+
+	attempts, err := c.loadAttempts()
+	if err != nil || len(attempts) != 1 {
+		t.Fatalf("Unexpected attempts: %+v %v", attempts, err)
+	}
+
+	// Answers not checked by the server may be submitted again
+	attempts[0].Time = time.Now().Add(-time.Minute)
+	if err = c.saveAttempts(attempts); err != nil {
+		t.Fatalf("Unable to save attempts: %s", err)
+	}
+
+	server.tooRecent = false
+	if res, err = c.SubmitAnswer(1, 1, NumberAnswer(42), Answers{}); err != nil || res.Status != SubmitCorrect || res.Local {
+		t.Errorf("Unexpected result: %+v %v", res, err)
+	}
+
+	if err = ioutil.WriteFile(c.AttemptsFile, []byte("["), 0644); err != nil {
+		t.Fatalf("Unable to write attempts: %s", err)
+	}
+
+	if _, err = c.SubmitAnswer(1, 1, NumberAnswer(43), Answers{}); err == nil {
+		t.Error("Invalid attempts file did not fail")
+	}
+}
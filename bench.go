@@ -0,0 +1,238 @@
+package aoc2019
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BenchmarkBaseline holds the run times of the solvers keyed by day and
+// part
+type BenchmarkBaseline map[int]map[int][]time.Duration
+
+// LoadBenchmarkBaseline reads a baseline file, a missing file yields an
+// empty baseline
+func LoadBenchmarkBaseline(fileName string) (BenchmarkBaseline, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return BenchmarkBaseline{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read baseline file")
+	}
+
+	var b = BenchmarkBaseline{}
+	return b, errors.Wrap(json.Unmarshal(raw, &b), "Unable to parse baseline file")
+}
+
+// Save writes the baseline into the file
+func (b BenchmarkBaseline) Save(fileName string) error {
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Unable to encode baseline")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(fileName, append(raw, '\n'), 0644), "Unable to write baseline file")
+}
+
+// Set stores the run times of the part
+func (b BenchmarkBaseline) Set(day, part int, samples []time.Duration) {
+	if b[day] == nil {
+		b[day] = map[int][]time.Duration{}
+	}
+	b[day][part] = samples
+}
+
+// MeasureSolver runs the part of the day the given number of times
+// after one unmeasured warm-up run and returns the run times
+func MeasureSolver(day, part int, input []byte, runs int) ([]time.Duration, error) {
+	if _, err := Solve(day, part, bytes.NewReader(input)); err != nil {
+		return nil, err
+	}
+
+	var samples = make([]time.Duration, runs)
+	for i := range samples {
+		start := time.Now()
+		if _, err := Solve(day, part, bytes.NewReader(input)); err != nil {
+			return nil, err
+		}
+		samples[i] = time.Since(start)
+	}
+
+	return samples, nil
+}
+
+// BenchmarkComparison is the result of comparing the run times of a
+// part with its baseline
+type BenchmarkComparison struct {
+	Old, New BenchmarkStats
+	// Relative change of the mean run time
+	Delta float64
+	// Probability of seeing the difference with equal run times (two
+	// sided Welch's t-test)
+	P float64
+	// Difference is significant and exceeds the threshold
+	Regression, Improvement bool
+}
+
+// BenchmarkStats describes the run times of one part
+type BenchmarkStats struct {
+	Mean, StdDev time.Duration
+	N            int
+}
+
+func newBenchmarkStats(samples []time.Duration) BenchmarkStats {
+	var (
+		s        = BenchmarkStats{N: len(samples)}
+		mean, sq float64
+	)
+
+	if s.N == 0 {
+		return s
+	}
+
+	for _, v := range samples {
+		mean += float64(v)
+	}
+	mean /= float64(s.N)
+
+	for _, v := range samples {
+		sq += (float64(v) - mean) * (float64(v) - mean)
+	}
+
+	s.Mean = time.Duration(mean)
+	if s.N > 1 {
+		s.StdDev = time.Duration(math.Sqrt(sq / float64(s.N-1)))
+	}
+	return s
+}
+
+// CompareBenchmarks compares the run times with the baseline: changes
+// are reported when they are significant at level alpha and the mean
+// changed by more than the threshold (relative to the old mean)
+func CompareBenchmarks(old, new []time.Duration, alpha, threshold float64) BenchmarkComparison {
+	var c = BenchmarkComparison{
+		Old: newBenchmarkStats(old),
+		New: newBenchmarkStats(new),
+		P:   1,
+	}
+
+	if c.Old.N < 2 || c.New.N < 2 || c.Old.Mean == 0 {
+		return c
+	}
+
+	c.Delta = float64(c.New.Mean-c.Old.Mean) / float64(c.Old.Mean)
+	c.P = welchTTest(c.Old, c.New)
+
+	if c.P < alpha && math.Abs(c.Delta) > threshold {
+		c.Regression = c.Delta > 0
+		c.Improvement = c.Delta < 0
+	}
+
+	return c
+}
+
+// welchTTest returns the two sided p-value for the means of the samples
+// being equal without assuming equal variances
+func welchTTest(a, b BenchmarkStats) float64 {
+	var (
+		va = math.Pow(float64(a.StdDev), 2) / float64(a.N)
+		vb = math.Pow(float64(b.StdDev), 2) / float64(b.N)
+		d  = float64(b.Mean - a.Mean)
+	)
+
+	if va+vb == 0 {
+		if d == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	var (
+		t  = d / math.Sqrt(va+vb)
+		df = (va + vb) * (va + vb) / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	)
+
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) through its continued
+// fraction (Numerical Recipes, betai)
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	var (
+		la, _  = math.Lgamma(a)
+		lb, _  = math.Lgamma(b)
+		lab, _ = math.Lgamma(a + b)
+		front  = math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	)
+
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	var (
+		c = 1.0
+		d = 1 - (a+b)*x/(a+1)
+	)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	var h = d
+
+	for m := 1.0; m <= maxIterations; m++ {
+		// Even step
+		aa := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step
+		aa = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,105 @@
+package aoc2019
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegularizedIncompleteBeta(t *testing.T) {
+	for _, tc := range []struct {
+		X, A, B, Exp float64
+	}{
+		{0, 2, 3, 0},
+		{1, 2, 3, 1},
+		{0.5, 4, 4, 0.5},
+		{0.3, 1, 1, 0.3},
+		{0.2, 2, 3, 0.1808},
+		// Two sided p-value of t=2.228 with 10 degrees of freedom
+		{10 / (10 + 2.228*2.228), 5, 0.5, 0.05},
+	} {
+		if v := regularizedIncompleteBeta(tc.X, tc.A, tc.B); math.Abs(v-tc.Exp) > 1e-4 {
+			t.Errorf("Unexpected value for I_%f(%f, %f): exp=%f got=%f", tc.X, tc.A, tc.B, tc.Exp, v)
+		}
+	}
+}
+
+func TestCompareBenchmarks(t *testing.T) {
+	ms := func(values ...float64) []time.Duration {
+		var out []time.Duration
+		for _, v := range values {
+			out = append(out, time.Duration(v*float64(time.Millisecond)))
+		}
+		return out
+	}
+
+	var base = ms(10, 11, 9, 10.5, 9.5, 10, 10.2, 9.8)
+
+	for name, tc := range map[string]struct {
+		New                     []time.Duration
+		Regression, Improvement bool
+	}{
+		"unchanged":         {New: ms(10.1, 9.9, 10.3, 9.7, 10, 10.4, 9.6, 10)},
+		"slower":            {New: ms(13, 12.5, 13.5, 12.8, 13.2, 13, 12.9, 13.1), Regression: true},
+		"faster":            {New: ms(5, 5.2, 4.8, 5.1, 4.9, 5, 5, 5), Improvement: true},
+		"below threshold":   {New: ms(10.4, 10.5, 10.6, 10.5, 10.4, 10.6, 10.5, 10.5)},
+		"too few samples":   {New: ms(30)},
+		"noisy but slower":  {New: ms(5, 25, 6, 24, 8, 20, 4, 30)},
+		"constant and same": {New: base},
+	} {
+		c := CompareBenchmarks(base, tc.New, 0.01, 0.1)
+		if c.Regression != tc.Regression || c.Improvement != tc.Improvement {
+			t.Errorf("Unexpected comparison for %q: exp=%v/%v got=%+v", name, tc.Regression, tc.Improvement, c)
+		}
+	}
+
+	if c := CompareBenchmarks(ms(10, 10), ms(10, 10), 0.01, 0.1); c.P != 1 || c.Delta != 0 {
+		t.Errorf("Equal constant run times yielded difference: %+v", c)
+	}
+
+	if c := CompareBenchmarks(ms(10, 10), ms(20, 20), 0.01, 0.1); c.P != 0 || !c.Regression {
+		t.Errorf("Different constant run times yielded no regression: %+v", c)
+	}
+}
+
+func TestBenchmarkBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aoc2019-bench-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var fileName = filepath.Join(dir, "baseline.json")
+
+	b, err := LoadBenchmarkBaseline(fileName)
+	if err != nil || len(b) != 0 {
+		t.Fatalf("Missing baseline did not yield empty baseline: %v %s", b, err)
+	}
+
+	samples, err := MeasureSolver(1, 1, []byte("12\n14\n"), 3)
+	if err != nil || len(samples) != 3 {
+		t.Fatalf("Unable to measure solver: %v %s", samples, err)
+	}
+
+	b.Set(1, 1, samples)
+	if err = b.Save(fileName); err != nil {
+		t.Fatalf("Unable to save baseline: %s", err)
+	}
+
+	loaded, err := LoadBenchmarkBaseline(fileName)
+	if err != nil {
+		t.Fatalf("Unable to load baseline: %s", err)
+	}
+
+	if !reflect.DeepEqual(loaded, b) {
+		t.Errorf("Baseline changed while saving: exp=%v got=%v", b, loaded)
+	}
+
+	if _, err = MeasureSolver(18, 1, nil, 3); err == nil {
+		t.Error("Measuring unknown day did not fail")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Luzifer/aoc2019"
+)
+
+var benchCfg = struct {
+	Alpha     float64
+	Baseline  string
+	Day       int
+	Part      int
+	Runs      int
+	Save      bool
+	Threshold float64
+}{}
+
+func benchCmd(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Float64Var(&benchCfg.Alpha, "alpha", 0.01, "Significance level for changes of the run time")
+	fs.StringVar(&benchCfg.Baseline, "baseline", "bench_baseline.json", "Baseline file to compare with or to save into")
+	fs.IntVar(&benchCfg.Day, "day", 0, "Day to benchmark (default: all)")
+	fs.IntVar(&benchCfg.Part, "part", 0, "Part to benchmark (default: both)")
+	fs.IntVar(&benchCfg.Runs, "runs", 10, "Number of measured runs per part")
+	fs.BoolVar(&benchCfg.Save, "save", false, "Save the run times into the baseline file instead of comparing")
+	fs.Float64Var(&benchCfg.Threshold, "threshold", 0.1, "Minimum relative change of the mean run time to report")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s bench [--day <day>] [--part <part>] [--save]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 0 || benchCfg.Runs < 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var (
+		days  = aoc2019.SolvedDays()
+		parts = []int{1, 2}
+	)
+
+	if benchCfg.Day != 0 {
+		days = []int{benchCfg.Day}
+	}
+
+	if benchCfg.Part != 0 {
+		parts = []int{benchCfg.Part}
+	}
+
+	baseline, err := aoc2019.LoadBenchmarkBaseline(benchCfg.Baseline)
+	if err != nil {
+		log.Printf("%s", err)
+		return 1
+	}
+
+	var failed bool
+	for _, day := range days {
+		input, err := ioutil.ReadFile(aoc2019.InputFile(day))
+		if err != nil {
+			log.Printf("Day %d: Unable to read input file: %s", day, err)
+			failed = true
+			continue
+		}
+
+		for _, part := range parts {
+			samples, err := aoc2019.MeasureSolver(day, part, input, benchCfg.Runs)
+			if err != nil {
+				log.Printf("%s", err)
+				failed = true
+				continue
+			}
+
+			old, ok := baseline[day][part]
+			if benchCfg.Save || !ok {
+				var (
+					c    = aoc2019.CompareBenchmarks(nil, samples, 0, 0)
+					note string
+				)
+				if !benchCfg.Save {
+					note = " (no baseline)"
+				}
+
+				fmt.Printf("Day %d Part %d: %s ± %s%s\n", day, part, formatDuration(c.New.Mean), formatDuration(c.New.StdDev), note)
+				baseline.Set(day, part, samples)
+				continue
+			}
+
+			c := aoc2019.CompareBenchmarks(old, samples, benchCfg.Alpha, benchCfg.Threshold)
+
+			var verdict string
+			switch {
+			case c.Regression:
+				verdict = " REGRESSION"
+				failed = true
+			case c.Improvement:
+				verdict = " improvement"
+			}
+
+			fmt.Printf("Day %d Part %d: %s ± %s (baseline %s, %+.1f%%, p=%.3f)%s\n",
+				day, part, formatDuration(c.New.Mean), formatDuration(c.New.StdDev),
+				formatDuration(c.Old.Mean), c.Delta*100, c.P, verdict)
+		}
+	}
+
+	if benchCfg.Save {
+		if err = baseline.Save(benchCfg.Baseline); err != nil {
+			log.Printf("%s", err)
+			return 1
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
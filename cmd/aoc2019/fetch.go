@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Luzifer/aoc2019"
+)
+
+var fetchCfg = struct {
+	Day int
+	Dir string
+}{}
+
+func fetchCmd(args []string) int {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	fs.IntVar(&fetchCfg.Day, "day", 0, "Day to download the input for")
+	fs.StringVar(&fetchCfg.Dir, "dir", ".", "Directory to store the input in")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s fetch --day <day>\n\nThe session token is read from AOC_SESSION or the file in AOC_SESSION_FILE (default: ~/.config/aoc/session)\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 0 || fetchCfg.Day == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// Inputs already stored do not need a session
+	session, sessionErr := aoc2019.LoadAOCSession()
+
+	c := aoc2019.NewAOCClient(session)
+	c.InputDir = fetchCfg.Dir
+
+	raw, err := c.FetchInput(fetchCfg.Day)
+	if err != nil {
+		if sessionErr != nil {
+			err = sessionErr
+		}
+		log.Printf("%s", err)
+		return 1
+	}
+
+	fmt.Printf("Day %d: %d bytes in %s\n", fetchCfg.Day, len(raw), filepath.Join(fetchCfg.Dir, aoc2019.InputFile(fetchCfg.Day)))
+	return 0
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Luzifer/aoc2019"
+)
+
+type result struct {
+	Day      int     `json:"day"`
+	Part     int     `json:"part"`
+	Answer   string  `json:"answer,omitempty"`
+	Status   string  `json:"status,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+}
+
+var cfg = struct {
+	All         bool
+	AnswersFile string
+	Day         int
+	Format      string
+	ImageDir    string
+	Input       string
+	Part        int
+}{}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s run [options]\n       %s bench [options]\n       %s fetch [options]\n       %s submit [options]\n\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "run":
+		os.Exit(runCmd(os.Args[2:]))
+	case "bench":
+		os.Exit(benchCmd(os.Args[2:]))
+	case "fetch":
+		os.Exit(fetchCmd(os.Args[2:]))
+	case "submit":
+		os.Exit(submitCmd(os.Args[2:]))
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.StringVar(&cfg.AnswersFile, "answers", "", "Answers file to check the answers against (default: next to the input file)")
+	fs.BoolVar(&cfg.All, "all", false, "Run all parts of all days with their inputs from the repository")
+	fs.IntVar(&cfg.Day, "day", 0, "Day to run")
+	fs.StringVar(&cfg.Format, "format", "text", "Output format: text or json")
+	fs.StringVar(&cfg.ImageDir, "images", "", "Save image answers as PNG files into this directory")
+	fs.StringVar(&cfg.Input, "input", "", "Input file, - to read from stdin (default: dayXX_input.txt)")
+	fs.IntVar(&cfg.Part, "part", 0, "Part to run (default: both)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s run --day <day> [--part <part>] [--input <file>]\n       %s run --all\n\n", os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 0 || cfg.All == (cfg.Day != 0) {
+		fs.Usage()
+		return 2
+	}
+
+	if cfg.All && (cfg.Part != 0 || cfg.Input != "" || cfg.AnswersFile != "") {
+		log.Print("--all cannot be combined with --part, --input or --answers")
+		return 2
+	}
+
+	if cfg.Format != "text" && cfg.Format != "json" {
+		log.Printf("Unknown output format %q", cfg.Format)
+		return 2
+	}
+
+	var (
+		days  = []int{cfg.Day}
+		parts = []int{1, 2}
+	)
+
+	if cfg.All {
+		days = aoc2019.SolvedDays()
+	}
+
+	if cfg.Part != 0 {
+		parts = []int{cfg.Part}
+	}
+
+	var (
+		results []result
+		failed  bool
+	)
+
+	for _, day := range days {
+		input, answers, inputErr := readInput(day)
+
+		for _, part := range parts {
+			if inputErr != nil {
+				results = append(results, result{Day: day, Part: part, Error: inputErr.Error()})
+				failed = true
+				if cfg.Format == "text" {
+					printText(results[len(results)-1])
+				}
+				continue
+			}
+
+			var (
+				start       = time.Now()
+				answer, err = aoc2019.Solve(day, part, bytes.NewReader(input))
+				res         = result{Day: day, Part: part, Duration: float64(time.Since(start)) / float64(time.Millisecond)}
+			)
+
+			if err == nil && answer.Kind == aoc2019.AnswerImage && cfg.ImageDir != "" {
+				err = saveImage(answer, filepath.Join(cfg.ImageDir, fmt.Sprintf("day%02d_part%d.png", day, part)))
+			}
+
+			if err != nil {
+				res.Error = err.Error()
+				failed = true
+			} else {
+				res.Answer = answer.String()
+				status := answers.Check(day, part, answer)
+				res.Status = status.String()
+				failed = failed || status == aoc2019.AnswerWrong
+			}
+
+			if cfg.Format == "text" {
+				printText(res)
+			}
+			results = append(results, res)
+		}
+	}
+
+	if cfg.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			log.Printf("Unable to encode results: %s", err)
+			return 1
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func printText(res result) {
+	if res.Error != "" {
+		fmt.Printf("Day %d Part %d: ERROR %s (%.1fms)\n", res.Day, res.Part, res.Error, res.Duration)
+		return
+	}
+
+	if strings.Contains(res.Answer, "\n") {
+		// Images are drawn below the header
+		fmt.Printf("Day %d Part %d: [%s] (%.1fms)\n%s\n", res.Day, res.Part, res.Status, res.Duration, res.Answer)
+		return
+	}
+	fmt.Printf("Day %d Part %d: %s [%s] (%.1fms)\n", res.Day, res.Part, res.Answer, res.Status, res.Duration)
+}
+
+func saveImage(answer aoc2019.Answer, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return errors.Wrap(err, "Unable to create image file")
+	}
+	defer f.Close()
+
+	return errors.Wrap(png.Encode(f, answer.Image), "Unable to store image")
+}
+
+// readInput reads the input given through --input or the input of the
+// day from the repository together with the answers known for it
+func readInput(day int) ([]byte, aoc2019.Answers, error) {
+	var (
+		inFile      = cfg.Input
+		answersFile = cfg.AnswersFile
+		raw         []byte
+		err         error
+	)
+
+	if inFile == "" {
+		inFile = aoc2019.InputFile(day)
+	}
+
+	if inFile == "-" {
+		if raw, err = ioutil.ReadAll(os.Stdin); err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to read stdin")
+		}
+	} else {
+		if raw, err = ioutil.ReadFile(inFile); err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to read input file")
+		}
+
+		if answersFile == "" {
+			answersFile = aoc2019.AnswersFile(inFile)
+		}
+	}
+
+	if answersFile == "" {
+		// Answers of stdin are unknown
+		return raw, aoc2019.Answers{}, nil
+	}
+
+	answers, err := aoc2019.LoadAnswers(answersFile)
+	return raw, answers, err
+}
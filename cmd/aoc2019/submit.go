@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Luzifer/aoc2019"
+)
+
+var submitCfg = struct {
+	Answer       string
+	AttemptsFile string
+	Day          int
+	Input        string
+	Part         int
+}{}
+
+func submitCmd(args []string) int {
+	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+	fs.StringVar(&submitCfg.Answer, "answer", "", "Answer to submit instead of solving the input (required for image answers)")
+	fs.StringVar(&submitCfg.AttemptsFile, "attempts", "aoc_attempts.json", "File to record submitted answers in")
+	fs.IntVar(&submitCfg.Day, "day", 0, "Day to submit the answer for")
+	fs.StringVar(&submitCfg.Input, "input", "", "Input file to solve (default: dayXX_input.txt)")
+	fs.IntVar(&submitCfg.Part, "part", 0, "Part to submit the answer for")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s submit --day <day> --part <part> [--answer <answer>]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 0 || submitCfg.Day == 0 || submitCfg.Part == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	inFile := submitCfg.Input
+	if inFile == "" {
+		inFile = aoc2019.InputFile(submitCfg.Day)
+	}
+
+	answer, err := submitAnswer(inFile)
+	if err != nil {
+		log.Printf("%s", err)
+		return 1
+	}
+
+	answersFile := aoc2019.AnswersFile(inFile)
+	answers, err := aoc2019.LoadAnswers(answersFile)
+	if err != nil {
+		log.Printf("%s", err)
+		return 1
+	}
+
+	// Answers known from earlier attempts do not need a session
+	session, sessionErr := aoc2019.LoadAOCSession()
+
+	c := aoc2019.NewAOCClient(session)
+	c.AttemptsFile = submitCfg.AttemptsFile
+
+	res, err := c.SubmitAnswer(submitCfg.Day, submitCfg.Part, answer, answers)
+	if err != nil {
+		if sessionErr != nil {
+			err = sessionErr
+		}
+		log.Printf("%s", err)
+		return 1
+	}
+
+	var note string
+	if res.Local {
+		note = " (not submitted)"
+	}
+	fmt.Printf("Day %d Part %d: %s is %s%s\n%s\n", submitCfg.Day, submitCfg.Part, answer, res.Status, note, res.Message)
+	if res.Wait > 0 {
+		fmt.Printf("Wait %s before submitting again\n", res.Wait)
+	}
+
+	switch res.Status {
+	case aoc2019.SubmitCorrect:
+		if err = answers.Save(answersFile); err != nil {
+			log.Printf("%s", err)
+			return 1
+		}
+		return 0
+	case aoc2019.SubmitAlreadySolved:
+		return 0
+	}
+	return 1
+}
+
+// submitAnswer returns the answer given through --answer or solves the
+// input file
+func submitAnswer(inFile string) (aoc2019.Answer, error) {
+	if submitCfg.Answer != "" {
+		if v, err := strconv.ParseInt(submitCfg.Answer, 10, 64); err == nil {
+			return aoc2019.NumberAnswer(v), nil
+		}
+		return aoc2019.TextAnswer(submitCfg.Answer), nil
+	}
+
+	raw, err := ioutil.ReadFile(inFile)
+	if err != nil {
+		return aoc2019.Answer{}, err
+	}
+
+	return aoc2019.Solve(submitCfg.Day, submitCfg.Part, bytes.NewReader(raw))
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/Luzifer/aoc2019"
+)
+
+type memoryPatches map[int64]int64
+
+func (m memoryPatches) String() string {
+	var parts []string
+	for addr, v := range m {
+		parts = append(parts, fmt.Sprintf("%d=%d", addr, v))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m memoryPatches) Set(in string) error {
+	parts := strings.SplitN(in, "=", 2)
+	if len(parts) != 2 {
+		return errors.Errorf("Patch %q is not in format addr=value", in)
+	}
+
+	addr, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || addr < 0 {
+		return errors.Errorf("Invalid address in patch %q", in)
+	}
+
+	v, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return errors.Errorf("Invalid value in patch %q", in)
+	}
+
+	m[addr] = v
+	return nil
+}
+
+type memoryLabels map[int64]string
+
+func (m memoryLabels) String() string {
+	var parts []string
+	for addr, l := range m {
+		parts = append(parts, fmt.Sprintf("%d=%s", addr, l))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m memoryLabels) Set(in string) error {
+	parts := strings.SplitN(in, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return errors.Errorf("Label %q is not in format addr=name", in)
+	}
+
+	addr, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || addr < 0 {
+		return errors.Errorf("Invalid address in label %q", in)
+	}
+
+	m[addr] = parts[1]
+	return nil
+}
+
+var cfg = struct {
+	Arithmetic string
+	ASCII      bool
+	Coverage   string
+	Decompile  bool
+	DumpMemory bool
+	Inputs     string
+	Labels     memoryLabels
+	Listen     string
+	MaxSteps   int64
+	Patches    memoryPatches
+	RecordFile string
+	ReplayFile string
+	ResumeFile string
+	SaveFile   string
+	TaintFile  string
+}{
+	Labels:  memoryLabels{},
+	Patches: memoryPatches{},
+}
+
+func init() {
+	flag.StringVar(&cfg.Arithmetic, "arithmetic", "wrap", "Arithmetic mode: wrap (int64), checked (int64, fail on overflow) or big (arbitrary precision)")
+	flag.BoolVar(&cfg.ASCII, "ascii", false, "Read input and write output as ASCII characters instead of decimal numbers")
+	flag.StringVar(&cfg.Coverage, "coverage", "", "Write an annotated disassembly with instruction coverage into this file (HTML for .html files, text otherwise)")
+	flag.BoolVar(&cfg.Decompile, "decompile", false, "Print the program as structured pseudocode instead of executing it")
+	flag.BoolVar(&cfg.DumpMemory, "dump-memory", false, "Print the final memory of the program after it exited")
+	flag.StringVar(&cfg.Inputs, "input", "", "Comma separated list of inputs to feed instead of reading stdin")
+	flag.Var(cfg.Labels, "label", "Label the initial value of a memory cell for --taint (addr=name, can be repeated)")
+	flag.StringVar(&cfg.Listen, "listen", "", "Serve the program on this TCP address, one value per line (characters with --ascii), every connection runs a new machine")
+	flag.Int64Var(&cfg.MaxSteps, "max-steps", 0, "Stop the program after executing this many instructions (0 = no limit)")
+	flag.Var(cfg.Patches, "set", "Patch memory before execution (addr=value, can be repeated)")
+	flag.StringVar(&cfg.RecordFile, "record", "", "Record every input and output with its instruction into this file for later replay")
+	flag.StringVar(&cfg.ReplayFile, "replay", "", "Replay a session recorded through --record and report the first divergence (plain lists of inputs are fed as input)")
+	flag.StringVar(&cfg.ResumeFile, "resume", "", "Resume the machine stored through --save instead of starting a program")
+	flag.StringVar(&cfg.SaveFile, "save", "", "Save the machine state into this file when the input is exhausted")
+	flag.StringVar(&cfg.TaintFile, "taint", "", "Write the labels (inputs and --label cells) every output and memory cell depends on into this file")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <program file>\n       %s [options] --resume <state file>\n\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if (cfg.ResumeFile == "" && flag.NArg() != 1) || (cfg.ResumeFile != "" && flag.NArg() != 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if cfg.Inputs != "" && cfg.ReplayFile != "" {
+		log.Fatal("Only one of --input and --replay can be used")
+	}
+
+	if cfg.ResumeFile != "" && len(cfg.Patches) > 0 {
+		log.Fatal("Memory patches cannot be applied to a resumed machine")
+	}
+
+	if len(cfg.Labels) > 0 && cfg.TaintFile == "" {
+		log.Fatal("--label requires --taint")
+	}
+
+	if cfg.Decompile {
+		if cfg.Listen != "" || cfg.Arithmetic != "wrap" {
+			log.Fatal("--decompile cannot be combined with --listen or other arithmetic modes")
+		}
+
+		if err := decompile(flag.Arg(0)); err != nil {
+			log.Fatalf("%s", err)
+		}
+		return
+	}
+
+	if cfg.Listen != "" {
+		if cfg.Arithmetic != "wrap" || cfg.ResumeFile != "" || cfg.Coverage != "" || cfg.TaintFile != "" || cfg.RecordFile != "" || cfg.ReplayFile != "" || cfg.Inputs != "" {
+			log.Fatal("--listen only supports --ascii and --set")
+		}
+
+		if err := serve(flag.Arg(0)); err != nil {
+			log.Fatalf("%s", err)
+		}
+		return
+	}
+
+	if err := run(flag.Arg(0)); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
+
+func run(programFile string) error {
+	in, events, err := inputSource()
+	if err != nil {
+		return errors.Wrap(err, "Unable to create input source")
+	}
+
+	var (
+		w   = bufio.NewWriter(os.Stdout)
+		mem []string
+	)
+
+	switch cfg.Arithmetic {
+	case "wrap", "checked", "big":
+		mem, err = runMachine(programFile, in, events, w)
+	default:
+		return errors.Errorf("Unknown arithmetic mode %q", cfg.Arithmetic)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Program execution failed")
+	}
+
+	if cfg.DumpMemory && mem != nil {
+		fmt.Fprintln(w, strings.Join(mem, ","))
+	}
+
+	return w.Flush()
+}
+
+func decompile(programFile string) error {
+	m, err := loadMachine(programFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(os.Stdout, aoc2019.DecompileIntcode(m.Memory()))
+	return errors.Wrap(err, "Unable to write pseudocode")
+}
+
+func serve(programFile string) error {
+	if _, err := loadMachine(programFile); err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return errors.Wrap(err, "Unable to listen")
+	}
+
+	log.Printf("Serving %s on %s", programFile, l.Addr())
+	return aoc2019.ServeIntcode(context.Background(), l, func() (*aoc2019.IntcodeMachine, error) {
+		return loadMachine(programFile)
+	}, cfg.ASCII)
+}
+
+func loadMachine(programFile string) (*aoc2019.IntcodeMachine, error) {
+	if cfg.ResumeFile != "" {
+		f, err := os.Open(cfg.ResumeFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to open state file")
+		}
+		defer f.Close()
+
+		return aoc2019.LoadIntcodeMachine(f)
+	}
+
+	raw, err := ioutil.ReadFile(programFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read program")
+	}
+
+	var code []*big.Int
+	if strings.HasSuffix(programFile, ".icl") {
+		compiled, err := aoc2019.CompileIntcode(string(raw))
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to compile program")
+		}
+		for _, v := range compiled {
+			code = append(code, big.NewInt(v))
+		}
+	} else {
+		// Programs are parsed with arbitrary precision, values exceeding
+		// int64 are rejected below unless running in big arithmetic mode
+		if code, err = aoc2019.ParseBigIntcode(strings.TrimSpace(string(raw))); err != nil {
+			return nil, errors.Wrap(err, "Unable to parse program")
+		}
+	}
+
+	for addr, v := range cfg.Patches {
+		if addr >= int64(len(code)) {
+			return nil, errors.Errorf("Patch address %d is outside program (len=%d)", addr, len(code))
+		}
+		code[addr] = big.NewInt(v)
+	}
+
+	if cfg.Arithmetic == "big" {
+		return aoc2019.NewBigIntcodeMachine(code), nil
+	}
+
+	var mem = make([]int64, len(code))
+	for addr, v := range code {
+		if !v.IsInt64() {
+			return nil, errors.Errorf("Value %s at address %d exceeds int64, use --arithmetic big", v, addr)
+		}
+		mem[addr] = v.Int64()
+	}
+
+	return aoc2019.NewIntcodeMachine(mem), nil
+}
+
+func runMachine(programFile string, in func() (int64, error), events []aoc2019.IntcodeEvent, w *bufio.Writer) ([]string, error) {
+	m, err := loadMachine(programFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Arithmetic {
+	case "checked":
+		m.Arithmetic = aoc2019.IntcodeArithmeticChecked
+	case "big":
+		m.Arithmetic = aoc2019.IntcodeArithmeticBig
+	}
+
+	m.MaxSteps = cfg.MaxSteps
+
+	if cfg.Coverage != "" {
+		m.Coverage = aoc2019.NewIntcodeCoverage()
+		defer writeCoverage(m.Coverage, append([]int64(nil), m.Memory()...))
+	}
+
+	if cfg.TaintFile != "" {
+		if cfg.ResumeFile != "" {
+			return nil, errors.New("Taint tracking cannot be applied to a resumed machine")
+		}
+
+		m.Taint = aoc2019.NewIntcodeTaintTracker()
+		for addr, label := range cfg.Labels {
+			if err := m.Taint.LabelMemory(addr, label); err != nil {
+				return nil, errors.Wrap(err, "Unable to label memory")
+			}
+		}
+		defer writeTaint(m)
+	}
+
+	if cfg.RecordFile != "" {
+		f, err := os.Create(cfg.RecordFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create record file")
+		}
+		defer f.Close()
+
+		m.OnIO = aoc2019.NewIntcodeRecorder(f).Record
+	}
+
+	out := func(v int64) error {
+		writeOutput(w, big.NewInt(v))
+		return w.Flush()
+	}
+
+	// Used instead of out in big arithmetic mode
+	m.BigOut = func(v *big.Int) error {
+		writeOutput(w, v)
+		return w.Flush()
+	}
+
+	if events != nil {
+		err = aoc2019.ReplayIntcode(context.Background(), m, events, out)
+	} else {
+		err = m.Run(context.Background(), in, out)
+	}
+
+	if err == aoc2019.ErrIntcodeInputExhausted && cfg.SaveFile != "" {
+		f, err := os.Create(cfg.SaveFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create state file")
+		}
+		defer f.Close()
+
+		log.Printf("Input exhausted, saving machine state to %s", cfg.SaveFile)
+		return nil, m.SaveState(f)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cells []string
+	for _, v := range m.BigMemory() {
+		cells = append(cells, v.String())
+	}
+
+	return cells, nil
+}
+
+// inputSource selects the input mode: inputs given through flags,
+// a recorded session, a file of inputs or (default) reading from stdin.
+// For recorded sessions the events to replay are returned instead of
+// an input callback.
+func inputSource() (func() (int64, error), []aoc2019.IntcodeEvent, error) {
+	switch {
+
+	case cfg.Inputs != "":
+		values, err := aoc2019.ParseIntcode(cfg.Inputs)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to parse inputs")
+		}
+		return sliceInput(values), nil, nil
+
+	case cfg.ReplayFile != "":
+		f, err := os.Open(cfg.ReplayFile)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to open replay file")
+		}
+		defer f.Close()
+
+		r := bufio.NewReader(f)
+		if aoc2019.IsIntcodeRecording(r) {
+			events, err := aoc2019.ReadIntcodeRecording(r)
+			return nil, events, errors.Wrap(err, "Unable to read recording")
+		}
+
+		values, err := readDecimals(r)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to read replay file")
+		}
+		return sliceInput(values), nil, nil
+
+	case cfg.ASCII:
+		r := bufio.NewReader(os.Stdin)
+		return func() (int64, error) {
+			c, err := r.ReadByte()
+			if err == io.EOF {
+				return 0, aoc2019.ErrIntcodeInputExhausted
+			}
+			return int64(c), errors.Wrap(err, "Unable to read stdin")
+		}, nil, nil
+
+	default:
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Split(bufio.ScanWords)
+		return func() (int64, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return 0, errors.Wrap(err, "Unable to read stdin")
+				}
+				return 0, aoc2019.ErrIntcodeInputExhausted
+			}
+			v, err := strconv.ParseInt(scanner.Text(), 10, 64)
+			return v, errors.Wrapf(err, "Invalid input %q", scanner.Text())
+		}, nil, nil
+
+	}
+}
+
+func readDecimals(r io.Reader) ([]int64, error) {
+	var values []int64
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		v, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid value %q", line)
+		}
+		values = append(values, v)
+	}
+
+	return values, errors.Wrap(scanner.Err(), "Unable to scan values")
+}
+
+func sliceInput(values []int64) func() (int64, error) {
+	return func() (int64, error) {
+		if len(values) == 0 {
+			return 0, aoc2019.ErrIntcodeInputExhausted
+		}
+		v := values[0]
+		values = values[1:]
+		return v, nil
+	}
+}
+
+// writeCoverage stores the coverage report even if the program failed
+// as the coverage up to the failure is the interesting part then
+func writeCoverage(cov *aoc2019.IntcodeCoverage, code []int64) {
+	f, err := os.Create(cfg.Coverage)
+	if err != nil {
+		log.Printf("Unable to create coverage file: %s", err)
+		return
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(cfg.Coverage, ".html") {
+		err = cov.WriteHTML(f, code)
+	} else {
+		err = cov.WriteText(f, code)
+	}
+
+	if err != nil {
+		log.Printf("Unable to write coverage: %s", err)
+	}
+}
+
+func writeTaint(m *aoc2019.IntcodeMachine) {
+	f, err := os.Create(cfg.TaintFile)
+	if err != nil {
+		log.Printf("Unable to create taint file: %s", err)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for n, o := range m.Taint.TaintedOutputs() {
+		if len(o.Labels) == 0 {
+			fmt.Fprintf(w, "output %d = %d\n", n, o.Value)
+			continue
+		}
+		fmt.Fprintf(w, "output %d = %d <- %s\n", n, o.Value, strings.Join(o.Labels, ", "))
+	}
+
+	for addr, v := range m.Memory() {
+		if l := m.Taint.MemoryLabels(int64(addr)); l != nil {
+			fmt.Fprintf(w, "memory %d = %d <- %s\n", addr, v, strings.Join(l, ", "))
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		log.Printf("Unable to write taint report: %s", err)
+	}
+}
+
+func writeOutput(w io.Writer, v *big.Int) {
+	if cfg.ASCII && v.IsInt64() && v.Int64() >= 0 && v.Int64() < 128 {
+		fmt.Fprintf(w, "%c", v.Int64())
+		return
+	}
+	fmt.Fprintln(w, v)
+}
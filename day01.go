@@ -2,7 +2,7 @@ package aoc2019
 
 import (
 	"bufio"
-	"os"
+	"io"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -24,21 +24,11 @@ func calculateDay1FuelForMassRecurse(mass int64) int64 {
 	return sumFuel
 }
 
-func solveDay1FromInput(inFile string, sumFn func(int64) int64) (int64, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return 0, errors.Wrap(err, "Unable to open input file")
-	}
-	defer f.Close()
-
+func solveDay1FromInput(r io.Reader, sumFn func(int64) int64) (int64, error) {
 	var sumFuel int64
 
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		if scanner.Err() != nil {
-			return 0, errors.Wrap(err, "Unable to scan file")
-		}
-
 		mass, err := strconv.ParseInt(scanner.Text(), 10, 64)
 		if err != nil {
 			return 0, errors.Wrapf(err, "Unable to parse integer input %q", scanner.Text())
@@ -47,13 +37,17 @@ func solveDay1FromInput(inFile string, sumFn func(int64) int64) (int64, error) {
 		sumFuel += sumFn(mass)
 	}
 
-	return sumFuel, nil
+	return sumFuel, errors.Wrap(scanner.Err(), "Unable to scan input")
+}
+
+func solveDay1Part1(r io.Reader) (int64, error) {
+	return solveDay1FromInput(r, calculateDay1FuelForMass)
 }
 
-func solveDay1Part1(inFile string) (int64, error) {
-	return solveDay1FromInput(inFile, calculateDay1FuelForMass)
+func solveDay1Part2(r io.Reader) (int64, error) {
+	return solveDay1FromInput(r, calculateDay1FuelForMassRecurse)
 }
 
-func solveDay1Part2(inFile string) (int64, error) {
-	return solveDay1FromInput(inFile, calculateDay1FuelForMassRecurse)
+func init() {
+	registerSolver(1, solverParts{int64Part(solveDay1Part1), int64Part(solveDay1Part2)})
 }
@@ -1,8 +1,13 @@
 package aoc2019
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestCalculateDay1_Examples(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
 	for mass, expFuel := range map[int64]int64{
 		12:     2,
 		14:     2,
@@ -25,20 +30,42 @@ func TestCalculateDay1_Examples(t *testing.T) {
 	}
 }
 
+func TestCalculateDay1_Reader(t *testing.T) {
+	fuel, err := solveDay1Part1(strings.NewReader("12\n14\n1969\n100756\n"))
+	if err != nil {
+		t.Fatalf("Day 1 solver failed: %s", err)
+	}
+
+	if fuel != 34241 {
+		t.Errorf("Unexpected fuel for example masses: exp=34241 got=%d", fuel)
+	}
+
+	if _, err = solveDay1Part1(strings.NewReader("12\nfoo\n")); err == nil {
+		t.Error("Invalid mass did not fail")
+	}
+}
+
 func TestCalculateDay1_Part1(t *testing.T) {
-	fuel, err := solveDay1Part1("day01_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	fuel, err := solveDay1Part1(readInput(t, "day01_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 1 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 1 Part 1: %d", fuel)
+	checkAnswer(t, 1, 1, NumberAnswer(fuel))
 }
 
 func TestCalculateDay1_Part2(t *testing.T) {
-	fuel, err := solveDay1Part2("day01_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	fuel, err := solveDay1Part2(readInput(t, "day01_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 1 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 1 Part 2: %d", fuel)
+	checkAnswer(t, 1, 2, NumberAnswer(fuel))
 }
+
+func BenchmarkCalculateDay1_Part1(b *testing.B) { benchmarkSolver(b, 1, 1) }
+func BenchmarkCalculateDay1_Part2(b *testing.B) { benchmarkSolver(b, 1, 2) }
@@ -1,20 +1,21 @@
 package aoc2019
 
 import (
+	"io"
 	"io/ioutil"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
-func parseDay02Intcode(code string) ([]int64, error) { return parseIntcode(code) }
+func parseDay02Intcode(code string) ([]int64, error) { return ParseIntcode(code) }
 
 func executeDay02Intcode(code []int64) ([]int64, error) {
 	return executeIntcode(code, nil, nil) // Day02 intcode may not contain I/O
 }
 
-func solveDay2Part1(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay2Part1(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
@@ -37,39 +38,40 @@ func solveDay2Part1(inFile string) (int64, error) {
 	return code[0], nil
 }
 
-func solveDay2Part2(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay2Part2(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	const expectedResult int64 = 19690720
-
-	for noun := int64(0); noun < 100; noun++ {
-		for verb := int64(0); verb < 100; verb++ {
-
-			// Re-initialize "memory"
-			code, err := parseDay02Intcode(strings.TrimSpace(string(raw)))
-			if err != nil {
-				return 0, errors.Wrap(err, "Unable to parse Intcode")
-			}
-
-			// Modify code
-			code[1] = noun
-			code[2] = verb
+	code, err := parseDay02Intcode(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to parse Intcode")
+	}
 
-			// Execute Intcode
-			code, err = executeDay02Intcode(code)
-			if err != nil {
-				return 0, errors.Wrap(err, "Unable to execute Intcode")
-			}
+	const expectedResult int64 = 19690720
 
-			if code[0] == expectedResult {
-				return 100*noun + verb, nil
-			}
+	res, err := solveIntcode(intcodeSolveParams{
+		Code: code,
+		Symbols: []intcodeSymbol{
+			{Name: "noun", Addr: 1, Min: 0, Max: 99},
+			{Name: "verb", Addr: 2, Min: 0, Max: 99},
+		},
+		Target: 0,
+		Value:  expectedResult,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to solve for inputs")
+	}
 
-		}
+	if len(res.Solutions) == 0 {
+		return 0, errors.New("No valid result was found")
 	}
 
-	return 0, errors.New("No valid result was found")
+	var noun, verb = res.Solutions[0]["noun"], res.Solutions[0]["verb"]
+	return 100*noun + verb, nil
+}
+
+func init() {
+	registerSolver(2, solverParts{int64Part(solveDay2Part1), int64Part(solveDay2Part2)})
 }
@@ -1,7 +1,11 @@
 package aoc2019
 
 import (
+	"context"
+	"io/ioutil"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -28,20 +32,96 @@ func TestExecuteDay02Intcode(t *testing.T) {
 	}
 }
 
+func TestDay02TaintNounVerb(t *testing.T) {
+	raw, err := ioutil.ReadFile("day02_input.txt")
+	if err != nil {
+		t.Fatalf("Unable to read input: %s", err)
+	}
+
+	code, err := parseDay02Intcode(strings.TrimSpace(string(raw)))
+	if err != nil {
+		t.Fatalf("Parsing Intcode failed: %s", err)
+	}
+
+	code[1], code[2] = 12, 2
+
+	m := NewIntcodeMachine(code)
+	m.Taint = NewIntcodeTaintTracker()
+	for addr, label := range map[int64]string{1: "noun", 2: "verb"} {
+		if err := m.Taint.LabelMemory(addr, label); err != nil {
+			t.Fatalf("Unable to label memory: %s", err)
+		}
+	}
+
+	if err := m.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if l := m.Taint.MemoryLabels(0); !reflect.DeepEqual(l, []string{"noun", "verb"}) {
+		t.Errorf("Unexpected labels for code[0]: %v", l)
+	}
+}
+
+func TestDay02SymbolicFormula(t *testing.T) {
+	raw, err := ioutil.ReadFile("day02_input.txt")
+	if err != nil {
+		t.Fatalf("Unable to read input: %s", err)
+	}
+
+	code, err := parseDay02Intcode(strings.TrimSpace(string(raw)))
+	if err != nil {
+		t.Fatalf("Parsing Intcode failed: %s", err)
+	}
+
+	res, err := solveIntcode(intcodeSolveParams{
+		Code: code,
+		Symbols: []intcodeSymbol{
+			{Name: "noun", Addr: 1, Min: 0, Max: 99},
+			{Name: "verb", Addr: 2, Min: 0, Max: 99},
+		},
+		Value: 19690720,
+	})
+	if err != nil {
+		t.Fatalf("Solving failed: %s", err)
+	}
+
+	t.Logf("Day 2 formula: %s", res.Formula)
+
+	if !regexp.MustCompile(`^code\[0\] = [0-9]+\*noun \+ verb \+ [0-9]+$`).MatchString(res.Formula) {
+		t.Errorf("Unexpected formula: %s", res.Formula)
+	}
+
+	if res.Method != "linear" || len(res.Solutions) == 0 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+
+	code[1], code[2] = res.Solutions[0]["noun"], res.Solutions[0]["verb"]
+	if mem, err := executeDay02Intcode(code); err != nil || mem[0] != 19690720 {
+		t.Errorf("Solution does not produce the target: %v (%v)", mem[0], err)
+	}
+}
+
 func TestCalculateDay2_Part1(t *testing.T) {
-	codeP0, err := solveDay2Part1("day02_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	codeP0, err := solveDay2Part1(readInput(t, "day02_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 2 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 2 Part 1: %d", codeP0)
+	checkAnswer(t, 2, 1, NumberAnswer(codeP0))
 }
 
 func TestCalculateDay2_Part2(t *testing.T) {
-	result, err := solveDay2Part2("day02_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	result, err := solveDay2Part2(readInput(t, "day02_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 2 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 2 Part 2: %d", result)
+	checkAnswer(t, 2, 2, NumberAnswer(result))
 }
+
+func BenchmarkCalculateDay2_Part1(b *testing.B) { benchmarkSolver(b, 2, 1) }
+func BenchmarkCalculateDay2_Part2(b *testing.B) { benchmarkSolver(b, 2, 2) }
@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"image/png"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
@@ -233,8 +234,8 @@ func parseDay3LineDefinition(definition string, startX, startY int) (day3Line, e
 	return out, nil
 }
 
-func solveDay3Part1(inFile string) (int, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay3Part1(r io.Reader) (int, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
@@ -253,8 +254,8 @@ func solveDay3Part1(inFile string) (int, error) {
 	return getDay3MinIntersectionDistance(l1, l2, 0, 0), nil
 }
 
-func solveDay3Part2(inFile string) (int, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay3Part2(r io.Reader) (int, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
@@ -272,3 +273,7 @@ func solveDay3Part2(inFile string) (int, error) {
 
 	return getDay3MinIntersectionSteps(l1, l2), nil
 }
+
+func init() {
+	registerSolver(3, solverParts{intPart(solveDay3Part1), intPart(solveDay3Part2)})
+}
@@ -84,19 +84,26 @@ func TestDay3DebugRender(t *testing.T) {
 }
 
 func TestCalculateDay3_Part1(t *testing.T) {
-	codeP0, err := solveDay3Part1("day03_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	codeP0, err := solveDay3Part1(readInput(t, "day03_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 3 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 3 Part 1: %d", codeP0)
+	checkAnswer(t, 3, 1, NumberAnswer(int64(codeP0)))
 }
 
 func TestCalculateDay3_Part2(t *testing.T) {
-	codeP0, err := solveDay3Part2("day03_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	codeP0, err := solveDay3Part2(readInput(t, "day03_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 3 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 3 Part 2: %d", codeP0)
+	checkAnswer(t, 3, 2, NumberAnswer(int64(codeP0)))
 }
+
+func BenchmarkCalculateDay3_Part1(b *testing.B) { benchmarkSolver(b, 3, 1) }
+func BenchmarkCalculateDay3_Part2(b *testing.B) { benchmarkSolver(b, 3, 2) }
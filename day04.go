@@ -1,6 +1,7 @@
 package aoc2019
 
 import (
+	"io"
 	"io/ioutil"
 	"strconv"
 	"strings"
@@ -87,10 +88,10 @@ func day4IsValidPasswordPart2(in, min, max int64) bool {
 	return count == 2
 }
 
-func solveDay4WithFunction(inFile string, vf func(int64, int64, int64) bool) (int, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay4WithFunction(r io.Reader, vf func(int64, int64, int64) bool) (int, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to read input file")
+		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
 	pts := strings.Split(strings.TrimSpace(string(raw)), "-")
@@ -114,10 +115,14 @@ func solveDay4WithFunction(inFile string, vf func(int64, int64, int64) bool) (in
 	return count, nil
 }
 
-func solveDay4Part1(inFile string) (int, error) {
-	return solveDay4WithFunction(inFile, day4IsValidPassword)
+func solveDay4Part1(r io.Reader) (int, error) {
+	return solveDay4WithFunction(r, day4IsValidPassword)
 }
 
-func solveDay4Part2(inFile string) (int, error) {
-	return solveDay4WithFunction(inFile, day4IsValidPasswordPart2)
+func solveDay4Part2(r io.Reader) (int, error) {
+	return solveDay4WithFunction(r, day4IsValidPasswordPart2)
+}
+
+func init() {
+	registerSolver(4, solverParts{intPart(solveDay4Part1), intPart(solveDay4Part2)})
 }
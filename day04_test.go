@@ -40,19 +40,26 @@ func TestDay4ValidPassword(t *testing.T) {
 }
 
 func TestCalculateDay4_Part1(t *testing.T) {
-	count, err := solveDay4Part1("day04_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay4Part1(readInput(t, "day04_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 4 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 4 Part 1: %d", count)
+	checkAnswer(t, 4, 1, NumberAnswer(int64(count)))
 }
 
 func TestCalculateDay4_Part2(t *testing.T) {
-	count, err := solveDay4Part2("day04_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay4Part2(readInput(t, "day04_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 4 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 4 Part 2: %d", count)
+	checkAnswer(t, 4, 2, NumberAnswer(int64(count)))
 }
+
+func BenchmarkCalculateDay4_Part1(b *testing.B) { benchmarkSolver(b, 4, 1) }
+func BenchmarkCalculateDay4_Part2(b *testing.B) { benchmarkSolver(b, 4, 2) }
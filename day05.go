@@ -1,44 +1,40 @@
 package aoc2019
 
 import (
+	"io"
 	"io/ioutil"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
-func solveDay5FromFile(inFile string, diagProgram int64) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay5FromInput(r io.Reader, diagProgram int64) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse Intcode")
 	}
 
-	var (
-		in      = make(chan int64, 1)
-		out     = make(chan int64, len(code)) // There cannot be more outputs than number of code parts
-		outputs []int64
-	)
-
 	/*
 	 * The TEST diagnostic program will start by requesting from the user
 	 * the ID of the system to test by running an input instruction - provide
 	 * it 1, the ID for the ship's air conditioner unit.
 	 */
-	in <- diagProgram
-
-	if _, err := executeIntcode(code, in, out); err != nil {
+	res, err := RunIntcode(IntcodeParams{Code: code, Inputs: []int64{diagProgram}})
+	if err != nil {
 		return 0, errors.Wrap(err, "Program execution failed")
 	}
 
-	for o := range out {
-		outputs = append(outputs, o)
+	if res.Reason != IntcodeHaltExit {
+		return 0, errors.Errorf("Program stopped unexpectedly: %s", res.Reason)
 	}
 
+	var outputs = res.Outputs
+
 	if len(outputs) < 1 {
 		return 0, errors.New("Program did not yield any output")
 	}
@@ -52,21 +48,25 @@ func solveDay5FromFile(inFile string, diagProgram int64) (int64, error) {
 	return outputs[len(outputs)-1], nil
 }
 
-func solveDay5Part1(inFile string) (int64, error) {
+func solveDay5Part1(r io.Reader) (int64, error) {
 	/*
 	 * The TEST diagnostic program will start by requesting from the user
 	 * the ID of the system to test by running an input instruction - provide
 	 * it 1, the ID for the ship's air conditioner unit.
 	 */
-	return solveDay5FromFile(inFile, 1)
+	return solveDay5FromInput(r, 1)
 }
 
-func solveDay5Part2(inFile string) (int64, error) {
+func solveDay5Part2(r io.Reader) (int64, error) {
 	/*
 	 * This time, when the TEST diagnostic program runs its input
 	 * instruction to get the ID of the system to test, provide it 5,
 	 * the ID for the ship's thermal radiator controller. This
 	 * diagnostic test suite only outputs one number, the diagnostic code.
 	 */
-	return solveDay5FromFile(inFile, 5)
+	return solveDay5FromInput(r, 5)
+}
+
+func init() {
+	registerSolver(5, solverParts{int64Part(solveDay5Part1), int64Part(solveDay5Part2)})
 }
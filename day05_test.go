@@ -3,19 +3,26 @@ package aoc2019
 import "testing"
 
 func TestCalculateDay5_Part1(t *testing.T) {
-	result, err := solveDay5Part1("day05_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	result, err := solveDay5Part1(readInput(t, "day05_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 5 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 5 Part 1: %d", result)
+	checkAnswer(t, 5, 1, NumberAnswer(result))
 }
 
 func TestCalculateDay5_Part2(t *testing.T) {
-	count, err := solveDay5Part2("day05_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay5Part2(readInput(t, "day05_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 5 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 5 Part 2: %d", count)
+	checkAnswer(t, 5, 2, NumberAnswer(count))
 }
+
+func BenchmarkCalculateDay5_Part1(b *testing.B) { benchmarkSolver(b, 5, 1) }
+func BenchmarkCalculateDay5_Part2(b *testing.B) { benchmarkSolver(b, 5, 2) }
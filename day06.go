@@ -3,7 +3,6 @@ package aoc2019
 import (
 	"bufio"
 	"io"
-	"os"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -88,14 +87,8 @@ func day06ParseOrbitMap(localMap io.Reader) (day06OrbitMap, error) {
 	return m, nil
 }
 
-func solveDay6Part1(inFile string) (int, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return 0, errors.Wrap(err, "Unable to open input file")
-	}
-	defer f.Close()
-
-	oMap, err := day06ParseOrbitMap(f)
+func solveDay6Part1(r io.Reader) (int, error) {
+	oMap, err := day06ParseOrbitMap(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse orbit map")
 	}
@@ -108,14 +101,8 @@ func solveDay6Part1(inFile string) (int, error) {
 	return c, nil
 }
 
-func solveDay6Part2(inFile string) (int, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return 0, errors.Wrap(err, "Unable to open input file")
-	}
-	defer f.Close()
-
-	oMap, err := day06ParseOrbitMap(f)
+func solveDay6Part2(r io.Reader) (int, error) {
+	oMap, err := day06ParseOrbitMap(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse orbit map")
 	}
@@ -126,3 +113,7 @@ func solveDay6Part2(inFile string) (int, error) {
 	// Distance is 2 too high as we're not a planet but a ship cycling a planet: Close enough
 	return dist - 2, nil
 }
+
+func init() {
+	registerSolver(6, solverParts{intPart(solveDay6Part1), intPart(solveDay6Part2)})
+}
@@ -55,19 +55,26 @@ func TestDay6OrbitMapCommonPlanet(t *testing.T) {
 }
 
 func TestCalculateDay6_Part1(t *testing.T) {
-	count, err := solveDay6Part1("day06_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay6Part1(readInput(t, "day06_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 6 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 6 Part 1: %d", count)
+	checkAnswer(t, 6, 1, NumberAnswer(int64(count)))
 }
 
 func TestCalculateDay6_Part2(t *testing.T) {
-	count, err := solveDay6Part2("day06_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay6Part2(readInput(t, "day06_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 6 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 6 Part 2: %d", count)
+	checkAnswer(t, 6, 2, NumberAnswer(int64(count)))
 }
+
+func BenchmarkCalculateDay6_Part1(b *testing.B) { benchmarkSolver(b, 6, 1) }
+func BenchmarkCalculateDay6_Part2(b *testing.B) { benchmarkSolver(b, 6, 2) }
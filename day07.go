@@ -1,102 +1,136 @@
 package aoc2019
 
 import (
+	"context"
+	"io"
 	"io/ioutil"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
-func day07TestMaxOutputFromChain(code []int64, chainStart, chainEnd int, looped bool) int64 {
+// day07RunChain runs the amplifiers one after another on machines of the
+// batch, each one until it needs the signal of its predecessor
+func day07RunChain(batch *intcodeBatch, seq []int64, looped bool) (int64, error) {
+	var amps = make([]*IntcodeMachine, len(seq))
+	for i, phase := range seq {
+		amps[i] = batch.acquire()
+		defer batch.release(amps[i])
+		amps[i].Feed(phase)
+	}
+
+	var signal int64 // Input signal
+	for {
+		for i, m := range amps {
+			var n = len(m.Outputs())
+
+			m.Feed(signal)
+			if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil && err != ErrIntcodeInputExhausted {
+				return 0, errors.Wrapf(err, "Amplifier %d failed", i)
+			}
+
+			if len(m.Outputs()) == n {
+				return 0, errors.Errorf("Amplifier %d produced no signal", i)
+			}
+			signal = m.Outputs()[len(m.Outputs())-1]
+		}
+
+		if !looped || amps[len(amps)-1].Exited() {
+			return signal, nil
+		}
+	}
+}
+
+func day07TestMaxOutputFromChain(code []int64, chainStart, chainEnd int, looped bool) (int64, error) {
 	var (
 		chainLen = chainEnd - chainStart + 1
-		permuts  [][]int64
-		permute  func(a []int64, k int)
+		permute  func(emit func(intcodeSearchCandidate) bool, a []int64, k int) bool
 		rootSeq  = make([]int64, chainLen)
+		batch    = newIntcodeBatch(code)
 	)
 
-	permute = func(a []int64, k int) {
+	permute = func(emit func(intcodeSearchCandidate) bool, a []int64, k int) bool {
 		if k == len(a) {
-			permuts = append(permuts, append([]int64{}, a...))
-		} else {
-			for i := k; i < len(rootSeq); i++ {
-				a[k], a[i] = a[i], a[k]
-				permute(a, k+1)
-				a[k], a[i] = a[i], a[k]
+			return emit(intcodeSearchCandidate{Inputs: append([]int64{}, a...)})
+		}
+
+		for i := k; i < len(rootSeq); i++ {
+			a[k], a[i] = a[i], a[k]
+			if !permute(emit, a, k+1) {
+				return false
 			}
+			a[k], a[i] = a[i], a[k]
 		}
+
+		return true
 	}
 
 	for i := range rootSeq {
 		rootSeq[i] = int64(chainStart + i)
 	}
-	permute(rootSeq, 0)
 
-	var maxOutput int64
-	for _, seq := range permuts {
-		var (
-			commInChans = make([]chan int64, chainLen)
-			commOut     = make(chan int64, 2)
-		)
-
-		// Create channels
-		for i := range commInChans {
-			commInChans[i] = make(chan int64, 2)
-		}
-
-		// Build execution chain
-		for i := 0; i < chainLen-1; i++ {
-			go executeIntcode(cloneIntcode(code), commInChans[i], commInChans[i+1])
-		}
-		go executeIntcode(cloneIntcode(code), commInChans[chainLen-1], commOut)
-
-		// Initialize chain
-		for i, v := range seq {
-			commInChans[i] <- v
-		}
-		commInChans[0] <- 0 // Input signal
-
-		var lastOutput int64
-		for r := range commOut {
-			lastOutput = r
-			if looped {
-				commInChans[0] <- r
+	// Every phase sequence is a candidate, collect the chain output of all
+	results, stats, err := searchIntcode(intcodeSearchParams{
+		Code:       code,
+		Candidates: func(emit func(intcodeSearchCandidate) bool) { permute(emit, rootSeq, 0) },
+		Evaluate: func(_ []int64, c intcodeSearchCandidate) intcodeSearchResult {
+			signal, err := day07RunChain(batch, c.Inputs, looped)
+			return intcodeSearchResult{
+				Candidate: c,
+				Err:       err,
+				Outputs:   []int64{signal},
 			}
-		}
+		},
+		Match:   func(intcodeSearchResult) bool { return true },
+		FindAll: true,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to search phase sequences")
+	}
+
+	if stats.Failed > 0 {
+		return 0, errors.Errorf("%d of %d phase sequences failed", stats.Failed, stats.Evaluated)
+	}
 
+	var maxOutput int64
+	for _, res := range results {
 		// Test output of last execution
-		if lastOutput > maxOutput {
-			maxOutput = lastOutput
+		if res.Outputs[0] > maxOutput {
+			maxOutput = res.Outputs[0]
 		}
 	}
 
-	return maxOutput
+	return maxOutput, nil
 }
 
-func solveDay7Part1(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay7Part1(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to read input file")
+		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode program")
 	}
 
-	return day07TestMaxOutputFromChain(code, 0, 4, false), nil
+	return day07TestMaxOutputFromChain(code, 0, 4, false)
 }
 
-func solveDay7Part2(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay7Part2(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to read input file")
+		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode program")
 	}
 
-	return day07TestMaxOutputFromChain(code, 5, 9, true), nil
+	return day07TestMaxOutputFromChain(code, 5, 9, true)
+}
+
+func init() {
+	registerSolver(7, solverParts{int64Part(solveDay7Part1), int64Part(solveDay7Part2)})
 }
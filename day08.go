@@ -3,10 +3,9 @@ package aoc2019
 import (
 	"image"
 	"image/color"
-	"image/png"
+	"io"
 	"io/ioutil"
 	"math"
-	"os"
 	"strconv"
 	"strings"
 
@@ -79,29 +78,23 @@ func day08ParseToLayers(in string, w, h int) ([]day08Layer, error) {
 }
 
 func day08RenderLayer(layer day08Layer) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, layer.w, layer.h))
+	fb := NewIntcodeFramebuffer(image.Rect(0, 0, layer.w, layer.h))
 
 	for idx, px := range layer.data {
-		var (
-			x = idx % layer.w
-			y = idx / layer.w
-		)
-
-		switch px {
-		case day08ColorBlack:
-			img.SetRGBA(x, y, color.RGBA{0x0, 0x0, 0x0, 0xff})
-		case day08ColorWhite:
-			img.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
-		}
+		fb.Set(idx%layer.w, idx/layer.w, int64(px))
 	}
 
-	return img
+	// Transparent pixels are not in the palette
+	return fb.Image(map[int64]color.Color{
+		int64(day08ColorBlack): color.RGBA{0x0, 0x0, 0x0, 0xff},
+		int64(day08ColorWhite): color.RGBA{0xff, 0xff, 0xff, 0xff},
+	})
 }
 
-func solveDay8Part1(inFile string) (int, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay8Part1(r io.Reader) (int, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to read input file")
+		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
 	layers, err := day08ParseToLayers(strings.TrimSpace(string(raw)), 25, 6)
@@ -123,24 +116,20 @@ func solveDay8Part1(inFile string) (int, error) {
 	return layer.countNumber(1) * layer.countNumber(2), nil
 }
 
-func solveDay8Part2(inFile string) error {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay8Part2(r io.Reader) (image.Image, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return errors.Wrap(err, "Unable to read input file")
+		return nil, errors.Wrap(err, "Unable to read input")
 	}
 
 	layers, err := day08ParseToLayers(strings.TrimSpace(string(raw)), 25, 6)
 	if err != nil {
-		return errors.Wrap(err, "Unable to parse layers")
+		return nil, errors.Wrap(err, "Unable to parse layers")
 	}
 
-	img := day08RenderLayer(day08ComposeLayers(layers))
-
-	f, err := os.Create("day08_image.png")
-	if err != nil {
-		return errors.Wrap(err, "Unable to open result file")
-	}
-	defer f.Close()
+	return day08RenderLayer(day08ComposeLayers(layers)), nil
+}
 
-	return errors.Wrap(png.Encode(f, img), "Unable to render image")
+func init() {
+	registerSolver(8, solverParts{intPart(solveDay8Part1), imagePart(solveDay8Part2)})
 }
@@ -42,18 +42,30 @@ func TestDay08ParseToLayers(t *testing.T) {
 }
 
 func TestCalculateDay8_Part1(t *testing.T) {
-	count, err := solveDay8Part1("day08_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay8Part1(readInput(t, "day08_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 8 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 8 Part 1: %d", count)
+	checkAnswer(t, 8, 1, NumberAnswer(int64(count)))
 }
 
 func TestCalculateDay8_Part2(t *testing.T) {
-	if err := solveDay8Part2("day08_input.txt"); err != nil {
+	defer checkGoroutineLeaks(t)()
+
+	img, err := solveDay8Part2(readInput(t, "day08_input.txt"))
+	if err != nil {
 		t.Fatalf("Day 8 solver failed: %s", err)
 	}
 
-	t.Log("Solution Day 8 Part 2: See day08_image.png")
+	if err = savePNG("day08_image.png", img); err != nil {
+		t.Fatalf("Unable to save image: %s", err)
+	}
+
+	checkAnswer(t, 8, 2, ImageAnswer(img))
 }
+
+func BenchmarkCalculateDay8_Part1(b *testing.B) { benchmarkSolver(b, 8, 1) }
+func BenchmarkCalculateDay8_Part2(b *testing.B) { benchmarkSolver(b, 8, 2) }
@@ -1,46 +1,43 @@
 package aoc2019
 
 import (
+	"io"
 	"io/ioutil"
 	"strings"
-	"sync"
 
 	"github.com/pkg/errors"
 )
 
-func solveDay9Part1(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+// day09RunBoost runs the BOOST program in the given mode and returns
+// all of its outputs
+func day09RunBoost(code []int64, mode int64) ([]int64, error) {
+	res, err := RunIntcode(IntcodeParams{Code: code, Inputs: []int64{mode}})
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to read input file")
+		return nil, errors.Wrap(err, "Unable to execute intcode")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	if res.Reason != IntcodeHaltExit {
+		return nil, errors.Errorf("Program stopped unexpectedly: %s", res.Reason)
+	}
+
+	return res.Outputs, nil
+}
+
+func solveDay9Part1(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to parse intcode program")
+		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	var (
-		inChan  = make(chan int64, 1)
-		outChan = make(chan int64, 1)
-		output  []int64
-		wg      sync.WaitGroup
-	)
-
-	inChan <- 1
-	wg.Add(1)
-
-	go func() {
-		for v := range outChan {
-			output = append(output, v)
-		}
-		wg.Done()
-	}()
-
-	if _, err := executeIntcode(code, inChan, outChan); err != nil {
-		return 0, errors.Wrap(err, "Unable to execute intcode")
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to parse intcode program")
 	}
 
-	wg.Wait()
+	output, err := day09RunBoost(code, 1)
+	if err != nil {
+		return 0, err
+	}
 
 	if len(output) != 1 {
 		return 0, errors.Errorf("Got malfunction information: %+v", output)
@@ -49,27 +46,29 @@ func solveDay9Part1(inFile string) (int64, error) {
 	return output[0], nil
 }
 
-func solveDay9Part2(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay9Part2(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, errors.Wrap(err, "Unable to read input file")
+		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode program")
 	}
 
-	var (
-		inChan  = make(chan int64, 1)
-		outChan = make(chan int64, 1)
-	)
-
-	inChan <- 2
+	output, err := day09RunBoost(code, 2)
+	if err != nil {
+		return 0, err
+	}
 
-	if _, err := executeIntcode(code, inChan, outChan); err != nil {
-		return 0, errors.Wrap(err, "Unable to execute intcode")
+	if len(output) != 1 {
+		return 0, errors.Errorf("Unexpected output: %+v", output)
 	}
 
-	return <-outChan, nil
+	return output[0], nil
+}
+
+func init() {
+	registerSolver(9, solverParts{int64Part(solveDay9Part1), int64Part(solveDay9Part2)})
 }
@@ -3,19 +3,26 @@ package aoc2019
 import "testing"
 
 func TestCalculateDay9_Part1(t *testing.T) {
-	codeP0, err := solveDay9Part1("day09_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	codeP0, err := solveDay9Part1(readInput(t, "day09_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 9 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 9 Part 1: %d", codeP0)
+	checkAnswer(t, 9, 1, NumberAnswer(codeP0))
 }
 
 func TestCalculateDay9_Part2(t *testing.T) {
-	result, err := solveDay9Part2("day09_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	result, err := solveDay9Part2(readInput(t, "day09_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 9 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 9 Part 2: %d", result)
+	checkAnswer(t, 9, 2, NumberAnswer(result))
 }
+
+func BenchmarkCalculateDay9_Part1(b *testing.B) { benchmarkSolver(b, 9, 1) }
+func BenchmarkCalculateDay9_Part2(b *testing.B) { benchmarkSolver(b, 9, 2) }
@@ -2,52 +2,43 @@ package aoc2019
 
 import (
 	"io"
-	"io/ioutil"
 	"math"
-	"os"
 	"sort"
 
 	"github.com/pkg/errors"
 )
 
-type day10MonitorGrid struct {
-	asteroidMap []byte
-	width       int
-}
+type day10MonitorGrid struct{ *DenseGrid }
 
 func (d day10MonitorGrid) asteroidCount() int {
 	var count int
-	for _, c := range d.asteroidMap {
-		if c == '#' {
+	d.Each(func(p Point, v int64) {
+		if v == '#' {
 			count++
 		}
-	}
+	})
 
 	return count
 }
 
 func (d day10MonitorGrid) clone() *day10MonitorGrid {
-	var grid = &day10MonitorGrid{asteroidMap: make([]byte, len(d.asteroidMap)), width: d.width}
-	for i, c := range d.asteroidMap {
-		grid.asteroidMap[i] = c
-	}
-	return grid
+	return &day10MonitorGrid{d.Clone()}
 }
 
-func (d day10MonitorGrid) getAsteroidPositions() []int {
-	var knownPositions []int
-	for i, c := range d.asteroidMap {
-		if c != '#' {
+func (d day10MonitorGrid) getAsteroidPositions() []Point {
+	var knownPositions []Point
+	d.Each(func(p Point, v int64) {
+		if v != '#' {
 			// Not an asteroid, don't care
-			continue
+			return
 		}
 
-		knownPositions = append(knownPositions, i)
-	}
+		knownPositions = append(knownPositions, p)
+	})
 	return knownPositions
 }
 
-func (d day10MonitorGrid) getCleanedGrid(x, y int) *day10MonitorGrid {
+func (d day10MonitorGrid) getCleanedGrid(observ Point) *day10MonitorGrid {
 	// Clone the map to work on
 	var grid = d.clone()
 
@@ -55,45 +46,45 @@ func (d day10MonitorGrid) getCleanedGrid(x, y int) *day10MonitorGrid {
 	var knownPositions = grid.getAsteroidPositions()
 
 	// Mark observer (does not count into observable asteroids)
-	grid.asteroidMap[grid.coordToPos(x, y)] = '@'
+	grid.Set(observ, '@')
 
 	// Iterate all positions and remove covered (invisible) asteroids
 	for _, pos := range knownPositions {
-		var aX, aY = d.posToCoord(pos)
-		if grid.isObstructed(x, y, aX, aY) {
-			grid.asteroidMap[pos] = '-'
+		if grid.isObstructed(observ, pos) {
+			grid.Set(pos, '-')
 		}
 	}
 
 	return grid
 }
 
-func (d *day10MonitorGrid) isObstructed(observX, observY, x, y int) bool {
-	var distX, distY = x - observX, y - observY
+func (d *day10MonitorGrid) isObstructed(observ, asteroid Point) bool {
+	var dist = asteroid.Sub(observ)
 
-	if distX == 0 && distY == 0 {
+	if dist.X == 0 && dist.Y == 0 {
 		// No steps, observer equals asteroid, needless calculation
 		return false
 	}
 
 	var (
-		div          = int(math.Abs(float64(greatestCommonDivisor(int64(distX), int64(distY)))))
-		stepX, stepY = distX / div, distY / div
+		div  = int(math.Abs(float64(greatestCommonDivisor(int64(dist.X), int64(dist.Y)))))
+		step = Pt(dist.X/div, dist.Y/div)
 	)
 
 	for i := 1; i < math.MaxInt64; i++ {
-		var rPosX, rPosY = observX + stepX*i, observY + stepY*i
+		var rPos = observ.Add(step.Mul(i))
 
-		if rPosX < 0 || rPosX >= d.width || rPosY < 0 || rPosY >= len(d.asteroidMap)/d.width {
-			// Position outside grid, stop searching
-			panic(errors.Errorf("Observed position ran out of bounds (obsX=%d obsY=%d x=%d y=%d div=%d stepX=%d stepY=%d)", observX, observY, x, y, div, stepX, stepY))
+		if rPos == asteroid {
+			return false
 		}
 
-		if rPosX == x && rPosY == y {
-			return false
+		v, ok := d.Get(rPos)
+		if !ok {
+			// Position outside grid, stop searching
+			panic(errors.Errorf("Observed position ran out of bounds (obs=%v asteroid=%v div=%d step=%v)", observ, asteroid, div, step))
 		}
 
-		if d.asteroidMap[d.coordToPos(rPosX, rPosY)] == '#' {
+		if v == '#' {
 			return true
 		}
 	}
@@ -101,8 +92,6 @@ func (d *day10MonitorGrid) isObstructed(observX, observY, x, y int) bool {
 	panic(errors.Errorf("Unreachable end was reached"))
 }
 
-func (d day10MonitorGrid) coordToPos(x, y int) int       { return y*d.width + x }
-func (d day10MonitorGrid) posToCoord(pos int) (int, int) { return pos % d.width, pos / d.width }
 func (d day10MonitorGrid) step2deg(x, y int) float64 {
 	rad := math.Atan2(float64(x), float64(y))
 	deg := rad * (180 / math.Pi)
@@ -110,47 +99,26 @@ func (d day10MonitorGrid) step2deg(x, y int) float64 {
 }
 
 func day10ReadAsteroidMap(in io.Reader) (*day10MonitorGrid, error) {
-	var grid = &day10MonitorGrid{}
-
-	raw, err := ioutil.ReadAll(in)
+	grid, err := ParseDenseGrid(in)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to read asteroid map")
 	}
 
-	for _, c := range raw {
-		if c == '\n' {
-			if grid.width == 0 {
-				grid.width = len(grid.asteroidMap)
-			}
-			// Skip newlines for our representation
-			continue
-		}
-
-		grid.asteroidMap = append(grid.asteroidMap, c)
-	}
-
-	return grid, nil
+	return &day10MonitorGrid{grid}, nil
 }
 
-func solveDay10Part1Coordinate(inFile string) (*day10MonitorGrid, int, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "Unable to open input file")
-	}
-	defer f.Close()
-
-	grid, err := day10ReadAsteroidMap(f)
+func solveDay10Part1Coordinate(r io.Reader) (*day10MonitorGrid, Point, error) {
+	grid, err := day10ReadAsteroidMap(r)
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "Unable to read asteroid map")
+		return nil, Point{}, errors.Wrap(err, "Unable to read asteroid map")
 	}
 
 	var (
-		bestMonitorPos    int
+		bestMonitorPos    Point
 		bestAsteroidCount int
 	)
 	for _, pos := range grid.getAsteroidPositions() {
-		var aX, aY = grid.posToCoord(pos)
-		rGrid := grid.getCleanedGrid(aX, aY)
+		rGrid := grid.getCleanedGrid(pos)
 
 		if c := rGrid.asteroidCount(); c > bestAsteroidCount {
 			bestMonitorPos = pos
@@ -161,53 +129,57 @@ func solveDay10Part1Coordinate(inFile string) (*day10MonitorGrid, int, error) {
 	return grid, bestMonitorPos, nil
 }
 
-func solveDay10Part1(inFile string) (int, error) {
-	grid, bestMonitorPos, err := solveDay10Part1Coordinate(inFile)
+func solveDay10Part1(r io.Reader) (int, error) {
+	grid, bestMonitorPos, err := solveDay10Part1Coordinate(r)
 	if err != nil {
 		return 0, err
 	}
 
-	var aX, aY = grid.posToCoord(bestMonitorPos)
-	return grid.getCleanedGrid(aX, aY).asteroidCount(), nil
+	return grid.getCleanedGrid(bestMonitorPos).asteroidCount(), nil
 }
 
-func solveDay10Part2(inFile string) (int, error) {
-	grid, bestMonitorPos, err := solveDay10Part1Coordinate(inFile)
+func solveDay10Part2(r io.Reader) (int, error) {
+	grid, monitor, err := solveDay10Part1Coordinate(r)
 	if err != nil {
 		return 0, err
 	}
 
-	var (
-		mX, mY    = grid.posToCoord(bestMonitorPos)
-		destroyed []int
-	)
+	var destroyed []Point
 
 	// Mark monitor / laser -- cannot be destroyed
-	grid.asteroidMap[bestMonitorPos] = 'M'
+	grid.Set(monitor, 'M')
 
 	// Gradually destroy asteroids
 	for grid.asteroidCount() > 0 {
-		asteroidsInSight := grid.getCleanedGrid(mX, mY).getAsteroidPositions()
-		var degPos [][2]int
+		asteroidsInSight := grid.getCleanedGrid(monitor).getAsteroidPositions()
+
+		type degPos struct {
+			pos Point
+			deg int
+		}
+
+		var targets []degPos
 		for _, pos := range asteroidsInSight {
-			var aX, aY = grid.posToCoord(pos)
+			var step = pos.Sub(monitor)
 
-			degPos = append(degPos, [2]int{
-				pos,
-				int(grid.step2deg(aX-mX, aY-mY) * 1000000), // Degree to asteroid in 6-digit precision
+			targets = append(targets, degPos{
+				pos: pos,
+				deg: int(grid.step2deg(step.X, step.Y) * 1000000), // Degree to asteroid in 6-digit precision
 			})
 		}
 
 		// Sort by degree low-to-high -- represents order of destruction
-		sort.Slice(degPos, func(i, j int) bool { return degPos[i][1] < degPos[j][1] })
+		sort.Slice(targets, func(i, j int) bool { return targets[i].deg < targets[j].deg })
 
-		for _, dp := range degPos {
-			grid.asteroidMap[dp[0]] = '*' // Mark asteroids destroyed
-			destroyed = append(destroyed, dp[0])
+		for _, t := range targets {
+			grid.Set(t.pos, '*') // Mark asteroids destroyed
+			destroyed = append(destroyed, t.pos)
 		}
 	}
 
-	destr200X, destr200Y := grid.posToCoord(destroyed[199])
+	return 100*destroyed[199].X + destroyed[199].Y, nil
+}
 
-	return 100*destr200X + destr200Y, nil
+func init() {
+	registerSolver(10, solverParts{intPart(solveDay10Part1), intPart(solveDay10Part2)})
 }
@@ -11,12 +11,12 @@ func TestDay10ReadAsteroidMap(t *testing.T) {
 		t.Fatalf("Asteroid map parser failed: %s", err)
 	}
 
-	if grid.width != 5 {
-		t.Errorf("Wrong width detected: exp=5 got=%d", grid.width)
+	if w := grid.Bounds().Width(); w != 5 {
+		t.Errorf("Wrong width detected: exp=5 got=%d", w)
 	}
 
-	if l := len(grid.asteroidMap); l != 25 {
-		t.Errorf("Wrong length of asteroid map detected: exp=25 got=%d", l)
+	if h := grid.Bounds().Height(); h != 5 {
+		t.Errorf("Wrong height detected: exp=5 got=%d", h)
 	}
 
 	if c := grid.asteroidCount(); c != 10 {
@@ -30,20 +30,22 @@ func TestDay10GetCleanedGrid(t *testing.T) {
 		t.Fatalf("Asteroid map parser failed: %s", err)
 	}
 
-	for expCount, coords := range map[int][2]int{
+	for expCount, pos := range map[int]Point{
 		5: {4, 2},
 		6: {0, 2},
 		7: {1, 0},
 		8: {3, 4},
 	} {
-		rGrid := grid.getCleanedGrid(coords[0], coords[1])
+		rGrid := grid.getCleanedGrid(pos)
 
 		if c := rGrid.asteroidCount(); c != expCount {
-			t.Errorf("Wrong number of asteroids detected: exp=%d got=%d (grid=%s)", expCount, c, rGrid.asteroidMap)
+			t.Errorf("Wrong number of asteroids detected: exp=%d got=%d (grid=\n%s)", expCount, c, GridString(rGrid, day10RenderCell))
 		}
 	}
 }
 
+func day10RenderCell(p Point, v int64, ok bool) rune { return rune(v) }
+
 func TestDay10StepToDeg(t *testing.T) {
 	// Is a function on a day10MonitorGrid, grid itself is not used
 	grid, err := day10ReadAsteroidMap(strings.NewReader(".#..#\n.....\n#####\n....#\n...##"))
@@ -68,19 +70,26 @@ func TestDay10StepToDeg(t *testing.T) {
 }
 
 func TestCalculateDay10_Part1(t *testing.T) {
-	count, err := solveDay10Part1("day10_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay10Part1(readInput(t, "day10_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 10 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 10 Part 1: %d", count)
+	checkAnswer(t, 10, 1, NumberAnswer(int64(count)))
 }
 
 func TestCalculateDay10_Part2(t *testing.T) {
-	res, err := solveDay10Part2("day10_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay10Part2(readInput(t, "day10_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 10 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 10 Part 2: %d", res)
+	checkAnswer(t, 10, 2, NumberAnswer(int64(res)))
 }
+
+func BenchmarkCalculateDay10_Part1(b *testing.B) { benchmarkSolver(b, 10, 1) }
+func BenchmarkCalculateDay10_Part2(b *testing.B) { benchmarkSolver(b, 10, 2) }
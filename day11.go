@@ -1,155 +1,108 @@
 package aoc2019
 
 import (
-	"fmt"
+	"context"
 	"image"
 	"image/color"
-	"image/png"
+	"io"
 	"io/ioutil"
-	"math"
-	"os"
 	"strings"
-	"sync"
 
 	"github.com/pkg/errors"
 )
 
-type day11PaintDirective struct {
-	X, Y  int
-	Color int64
+// day11PaintRecord is the answer of the robot to a scan: the color to
+// paint the current panel and the direction to turn afterwards
+type day11PaintRecord struct {
+	Color     int64
+	TurnRight bool
 }
 
-func (d day11PaintDirective) positionKey() string { return fmt.Sprintf("%d:%d", d.X, d.Y) }
+var day11PaintProtocol = intcodeFrameProtocol{
+	size: 2,
+	decode: func(f intcodeFrame) (interface{}, error) {
+		if f[1] != 0 && f[1] != 1 {
+			return nil, errors.Errorf("Invalid turn %d", f[1])
+		}
+		return day11PaintRecord{Color: f[0], TurnRight: f[1] == 1}, nil
+	},
+}
 
-func day11ExecutePaintRobot(inFile string, startPanelColor int64) ([]day11PaintDirective, error) {
+// day11ExecutePaintRobot runs the robot and returns the colors of all
+// panels painted at least once
+func day11ExecutePaintRobot(r io.Reader, startPanelColor int64) (*SparseGrid, error) {
 	var (
-		posX, posY int
-		direction  int // Clock-hand direction
-		directives = map[string]day11PaintDirective{
-			"0:0": {X: 0, Y: 0, Color: startPanelColor},
-		}
+		pos       Point
+		direction = DirUp
+		panels    = NewSparseGrid()
 	)
 
-	move := func() {
-		switch direction {
-		case 0:
-			posY -= 1
-		case 3:
-			posX += 1
-		case 6:
-			posY += 1
-		case 9:
-			posX -= 1
-		default:
-			panic(errors.Errorf("Invalid direction: %d", direction))
-		}
-	}
-
-	rotate := func(dir int64) {
-		var factor int
-		if dir == 1 {
-			// turn right
-			factor = 1
-		} else {
-			// turn left
-			factor = -1
-		}
-		direction = direction + factor*3
-		if direction < 0 {
-			direction += 12
-		}
-		if direction > 11 {
-			direction -= 12
-		}
-	}
+	panels.Set(pos, startPanelColor)
 
 	// Initialize code
-	rawCode, err := ioutil.ReadFile(inFile)
+	rawCode, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to read intcode")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(rawCode)))
+	code, err := ParseIntcode(strings.TrimSpace(string(rawCode)))
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to parse intcode")
 	}
 
-	// Run program in background
-	var (
-		in          = make(chan int64, 1)
-		out         = make(chan int64, 2)
-		outputs     []int64
-		programExit bool
-		wg          = new(sync.WaitGroup)
-	)
-
-	go executeIntcode(code, in, out)
-	go func() {
-		for o := range out {
-			outputs = append(outputs, o)
-			wg.Done()
-		}
-		programExit = true
-	}()
-
-	// Start feeding scan results
-	for !programExit {
-		// Get previous directive for current position
-		var dir = day11PaintDirective{X: posX, Y: posY}
-		if d, ok := directives[dir.positionKey()]; ok {
-			dir = d
-		}
-
-		// Feed current color and expect two output
-		wg.Add(2)
-		in <- dir.Color
-		wg.Wait()
-
+	// Every scan result is answered by two outputs: color and rotation
+	decoder := newIntcodeFrameDecoder(day11PaintProtocol, func(rec interface{}) error {
+		paint := rec.(day11PaintRecord)
 		// Set current color
-		dir.Color = outputs[0]
-		directives[dir.positionKey()] = dir
+		panels.Set(pos, paint.Color)
 		// Rotate robot
-		rotate(outputs[1])
+		if paint.TurnRight {
+			direction = direction.TurnRight()
+		} else {
+			direction = direction.TurnLeft()
+		}
 		// Move
-		move()
+		pos = pos.Move(direction)
 
-		// Reset outputs
-		outputs = nil
+		return nil
+	})
+
+	// Feed scan results of the current panel
+	in := func() (int64, error) {
+		// Panels not painted yet are black
+		c, _ := panels.Get(pos)
+		return c, nil
 	}
 
-	var result []day11PaintDirective
-	for _, v := range directives {
-		result = append(result, v)
+	if _, err := ExecuteIntcodeWithParams(IntcodeParams{
+		Code:    code,
+		Context: context.Background(),
+		In:      in,
+		Out:     decoder.push,
+	}); err != nil {
+		return nil, errors.Wrap(err, "Unable to execute intcode")
 	}
 
-	return result, nil
-}
+	if err := decoder.finish(); err != nil {
+		return nil, errors.Wrap(err, "Unable to decode output")
+	}
 
-func solveDay11Part1(inFile string) (int, error) {
-	dirs, err := day11ExecutePaintRobot(inFile, 0)
-	return len(dirs), errors.Wrap(err, "Unable to execute robot")
+	return panels, nil
 }
 
-func solveDay11Part2(inFile string) error {
-	dirs, err := day11ExecutePaintRobot(inFile, 1)
+func solveDay11Part1(r io.Reader) (int, error) {
+	panels, err := day11ExecutePaintRobot(r, 0)
 	if err != nil {
-		return errors.Wrap(err, "Unable to execute robot")
+		return 0, errors.Wrap(err, "Unable to execute robot")
 	}
 
-	var minX, minY, maxX, maxY = math.MaxInt64, math.MaxInt64, 0, 0
-	for _, dir := range dirs {
-		if dir.X < minX {
-			minX = dir.X
-		}
-		if dir.X > maxX {
-			maxX = dir.X
-		}
-		if dir.Y < minY {
-			minY = dir.Y
-		}
-		if dir.Y > maxY {
-			maxY = dir.Y
-		}
+	return panels.Len(), nil
+}
+
+func solveDay11Part2(r io.Reader) (image.Image, error) {
+	panels, err := day11ExecutePaintRobot(r, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to execute robot")
 	}
 
 	colors := map[int64]color.Color{
@@ -157,16 +110,15 @@ func solveDay11Part2(inFile string) error {
 		1: color.RGBA{0xff, 0xff, 0xff, 0xff},
 	}
 
-	img := image.NewRGBA(image.Rect(minX-5, minY-5, maxX+5, maxY+5))
-	for _, dir := range dirs {
-		img.Set(dir.X, dir.Y, colors[dir.Color])
-	}
+	// Panels never painted stay transparent
+	b := panels.Bounds()
+	fb := NewIntcodeFramebuffer(image.Rect(b.Min.X-5, b.Min.Y-5, b.Max.X+5, b.Max.Y+5))
+	fb.Fill(-1)
+	panels.Each(func(p Point, c int64) { fb.Set(p.X, p.Y, c) })
 
-	w, err := os.Create("day11_image.png")
-	if err != nil {
-		return errors.Wrap(err, "Unable to open result image file")
-	}
-	defer w.Close()
+	return fb.Image(colors), nil
+}
 
-	return errors.Wrap(png.Encode(w, img), "Unable to store image")
+func init() {
+	registerSolver(11, solverParts{intPart(solveDay11Part1), imagePart(solveDay11Part2)})
 }
@@ -3,19 +3,30 @@ package aoc2019
 import "testing"
 
 func TestCalculateDay11_Part1(t *testing.T) {
-	count, err := solveDay11Part1("day11_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay11Part1(readInput(t, "day11_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 11 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 11 Part 1: %d", count)
+	checkAnswer(t, 11, 1, NumberAnswer(int64(count)))
 }
 
 func TestCalculateDay11_Part2(t *testing.T) {
-	err := solveDay11Part2("day11_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	img, err := solveDay11Part2(readInput(t, "day11_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 11 solver failed: %s", err)
 	}
 
-	t.Log("Solution Day 11 Part 2: See day11_image.png")
+	if err = savePNG("day11_image.png", img); err != nil {
+		t.Fatalf("Unable to save image: %s", err)
+	}
+
+	checkAnswer(t, 11, 2, ImageAnswer(img))
 }
+
+func BenchmarkCalculateDay11_Part1(b *testing.B) { benchmarkSolver(b, 11, 1) }
+func BenchmarkCalculateDay11_Part2(b *testing.B) { benchmarkSolver(b, 11, 2) }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"os"
 	"regexp"
 	"strconv"
 
@@ -14,16 +13,6 @@ import (
 
 type day12MoonSystem []*day12Moon
 
-func day12MoonSystemFromFile(inFile string) (day12MoonSystem, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return nil, errors.Wrap(err, "Unable to open inFile")
-	}
-	defer f.Close()
-
-	return day12MoonSystemFromReader(f)
-}
-
 func day12MoonSystemFromReader(r io.Reader) (day12MoonSystem, error) {
 	var moons day12MoonSystem
 
@@ -31,7 +20,7 @@ func day12MoonSystemFromReader(r io.Reader) (day12MoonSystem, error) {
 	for scanner.Scan() {
 		m, err := day12MoonFromScan(scanner.Text())
 		if err != nil {
-			return nil, errors.Wrap(err, "Unable to read input file")
+			return nil, errors.Wrap(err, "Unable to read input")
 		}
 		moons = append(moons, m)
 	}
@@ -39,6 +28,15 @@ func day12MoonSystemFromReader(r io.Reader) (day12MoonSystem, error) {
 	return moons, errors.Wrap(scanner.Err(), "Unable to scan file")
 }
 
+func (d day12MoonSystem) clone() day12MoonSystem {
+	var out = make(day12MoonSystem, len(d))
+	for i, m := range d {
+		c := *m
+		out[i] = &c
+	}
+	return out
+}
+
 func (d day12MoonSystem) move(steps int) {
 	for i := 0; i < steps; i++ {
 		d.updateVelocity()
@@ -147,8 +145,8 @@ func (d day12Moon) totalEnergy() int64 {
 	return int64((math.Abs(float64(d.PX)) + math.Abs(float64(d.PY)) + math.Abs(float64(d.PZ))) * (math.Abs(float64(d.VX)) + math.Abs(float64(d.VY)) + math.Abs(float64(d.VZ))))
 }
 
-func solveDay12Part1(inFile string) (int64, error) {
-	moons, err := day12MoonSystemFromFile(inFile)
+func solveDay12Part1(r io.Reader) (int64, error) {
+	moons, err := day12MoonSystemFromReader(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read system scan")
 	}
@@ -158,13 +156,13 @@ func solveDay12Part1(inFile string) (int64, error) {
 	return moons.totalEnergy(), nil
 }
 
-func solveDay12Part2(inFile string) (int64, error) {
-	moons, err := day12MoonSystemFromFile(inFile)
+func solveDay12Part2(r io.Reader) (int64, error) {
+	moons, err := day12MoonSystemFromReader(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read system scan")
 	}
 
-	initMoons, _ := day12MoonSystemFromFile(inFile)
+	initMoons := moons.clone()
 	var (
 		iterations [3]int64
 		completed  [3]bool
@@ -205,3 +203,7 @@ func solveDay12Part2(inFile string) (int64, error) {
 
 	return leastCommonMultiple(leastCommonMultiple(iterations[0], iterations[1]), iterations[2]), nil
 }
+
+func init() {
+	registerSolver(12, solverParts{int64Part(solveDay12Part1), int64Part(solveDay12Part2)})
+}
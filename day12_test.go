@@ -68,19 +68,26 @@ func TestDay12MoonExample(t *testing.T) {
 }
 
 func TestCalculateDay12_Part1(t *testing.T) {
-	count, err := solveDay12Part1("day12_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay12Part1(readInput(t, "day12_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 12 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 12 Part 1: %d", count)
+	checkAnswer(t, 12, 1, NumberAnswer(count))
 }
 
 func TestCalculateDay12_Part2(t *testing.T) {
-	res, err := solveDay12Part2("day12_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay12Part2(readInput(t, "day12_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 12 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 12 Part 2: %d", res)
+	checkAnswer(t, 12, 2, NumberAnswer(res))
 }
+
+func BenchmarkCalculateDay12_Part1(b *testing.B) { benchmarkSolver(b, 12, 1) }
+func BenchmarkCalculateDay12_Part2(b *testing.B) { benchmarkSolver(b, 12, 2) }
@@ -1,12 +1,11 @@
 package aoc2019
 
 import (
-	"fmt"
+	"context"
+	"io"
 	"io/ioutil"
 	"log"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/pkg/errors"
 )
@@ -21,162 +20,145 @@ const (
 	day13TileTypeBall                         // The ball moves diagonally and bounces off objects.
 )
 
-type day13Tile struct {
-	X, Y int64
+// day13TileRecord draws a tile of the game
+type day13TileRecord struct {
+	Pos  Point
 	Type day13TileType
 }
 
-func (d day13Tile) key() string { return fmt.Sprintf("%d:%d", d.X, d.Y) }
+// day13ScoreRecord updates the score display
+type day13ScoreRecord struct{ Score int64 }
+
+var day13ScreenProtocol = intcodeFrameProtocol{
+	size: 3,
+	decode: func(f intcodeFrame) (interface{}, error) {
+		if f[2] < int64(day13TileTypeEmpty) || f[2] > int64(day13TileTypeBall) {
+			return nil, errors.Errorf("Invalid tile type %d", f[2])
+		}
+		return day13TileRecord{Pos: Pt(int(f[0]), int(f[1])), Type: day13TileType(f[2])}, nil
+	},
+}.withSpecial(matchIntcodeFramePrefix(-1, 0), func(f intcodeFrame) (interface{}, error) {
+	return day13ScoreRecord{Score: f[2]}, nil
+})
 
 type day13Field struct {
-	tiles      map[string]day13Tile
-	score      int64
-	maxX, maxY int64
-	ball       day13Tile
+	tiles *SparseGrid
+	score int64
 
-	tileLock sync.RWMutex
+	// Last drawn positions, -1,-1 before they were drawn
+	ball, paddle Point
 }
 
-func (d *day13Field) getPosition(tileType day13TileType) (int64, int64) {
-	d.tileLock.RLock()
-	defer d.tileLock.RUnlock()
-
-	if tileType == day13TileTypeBall {
-		return d.ball.X, d.ball.Y
-	}
-
-	for _, t := range d.tiles {
-		if t.Type == tileType {
-			return t.X, t.Y
-		}
-	}
-
-	return -1, -1
+func newDay13Field() *day13Field {
+	return &day13Field{tiles: NewSparseGrid(), ball: Pt(-1, -1), paddle: Pt(-1, -1)}
 }
 
 func (d *day13Field) remainingTiles(tileType day13TileType) int {
-	d.tileLock.RLock()
-	defer d.tileLock.RUnlock()
-
 	var count int
-	for _, t := range d.tiles {
-		if t.Type == tileType {
+	d.tiles.Each(func(p Point, t int64) {
+		if day13TileType(t) == tileType {
 			count++
 		}
-	}
+	})
 	return count
 }
 
-func day13PlayGame(code []int64, field *day13Field, in func() (int64, error)) error {
-	var (
-		err    error
-		out    = make(chan int64)
-		output []int64
-		wg     = new(sync.WaitGroup)
-	)
-
-	in = func(f func() (int64, error)) func() (int64, error) {
-		return func() (int64, error) {
-			// Ensure every output is already processed before deciding on the input.
-			// Without this there is a race which randomly breaks the program in
-			// different ways m(
-			for len(output) > 0 {
-				time.Sleep(time.Nanosecond)
+// day13PlayGame runs the game and draws its outputs into the field,
+// onIO (optional) receives every input and output of the game
+func day13PlayGame(code []int64, field *day13Field, in func() (int64, error), onIO func(IntcodeEvent) error) error {
+	decoder := newIntcodeFrameDecoder(day13ScreenProtocol, func(rec interface{}) error {
+		switch rec := rec.(type) {
+		case day13TileRecord:
+			field.tiles.Set(rec.Pos, int64(rec.Type))
+
+			// Ball sometimes disappear, store it extra and keep the
+			// paddle to not search the field on every input
+			switch rec.Type {
+			case day13TileTypeBall:
+				field.ball = rec.Pos
+			case day13TileTypeHPaddle:
+				field.paddle = rec.Pos
 			}
 
-			return f()
+		case day13ScoreRecord:
+			field.score = rec.Score
 		}
-	}(in)
-
-	go func() {
-		for o := range out {
-			output = append(output, o)
 
-			if len(output) >= 3 {
-				field.tileLock.Lock()
-
-				if output[0] == -1 && output[1] == 0 {
-					field.score = output[2]
-					output = output[3:]
-
-					field.tileLock.Unlock()
-					continue
-				}
-
-				tile := day13Tile{X: output[0], Y: output[1], Type: day13TileType(output[2])}
-				field.tiles[tile.key()] = tile
-
-				if tile.Type == day13TileTypeBall {
-					// Ball sometimes disappear, store it extra
-					field.ball = tile
-				}
-
-				if tile.X > field.maxX {
-					field.maxX = tile.X
-				}
-				if tile.Y > field.maxY {
-					field.maxY = tile.Y
-				}
-				output = output[3:]
-
-				field.tileLock.Unlock()
-			}
-		}
-		wg.Done()
-	}()
-
-	wg.Add(1)
-	_, err = executeIntcode(code, in, out)
-	wg.Wait()
+		return nil
+	})
+
+	// Outputs are decoded within the program execution so every output
+	// is already processed before the program asks for the next input
+	_, err := ExecuteIntcodeWithParams(IntcodeParams{
+		Code:    code,
+		Context: context.Background(),
+		In:      in,
+		Out:     decoder.push,
+		OnIO:    onIO,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to execute intcode")
+	}
 
-	return errors.Wrap(err, "Unable to execute intcode")
+	return errors.Wrap(decoder.finish(), "Unable to decode output")
 }
 
-func solveDay13Part1(inFile string) (int, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay13Part1(r io.Reader) (int, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read code")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse code")
 	}
 
-	var field = &day13Field{tiles: make(map[string]day13Tile)}
-	if err := day13PlayGame(code, field, nil); err != nil {
+	var field = newDay13Field()
+	if err := day13PlayGame(code, field, nil, nil); err != nil {
 		return 0, errors.Wrap(err, "Unable to draw field")
 	}
 
 	return field.remainingTiles(day13TileTypeBlock), nil
 }
 
-func solveDay13Part2(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay13Part2(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read code")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse code")
 	}
 
 	// Let the game initialize once to get tick count for initialization
-	var field = &day13Field{tiles: make(map[string]day13Tile)}
+	var field = newDay13Field()
 
 	// Start the real game
 	code[0] = 2 // Insert two quarters
 
-	// Input callback
-	var in = func() (int64, error) {
-		field.tileLock.RLock()
-		defer field.tileLock.RUnlock()
+	//intcodeDebugging = true
+	if err := day13PlayGame(code, field, day13Joystick(field), nil); err != nil {
+		log.Printf("err=%s", err)
+	}
+
+	if rb := field.remainingTiles(day13TileTypeBlock); rb > 0 {
+		return 0, errors.Errorf("Game logic quit with blocks remaining: %d", rb)
+	}
+
+	return field.score, nil
+}
 
+// day13Joystick creates an input callback moving the paddle towards
+// the ball
+func day13Joystick(field *day13Field) func() (int64, error) {
+	return func() (int64, error) {
 		var (
-			ballX, _   = field.getPosition(day13TileTypeBall)
-			dir        int64
-			paddleX, _ = field.getPosition(day13TileTypeHPaddle)
+			ballX   = field.ball.X
+			dir     int64
+			paddleX = field.paddle.X
 		)
 
 		// Move paddle in ball direction
@@ -191,15 +173,8 @@ func solveDay13Part2(inFile string) (int64, error) {
 
 		return dir, nil
 	}
+}
 
-	//intcodeDebugging = true
-	if err := day13PlayGame(code, field, in); err != nil {
-		log.Printf("err=%s", err)
-	}
-
-	if rb := field.remainingTiles(day13TileTypeBlock); rb > 0 {
-		return 0, errors.Errorf("Game logic quit with blocks remaining: %d", rb)
-	}
-
-	return field.score, nil
+func init() {
+	registerSolver(13, solverParts{intPart(solveDay13Part1), int64Part(solveDay13Part2)})
 }
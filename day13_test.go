@@ -1,21 +1,66 @@
 package aoc2019
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
 
 func TestCalculateDay13_Part1(t *testing.T) {
-	count, err := solveDay13Part1("day13_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay13Part1(readInput(t, "day13_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 13 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 13 Part 1: %d", count)
+	checkAnswer(t, 13, 1, NumberAnswer(int64(count)))
 }
 
 func TestCalculateDay13_Part2(t *testing.T) {
-	res, err := solveDay13Part2("day13_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay13Part2(readInput(t, "day13_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 13 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 13 Part 2: %d", res)
+	checkAnswer(t, 13, 2, NumberAnswer(res))
+}
+
+func TestDay13RecordReplay(t *testing.T) {
+	raw, err := ioutil.ReadFile("day13_input.txt")
+	if err != nil {
+		t.Fatalf("Unable to read code: %s", err)
+	}
+
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
+	if err != nil {
+		t.Fatalf("Unable to parse code: %s", err)
+	}
+	code[0] = 2
+
+	var (
+		buf   = new(bytes.Buffer)
+		field = newDay13Field()
+	)
+
+	if err := day13PlayGame(cloneIntcode(code), field, day13Joystick(field), NewIntcodeRecorder(buf).Record); err != nil {
+		t.Fatalf("Recorded game failed: %s", err)
+	}
+
+	events, err := ReadIntcodeRecording(buf)
+	if err != nil {
+		t.Fatalf("Unable to read recording: %s", err)
+	}
+
+	// Replay the game without the joystick logic
+	if err := ReplayIntcode(context.Background(), NewIntcodeMachine(code), events, nil); err != nil {
+		t.Errorf("Replay of recorded game failed: %s", err)
+	}
 }
+
+func BenchmarkCalculateDay13_Part1(b *testing.B) { benchmarkSolver(b, 13, 1) }
+func BenchmarkCalculateDay13_Part2(b *testing.B) { benchmarkSolver(b, 13, 2) }
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"io"
 	"math"
-	"os"
 	"strconv"
 	"strings"
 
@@ -96,14 +95,8 @@ func day14ParseReactionChain(r io.Reader) (*day14NanoFactory, error) {
 	return factory, errors.Wrap(scanner.Err(), "Unable to scan input")
 }
 
-func solveDay14Part1(inFile string) (int64, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return 0, errors.Wrap(err, "Unable to open input file")
-	}
-	defer f.Close()
-
-	factory, err := day14ParseReactionChain(f)
+func solveDay14Part1(r io.Reader) (int64, error) {
+	factory, err := day14ParseReactionChain(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse reaction chain")
 	}
@@ -111,14 +104,8 @@ func solveDay14Part1(inFile string) (int64, error) {
 	return factory.calculateOreForFuel(1), nil
 }
 
-func solveDay14Part2(inFile string) (int64, error) {
-	f, err := os.Open(inFile)
-	if err != nil {
-		return 0, errors.Wrap(err, "Unable to open input file")
-	}
-	defer f.Close()
-
-	factory, err := day14ParseReactionChain(f)
+func solveDay14Part2(r io.Reader) (int64, error) {
+	factory, err := day14ParseReactionChain(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse reaction chain")
 	}
@@ -153,3 +140,7 @@ func solveDay14Part2(inFile string) (int64, error) {
 
 	}
 }
+
+func init() {
+	registerSolver(14, solverParts{int64Part(solveDay14Part1), int64Part(solveDay14Part2)})
+}
@@ -46,19 +46,26 @@ func TestDay14OreForFuel(t *testing.T) {
 }
 
 func TestCalculateDay14_Part1(t *testing.T) {
-	count, err := solveDay14Part1("day14_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay14Part1(readInput(t, "day14_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 14 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 14 Part 1: %d", count)
+	checkAnswer(t, 14, 1, NumberAnswer(count))
 }
 
 func TestCalculateDay14_Part2(t *testing.T) {
-	res, err := solveDay14Part2("day14_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay14Part2(readInput(t, "day14_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 14 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 14 Part 2: %d", res)
+	checkAnswer(t, 14, 2, NumberAnswer(res))
 }
+
+func BenchmarkCalculateDay14_Part1(b *testing.B) { benchmarkSolver(b, 14, 1) }
+func BenchmarkCalculateDay14_Part2(b *testing.B) { benchmarkSolver(b, 14, 2) }
@@ -1,10 +1,8 @@
 package aoc2019
 
 import (
-	"context"
-	"fmt"
+	"io"
 	"io/ioutil"
-	"math"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -19,158 +17,83 @@ const (
 	day15TileTypeUnknown
 )
 
-type day15Tile struct {
-	X, Y int64
-	Type day15TileType
+// day15MoveCommands maps the directions to the movement commands of
+// the robot
+var day15MoveCommands = map[Direction]int64{DirUp: 1, DirDown: 2, DirLeft: 3, DirRight: 4}
 
-	distFromStart int64
-}
-
-func (d day15Tile) key() string { return fmt.Sprintf("%d:%d", d.X, d.Y) }
-
-type day15Grid map[string]*day15Tile
+type day15Grid struct{ *SparseGrid }
 
-func (d day15Grid) annotateDistance(x, y, dist int64) {
-	var t = d.getTile(x, y)
-	if t == nil {
-		panic("Access to non-existent tile")
-	}
-
-	if t.Type != day15TileTypeFloor && t.Type != day15TileTypeOxygen {
+// annotateDistance stores the shortest distance from the start for every
+// tile reachable from p
+func (d day15Grid) annotateDistance(dists *SparseGrid, p Point, dist int64) {
+	if t := d.getTileType(p); t != day15TileTypeFloor && t != day15TileTypeOxygen {
 		// Do not annotate distance on walls
 		return
 	}
 
-	if t.distFromStart <= dist {
+	if known, ok := dists.Get(p); ok && known <= dist {
 		// Distance already set, no need to set again
 		return
 	}
 
 	// Set distance
-	t.distFromStart = dist
+	dists.Set(p, dist)
 
 	// Annotate next fields
-	d.annotateDistance(x-1, y, dist+1)
-	d.annotateDistance(x+1, y, dist+1)
-	d.annotateDistance(x, y-1, dist+1)
-	d.annotateDistance(x, y+1, dist+1)
-}
-
-func (d day15Grid) bounds() (minX, minY, maxX, maxY int64) {
-	minX = math.MaxInt64
-	minY = math.MaxInt64
-
-	for _, t := range d {
-		if t.X < minX {
-			minX = t.X
-		}
-		if t.X > maxX {
-			maxX = t.X
-		}
-		if t.Y < minY {
-			minY = t.Y
-		}
-		if t.Y > maxY {
-			maxY = t.Y
-		}
+	for _, n := range p.Neighbors() {
+		d.annotateDistance(dists, n, dist+1)
 	}
-
-	return
 }
 
-func (d day15Grid) getTile(x, y int64) *day15Tile {
-	if v, ok := d[day15Tile{X: x, Y: y}.key()]; ok {
-		return v
-	}
-	return nil
+func (d day15Grid) find(tile day15TileType) Point {
+	var pos Point
+	d.Each(func(p Point, t int64) {
+		if day15TileType(t) == tile {
+			pos = p
+		}
+	})
+	return pos
 }
 
-func (d day15Grid) getTileType(x, y int64) day15TileType {
-	if v := d.getTile(x, y); v != nil {
-		return v.Type
+func (d day15Grid) getTileType(p Point) day15TileType {
+	if v, ok := d.Get(p); ok {
+		return day15TileType(v)
 	}
 	return day15TileTypeUnknown
 }
 
-func (d day15Grid) print() {
-	minX, minY, maxX, maxY := d.bounds()
-	for y := minY; y <= maxY; y++ {
-		for x := minX; x <= maxX; x++ {
-			if x == 0 && y == 0 {
-				fmt.Printf("@")
-				continue
-			}
-
-			t := d.getTileType(x, y)
-			switch t {
-			case day15TileTypeFloor:
-				fmt.Printf(".")
-			case day15TileTypeWall:
-				fmt.Printf("\u2588")
-			case day15TileTypeOxygen:
-				fmt.Printf("X")
-			case day15TileTypeUnknown:
-				fmt.Printf("\u2593")
-			}
+func (d day15Grid) String() string {
+	return GridString(d, func(p Point, t int64, ok bool) rune {
+		switch {
+		case p == Point{}:
+			return '@'
+		case !ok:
+			return '\u2593'
+		case day15TileType(t) == day15TileTypeFloor:
+			return '.'
+		case day15TileType(t) == day15TileTypeWall:
+			return '\u2588'
+		default:
+			return 'X'
 		}
-		fmt.Println()
-	}
+	})
 }
 
 func day15ScanGrid(code []int64) (day15Grid, error) {
 	var (
-		grid       = make(day15Grid)
-		posX, posY int64
-		rotation   int64 = 1 // start facing north
+		grid      = day15Grid{NewSparseGrid()}
+		pos       Point
+		direction = DirUp // start facing north
 	)
 
-	recordPosition := func(success bool, tile day15TileType) bool {
-		var nPosX, nPosY = posX, posY
+	recordPosition := func(success bool, tile day15TileType) {
+		var nPos = pos.Move(direction)
 
 		if success {
-			defer func() { posX, posY = nPosX, nPosY }()
-		}
-
-		switch rotation {
-		case 1:
-			nPosX -= 1
-		case 2:
-			nPosX += 1
-		case 3:
-			nPosY -= 1
-		case 4:
-			nPosY += 1
-		}
-
-		if t := grid.getTileType(nPosX, nPosY); t == tile {
-			return true
+			pos = nPos
 		}
 
-		nT := day15Tile{X: nPosX, Y: nPosY, Type: tile, distFromStart: math.MaxInt64}
-		grid[nT.key()] = &nT
-
-		return false
-	}
-
-	rotate := func(forward bool) {
-		var (
-			fr = []int64{1, 4, 2, 3}
-			br = []int64{1, 3, 2, 4}
-			r  []int64
-		)
-
-		if forward {
-			r = fr
-		} else {
-			r = br
-		}
-
-		nP := int64IndexOf(r, rotation) + 1
-		if nP == len(r) {
-			nP = 0
-		}
-
-		rotation = r[nP]
+		grid.Set(nPos, int64(tile))
 	}
 
 	var (
@@ -178,69 +101,58 @@ func day15ScanGrid(code []int64) (day15Grid, error) {
 		out = make(chan int64)
 	)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	go executeIntcodeWithParams(intcodeParams{
-		Code:    code,
-		Context: ctx,
-		In:      in,
-		Out:     out,
+	proc := StartIntcode(IntcodeParams{
+		Code: code,
+		In:   in,
+		Out:  out,
 	})
+	defer proc.Stop()
 
-	// Start by moving
-	in <- rotation
+	// Start by moving, a stopped program closes the output and its
+	// error is reported below
+	proc.Send(in, day15MoveCommands[direction])
 
 	for res := range out {
-		var alreadyKnown bool
-
 		switch day15TileType(res) {
 		case day15TileTypeWall:
 			// Ran into wall, not a successful move
-			alreadyKnown = recordPosition(false, day15TileType(res))
-			// Rotate once forward
-			rotate(false)
+			recordPosition(false, day15TileType(res))
+			// Follow the wall on the right hand side
+			direction = direction.TurnLeft()
 
 		case day15TileTypeFloor, day15TileTypeOxygen:
 			// Moved to new tile, successful move
-			alreadyKnown = recordPosition(true, day15TileType(res))
-			// Rotate once backward
-			rotate(true)
+			recordPosition(true, day15TileType(res))
+			direction = direction.TurnRight()
 
 		default:
 			// Thefuck?
 			return grid, errors.Errorf("Invalid tile type detected: %d", res)
 		}
 
-		_ = alreadyKnown
-		if posX == 0 && posY == 0 {
-			// We've reached a position twice, let's quit the program but not
-			// yet the function as the input then will hang
-			cancel()
+		if pos == (Point{}) {
+			// We've reached a position twice, the program is stopped
+			// through the deferred Stop
+			return grid, nil
 		}
 
-		in <- rotation
+		proc.Send(in, day15MoveCommands[direction])
 	}
 
-	return grid, nil
-}
-
-func int64IndexOf(s []int64, e int64) int {
-	for i, se := range s {
-		if se == e {
-			return i
-		}
+	if _, err := proc.Wait(); err != nil {
+		return grid, errors.Wrap(err, "Robot program failed")
 	}
-	return -1
+
+	return grid, errors.New("Robot program exited before returning to start")
 }
 
-func solveDay15Part1(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay15Part1(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse Intcode")
 	}
@@ -250,26 +162,20 @@ func solveDay15Part1(inFile string) (int64, error) {
 		return 0, errors.Wrap(err, "Unable to scan grid")
 	}
 
-	grid.annotateDistance(0, 0, 0)
-
-	var oxygen *day15Tile
-	for _, t := range grid {
-		if t.Type == day15TileTypeOxygen {
-			oxygen = t
-			break
-		}
-	}
+	dists := NewSparseGrid()
+	grid.annotateDistance(dists, Point{}, 0)
 
-	return oxygen.distFromStart, nil
+	dist, _ := dists.Get(grid.find(day15TileTypeOxygen))
+	return dist, nil
 }
 
-func solveDay15Part2(inFile string) (int64, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay15Part2(r io.Reader) (int64, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read input")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(raw)))
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse Intcode")
 	}
@@ -279,22 +185,19 @@ func solveDay15Part2(inFile string) (int64, error) {
 		return 0, errors.Wrap(err, "Unable to scan grid")
 	}
 
-	var oxygenSystem *day15Tile
-	for _, t := range grid {
-		if t.Type == day15TileTypeOxygen {
-			oxygenSystem = t
-			break
-		}
-	}
+	dists := NewSparseGrid()
+	grid.annotateDistance(dists, grid.find(day15TileTypeOxygen), 0)
 
-	grid.annotateDistance(oxygenSystem.X, oxygenSystem.Y, 0)
-
-	var farthestTile = oxygenSystem
-	for _, t := range grid {
-		if t.distFromStart > farthestTile.distFromStart && t.Type == day15TileTypeFloor {
-			farthestTile = t
+	var farthest int64
+	dists.Each(func(p Point, dist int64) {
+		if dist > farthest {
+			farthest = dist
 		}
-	}
+	})
+
+	return farthest, nil
+}
 
-	return farthestTile.distFromStart, nil
+func init() {
+	registerSolver(15, solverParts{int64Part(solveDay15Part1), int64Part(solveDay15Part2)})
 }
@@ -3,19 +3,26 @@ package aoc2019
 import "testing"
 
 func TestCalculateDay15_Part1(t *testing.T) {
-	count, err := solveDay15Part1("day15_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	count, err := solveDay15Part1(readInput(t, "day15_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 15 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 15 Part 1: %d", count)
+	checkAnswer(t, 15, 1, NumberAnswer(count))
 }
 
 func TestCalculateDay15_Part2(t *testing.T) {
-	res, err := solveDay15Part2("day15_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay15Part2(readInput(t, "day15_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 15 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 15 Part 2: %d", res)
+	checkAnswer(t, 15, 2, NumberAnswer(res))
 }
+
+func BenchmarkCalculateDay15_Part1(b *testing.B) { benchmarkSolver(b, 15, 1) }
+func BenchmarkCalculateDay15_Part2(b *testing.B) { benchmarkSolver(b, 15, 2) }
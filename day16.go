@@ -2,6 +2,7 @@ package aoc2019
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"math"
 	"strconv"
@@ -74,10 +75,10 @@ func day16ProcessSignal(s []int64, phases int) []int64 {
 	return processedSignal
 }
 
-func solveDay16Part1(inFile string) (string, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay16Part1(r io.Reader) (string, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return "", errors.Wrap(err, "Unable to read input file")
+		return "", errors.Wrap(err, "Unable to read input")
 	}
 
 	s, err := day16ReadInputSignal(strings.TrimSpace(string(raw)))
@@ -95,10 +96,10 @@ func solveDay16Part1(inFile string) (string, error) {
 	return res, nil
 }
 
-func solveDay16Part2(inFile string) (string, error) {
-	raw, err := ioutil.ReadFile(inFile)
+func solveDay16Part2(r io.Reader) (string, error) {
+	raw, err := ioutil.ReadAll(r)
 	if err != nil {
-		return "", errors.Wrap(err, "Unable to read input file")
+		return "", errors.Wrap(err, "Unable to read input")
 	}
 
 	raw = bytes.TrimSpace(raw)
@@ -139,3 +140,7 @@ func solveDay16Part2(inFile string) (string, error) {
 
 	return res, nil
 }
+
+func init() {
+	registerSolver(16, solverParts{textPart(solveDay16Part1), textPart(solveDay16Part2)})
+}
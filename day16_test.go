@@ -79,19 +79,26 @@ func TestDay16ProcessSignal8OfLonger(t *testing.T) {
 }
 
 func TestCalculateDay16_Part1(t *testing.T) {
-	res, err := solveDay16Part1("day16_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay16Part1(readInput(t, "day16_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 16 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 16 Part 1: %s", res)
+	checkAnswer(t, 16, 1, TextAnswer(res))
 }
 
 func TestCalculateDay16_Part2(t *testing.T) {
-	res, err := solveDay16Part2("day16_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay16Part2(readInput(t, "day16_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 16 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 16 Part 2: %s", res)
+	checkAnswer(t, 16, 2, TextAnswer(res))
 }
+
+func BenchmarkCalculateDay16_Part1(b *testing.B) { benchmarkSolver(b, 16, 1) }
+func BenchmarkCalculateDay16_Part2(b *testing.B) { benchmarkSolver(b, 16, 2) }
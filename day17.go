@@ -1,13 +1,12 @@
 package aoc2019
 
 import (
-	"fmt"
+	"bytes"
+	"io"
 	"io/ioutil"
-	"math"
 	"reflect"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -25,163 +24,88 @@ const (
 	day17TileTypeSpace      day17TileType = 46 // .
 )
 
-type day17Tile struct {
-	X, Y int64
-	Type day17TileType
+// day17RobotDirections maps the robot tiles to the direction the robot
+// is facing
+var day17RobotDirections = map[day17TileType]Direction{
+	day17TileTypeRobotUp:    DirUp,
+	day17TileTypeRobotRight: DirRight,
+	day17TileTypeRobotDown:  DirDown,
+	day17TileTypeRobotLeft:  DirLeft,
 }
 
-type day17Grid map[string]*day17Tile
+type day17Grid struct{ *DenseGrid }
 
-func (d day17Grid) bounds() (minX, minY, maxX, maxY int64) {
-	minX, minY = math.MaxInt64, math.MaxInt64
+func (d day17Grid) findCurrentPosition() (Point, Direction, bool) {
+	var (
+		pos   Point
+		dir   Direction
+		found bool
+	)
 
-	for _, t := range d {
-		if t.X < minX {
-			minX = t.X
-		}
-		if t.Y < minY {
-			minY = t.Y
-		}
-		if t.X > maxX {
-			maxX = t.X
-		}
-		if t.Y > maxY {
-			maxY = t.Y
+	d.Each(func(p Point, t int64) {
+		if rd, ok := day17RobotDirections[day17TileType(t)]; ok {
+			pos, dir, found = p, rd, true
 		}
-	}
+	})
 
-	return
-}
-
-func (d day17Grid) findCurrentPosition() (int64, int64, day17TileType) {
-	for _, t := range d {
-		if t.Type == day17TileTypeRobotDown || t.Type == day17TileTypeRobotLeft || t.Type == day17TileTypeRobotRight || t.Type == day17TileTypeRobotUp {
-			return t.X, t.Y, t.Type
-		}
-	}
-	return -1, -1, day17TileTypeRobotLost
+	return pos, dir, found
 }
 
 func (d day17Grid) findPath() []int64 {
 	var (
-		count           int64
-		directives      []int64
-		x, y, direction = d.findCurrentPosition()
+		count                  int64
+		directives             []int64
+		pos, direction, onGrid = d.findCurrentPosition()
 	)
 
-	move := func() {
-		count++
-
-		switch direction {
-		case day17TileTypeRobotDown:
-			y += 1
-		case day17TileTypeRobotLeft:
-			x -= 1
-		case day17TileTypeRobotRight:
-			x += 1
-		case day17TileTypeRobotUp:
-			y -= 1
-		}
+	if !onGrid {
+		// Robot is lost, no path to follow
+		return nil
 	}
 
-	opportunities := func() (forward, left, right bool) {
-		var tileFwd, tileL, tileR *day17Tile
-
-		switch direction {
-		case day17TileTypeRobotDown:
-			tileFwd, tileL, tileR = d[d.mapKey(x, y+1)], d[d.mapKey(x+1, y)], d[d.mapKey(x-1, y)]
-		case day17TileTypeRobotLeft:
-			tileFwd, tileL, tileR = d[d.mapKey(x-1, y)], d[d.mapKey(x, y+1)], d[d.mapKey(x, y-1)]
-		case day17TileTypeRobotRight:
-			tileFwd, tileL, tileR = d[d.mapKey(x+1, y)], d[d.mapKey(x, y-1)], d[d.mapKey(x, y+1)]
-		case day17TileTypeRobotUp:
-			tileFwd, tileL, tileR = d[d.mapKey(x, y-1)], d[d.mapKey(x-1, y)], d[d.mapKey(x+1, y)]
-		}
-
-		forward = tileFwd != nil && tileFwd.Type == day17TileTypeScaffold
-		left = tileL != nil && tileL.Type == day17TileTypeScaffold
-		right = tileR != nil && tileR.Type == day17TileTypeScaffold
-
-		return
+	isScaffold := func(p Point) bool {
+		t, ok := d.Get(p)
+		return ok && day17TileType(t) == day17TileTypeScaffold
 	}
 
-	turn := func(left bool) {
-		var (
-			dl = []day17TileType{day17TileTypeRobotUp, day17TileTypeRobotLeft, day17TileTypeRobotDown, day17TileTypeRobotRight}
-			dr = []day17TileType{day17TileTypeRobotUp, day17TileTypeRobotRight, day17TileTypeRobotDown, day17TileTypeRobotLeft}
-			nd []day17TileType
-		)
+	// Do the movement
+	for {
+		if isScaffold(pos.Move(direction)) {
+			count++
+			pos = pos.Move(direction)
+			continue
+		}
 
 		if count > 0 {
 			directives = append(directives, count)
 			count = 0
 		}
 
-		if left {
-			nd = dl
+		switch {
+		case isScaffold(pos.Move(direction.TurnLeft())):
+			direction = direction.TurnLeft()
 			directives = append(directives, int64('L'))
-		} else {
-			nd = dr
-			directives = append(directives, int64('R'))
-		}
-
-		var cd int
-		for i, t := range nd {
-			if t == direction {
-				cd = i
-				break
-			}
-		}
-		if cd == len(nd)-1 {
-			cd = -1
-		}
-
-		direction = nd[cd+1]
-	}
-
-	// Do the movement
-	for {
-		fw, l, r := opportunities()
-
-		if fw {
-			move()
-			continue
-		}
 
-		if l {
-			turn(true)
-			continue
-		}
+		case isScaffold(pos.Move(direction.TurnRight())):
+			direction = direction.TurnRight()
+			directives = append(directives, int64('R'))
 
-		if r {
-			turn(false)
-			continue
+		default:
+			// Nothing possible, must be the end
+			return directives
 		}
-
-		// Nothing possible, must be the end
-		directives = append(directives, count)
-		break
 	}
-
-	return directives
 }
 
-func (d day17Grid) isScaffoldIntersection(x, y int64) bool {
-	t := d[d.mapKey(x, y)]
-
-	if t.Type == day17TileTypeSpace {
+func (d day17Grid) isScaffoldIntersection(p Point) bool {
+	if t, _ := d.Get(p); day17TileType(t) == day17TileTypeSpace {
 		// Space cannot be a scaffold intersection
 		return false
 	}
 
 	var count int64
-	for _, at := range []*day17Tile{
-		d[d.mapKey(x-1, y)],
-		d[d.mapKey(x+1, y)],
-		d[d.mapKey(x, y-1)],
-		d[d.mapKey(x, y+1)],
-	} {
-		if at != nil && at.Type != day17TileTypeSpace {
+	for _, n := range p.Neighbors() {
+		if t, ok := d.Get(n); ok && day17TileType(t) != day17TileTypeSpace {
 			count++
 		}
 	}
@@ -192,61 +116,48 @@ func (d day17Grid) isScaffoldIntersection(x, y int64) bool {
 	return count >= 3
 }
 
-func (d day17Grid) mapKey(x, y int64) string { return fmt.Sprintf("%d:%d", x, y) }
-
-func (d day17Grid) print() {
-	var minX, minY, maxX, maxY = d.bounds()
-
-	for y := minY; y <= maxY; y++ {
-		for x := minX; x <= maxX; x++ {
-			fmt.Printf("%s", string(d[d.mapKey(x, y)].Type))
-		}
-		fmt.Println()
-	}
+func (d day17Grid) String() string {
+	return GridString(d, func(p Point, t int64, ok bool) rune { return rune(t) })
 }
 
 func day17ReadGrid(code []int64) (day17Grid, error) {
 	var (
-		grid = make(day17Grid)
-		out  = make(chan int64)
-		x, y int64
+		buf = new(bytes.Buffer)
+		out = make(chan int64)
 	)
 
-	go executeIntcode(code, nil, out)
+	proc := StartIntcode(IntcodeParams{Code: code, Out: out})
+	defer proc.Stop()
 
 	for o := range out {
 		switch day17TileType(o) {
 
-		case day17TileTypeNewline:
-			y += 1
-			x = 0
-
-		case day17TileTypeRobotDown, day17TileTypeRobotLeft, day17TileTypeRobotRight, day17TileTypeRobotUp:
-			fallthrough // Not yet used
-
-		case day17TileTypeRobotLost:
-			fallthrough // Not yet used
-
-		case day17TileTypeScaffold, day17TileTypeSpace:
-			grid[grid.mapKey(x, y)] = &day17Tile{X: x, Y: y, Type: day17TileType(o)}
-			x += 1
+		case day17TileTypeNewline, day17TileTypeScaffold, day17TileTypeSpace,
+			day17TileTypeRobotDown, day17TileTypeRobotLeft, day17TileTypeRobotRight, day17TileTypeRobotUp,
+			day17TileTypeRobotLost:
+			buf.WriteByte(byte(o))
 
 		default:
-			return nil, errors.Errorf("Invalid character %d", o)
+			return day17Grid{}, errors.Errorf("Invalid character %d", o)
 
 		}
 	}
 
-	return grid, nil
+	if _, err := proc.Wait(); err != nil {
+		return day17Grid{}, errors.Wrap(err, "Camera program failed")
+	}
+
+	grid, err := ParseDenseGrid(buf)
+	return day17Grid{grid}, errors.Wrap(err, "Unable to parse camera image")
 }
 
-func solveDay17Part1(inFile string) (int64, error) {
-	rawCode, err := ioutil.ReadFile(inFile)
+func solveDay17Part1(r io.Reader) (int64, error) {
+	rawCode, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read intcode")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(rawCode)))
+	code, err := ParseIntcode(strings.TrimSpace(string(rawCode)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode")
 	}
@@ -256,31 +167,23 @@ func solveDay17Part1(inFile string) (int64, error) {
 		return 0, errors.Wrap(err, "Unable to read grid")
 	}
 
-	var (
-		apSum                  int64
-		minX, minY, maxX, maxY = grid.bounds()
-	)
-
-	for y := minY; y <= maxY; y++ {
-		for x := minX; x <= maxX; x++ {
-
-			if grid.isScaffoldIntersection(x, y) {
-				apSum += x * y
-			}
-
+	var apSum int64
+	grid.Each(func(p Point, t int64) {
+		if grid.isScaffoldIntersection(p) {
+			apSum += int64(p.X * p.Y)
 		}
-	}
+	})
 
 	return apSum, nil
 }
 
-func solveDay17Part2(inFile string) (int64, error) {
-	rawCode, err := ioutil.ReadFile(inFile)
+func solveDay17Part2(r io.Reader) (int64, error) {
+	rawCode, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read intcode")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(rawCode)))
+	code, err := ParseIntcode(strings.TrimSpace(string(rawCode)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode")
 	}
@@ -373,7 +276,6 @@ func solveDay17Part2(inFile string) (int64, error) {
 	var (
 		in  = make(chan int64, 1000) // I could calculate the length but I don't care
 		out = make(chan int64)
-		wg  = new(sync.WaitGroup)
 	)
 
 	// Feed main movement routine
@@ -385,7 +287,7 @@ func solveDay17Part2(inFile string) (int64, error) {
 	// Answer "continuous video feed" question
 	feedSlice(in, []int64{int64('n')})
 
-	code, err = parseIntcode(strings.TrimSpace(string(rawCode)))
+	code, err = ParseIntcode(strings.TrimSpace(string(rawCode)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode")
 	}
@@ -396,18 +298,21 @@ func solveDay17Part2(inFile string) (int64, error) {
 
 	// Execute the program and throw away all but last output, we know
 	// how the grid looks
-	wg.Add(1)
-	go executeIntcode(code, in, out)
+	proc := StartIntcode(IntcodeParams{Code: code, In: in, Out: out})
+	defer proc.Stop()
 
 	var result int64
-	go func() {
-		for o := range out {
-			result = o
-		}
-		wg.Done()
-	}()
+	for o := range out {
+		result = o
+	}
 
-	wg.Wait()
+	if _, err := proc.Wait(); err != nil {
+		return 0, errors.Wrap(err, "Vacuum robot program failed")
+	}
 
 	return result, nil
 }
+
+func init() {
+	registerSolver(17, solverParts{int64Part(solveDay17Part1), int64Part(solveDay17Part2)})
+}
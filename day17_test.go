@@ -3,19 +3,26 @@ package aoc2019
 import "testing"
 
 func TestCalculateDay17_Part1(t *testing.T) {
-	res, err := solveDay17Part1("day17_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay17Part1(readInput(t, "day17_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 17 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 17 Part 1: %d", res)
+	checkAnswer(t, 17, 1, NumberAnswer(res))
 }
 
 func TestCalculateDay17_Part2(t *testing.T) {
-	res, err := solveDay17Part2("day17_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay17Part2(readInput(t, "day17_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 17 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 17 Part 2: %d", res)
+	checkAnswer(t, 17, 2, NumberAnswer(res))
 }
+
+func BenchmarkCalculateDay17_Part1(b *testing.B) { benchmarkSolver(b, 17, 1) }
+func BenchmarkCalculateDay17_Part2(b *testing.B) { benchmarkSolver(b, 17, 2) }
@@ -1,8 +1,9 @@
 package aoc2019
 
 import (
+	"context"
+	"io"
 	"io/ioutil"
-	"log"
 	"math"
 	"strings"
 
@@ -34,71 +35,95 @@ func (f day19Fact) String() string {
 	}[f]
 }
 
-func day19CountFieldsInTractorBeam(code []int64, maxX, maxY int64) int64 {
-	var count int64
+// day19Probe reports whether the coordinate is covered by the beam
+func day19Probe(batch *intcodeBatch, x, y int64) (bool, error) {
+	res, err := batch.runOne(context.Background(), []int64{x, y})
+	if err != nil {
+		return false, errors.Wrapf(err, "Probe of %d,%d failed", x, y)
+	}
+	if len(res.Outputs) != 1 {
+		return false, errors.Errorf("Probe of %d,%d yielded %d outputs", x, y, len(res.Outputs))
+	}
+	return res.Outputs[0] == 1, nil
+}
 
-	for y := int64(0); y <= maxY; y++ {
-		for x := int64(0); x <= maxX; x++ {
+func day19CountFieldsInTractorBeam(code []int64, maxX, maxY int64) (int64, error) {
+	var (
+		count    int64
+		probeErr error
+	)
 
-			var (
-				in  = make(chan int64)
-				out = make(chan int64)
-			)
-			go executeIntcode(code, in, out)
+	err := newIntcodeBatch(code).stream(context.Background(), func(emit func([]int64) bool) {
+		for y := int64(0); y <= maxY; y++ {
+			for x := int64(0); x <= maxX; x++ {
+				if !emit([]int64{x, y}) {
+					return
+				}
+			}
+		}
+	}, func(r intcodeBatchResult) bool {
+		switch {
+		case r.Err != nil:
+			probeErr = errors.Wrapf(r.Err, "Probe of %d,%d failed", r.Inputs[0], r.Inputs[1])
+			return false
+		case len(r.Result.Outputs) != 1:
+			probeErr = errors.Errorf("Probe of %d,%d yielded %d outputs", r.Inputs[0], r.Inputs[1], len(r.Result.Outputs))
+			return false
+		}
 
-			// Submit coordinates
-			in <- x
-			in <- y
+		// Count fields with tractor beam
+		count += r.Result.Outputs[0]
+		return true
+	})
 
-			// Count fields with tractor beam
-			o := <-out
-			count += o
-		}
+	switch {
+	case err != nil:
+		return 0, err
+	case probeErr != nil:
+		return 0, probeErr
 	}
 
-	return count
+	return count, nil
 }
 
-func day19Find100x100ShipPlace(code []int64) int64 {
+func day19Find100x100ShipPlace(code []int64) (int64, error) {
 	var (
 		x, y     int64
 		bvL, bvR int64
+		batch    = newIntcodeBatch(code)
 	)
 
-	getBeamVectors := func() {
+	getBeamVectors := func() error {
 		for x := int64(0); x <= math.MaxInt64; x++ {
+			inBeam, err := day19Probe(batch, x, 100)
+			if err != nil {
+				return err
+			}
 
-			var (
-				in  = make(chan int64)
-				out = make(chan int64)
-			)
-			go executeIntcode(code, in, out)
-
-			// Submit coordinates
-			in <- x
-			in <- 100
-
-			// Count fields with tractor beam
-			o := <-out
 			switch {
-			case o == 0 && bvR == 0:
+			case !inBeam && bvR == 0:
 				// Did not yet find the beam
-			case o == 1 && bvL == 0:
+			case inBeam && bvL == 0:
 				// Left beam end has not been set
 				bvL, bvR = x, x
-			case o == 1 && x > bvR:
+			case inBeam && x > bvR:
 				// New right "edge" found
 				bvR = x
-			case 0 == 0 && bvR > 0:
+			case bvR > 0:
 				// End of right edge found, end
-				return
+				return nil
 			}
 
 		}
+		return nil
 	}
 
-	checkCoordinate := func(x, y int64) day19Fact {
-		var facts day19Fact
+	checkCoordinate := func(x, y int64) (day19Fact, error) {
+		var (
+			facts  day19Fact
+			flags  []day19Fact
+			inputs [][]int64
+		)
 
 		for flag, c := range map[day19Fact][2]int64{
 			day19FactNoMoveDownPossible:  {x, y + 100},
@@ -115,39 +140,46 @@ func day19Find100x100ShipPlace(code []int64) int64 {
 				continue
 			}
 
-			var (
-				in  = make(chan int64)
-				out = make(chan int64)
-			)
-			defer close(in)
-
-			go executeIntcode(code, in, out)
+			flags = append(flags, flag)
+			inputs = append(inputs, []int64{c[0], c[1]})
+		}
 
-			// Submit coordinates
-			in <- c[0]
-			in <- c[1]
+		results, err := batch.run(context.Background(), inputs)
+		if err != nil {
+			return 0, err
+		}
 
-			o := <-out
-			if o == 0 {
-				facts |= flag
+		for i, r := range results {
+			if r.Err != nil {
+				return 0, errors.Wrapf(r.Err, "Probe of %d,%d failed", r.Inputs[0], r.Inputs[1])
+			}
+			if len(r.Result.Outputs) != 1 {
+				return 0, errors.Errorf("Probe of %d,%d yielded %d outputs", r.Inputs[0], r.Inputs[1], len(r.Result.Outputs))
+			}
+			if r.Result.Outputs[0] == 0 {
+				facts |= flags[i]
 			}
-
 		}
 
-		return facts
+		return facts, nil
 	}
 
 	// Initially get vectors for beam edges
-	getBeamVectors()
+	if err := getBeamVectors(); err != nil {
+		return 0, err
+	}
 
 	// Try to get to the best position through movement
 	var success bool
 	for !success {
-		var f = checkCoordinate(x, y)
+		f, err := checkCoordinate(x, y)
+		if err != nil {
+			return 0, err
+		}
 
 		switch {
 		case f.has(day19FactOriginNotInBeam):
-			panic("Origin placed outside beam")
+			return 0, errors.Errorf("Origin %d,%d placed outside beam", x, y)
 
 		case f.has(day19FactX100NotInBeam) || f.has(day19FactY100NotInBeam):
 			// Ship does not fit, move further away
@@ -169,14 +201,15 @@ func day19Find100x100ShipPlace(code []int64) int64 {
 		}
 	}
 
-	log.Printf("Result before force-move: x=%d y=%d res=%d", x, y, x*10000+y)
-
 	// This MIGHT not be the perfect position, force further movement
 	// by ignoring some factors set above in order to compensate inaccurate
 	// vectors due to working with integers only
 	var fX, fY = x, y
 	for success {
-		var f = checkCoordinate(fX, fY)
+		f, err := checkCoordinate(fX, fY)
+		if err != nil {
+			return 0, err
+		}
 
 		if !f.has(day19FactX100NotInBeam) && !f.has(day19FactY100NotInBeam) {
 			// Found a better position through forcing
@@ -197,35 +230,37 @@ func day19Find100x100ShipPlace(code []int64) int64 {
 		}
 	}
 
-	log.Printf("Result after force-move:  x=%d y=%d res=%d", x, y, x*10000+y)
-
-	return x*10000 + y
+	return x*10000 + y, nil
 }
 
-func solveDay19Part1(inFile string) (int64, error) {
-	rawCode, err := ioutil.ReadFile(inFile)
+func solveDay19Part1(r io.Reader) (int64, error) {
+	rawCode, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read intcode")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(rawCode)))
+	code, err := ParseIntcode(strings.TrimSpace(string(rawCode)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode")
 	}
 
-	return day19CountFieldsInTractorBeam(code, 49, 49), nil
+	return day19CountFieldsInTractorBeam(code, 49, 49)
 }
 
-func solveDay19Part2(inFile string) (int64, error) {
-	rawCode, err := ioutil.ReadFile(inFile)
+func solveDay19Part2(r io.Reader) (int64, error) {
+	rawCode, err := ioutil.ReadAll(r)
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to read intcode")
 	}
 
-	code, err := parseIntcode(strings.TrimSpace(string(rawCode)))
+	code, err := ParseIntcode(strings.TrimSpace(string(rawCode)))
 	if err != nil {
 		return 0, errors.Wrap(err, "Unable to parse intcode")
 	}
 
-	return day19Find100x100ShipPlace(code), nil
+	return day19Find100x100ShipPlace(code)
+}
+
+func init() {
+	registerSolver(19, solverParts{int64Part(solveDay19Part1), int64Part(solveDay19Part2)})
 }
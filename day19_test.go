@@ -3,19 +3,26 @@ package aoc2019
 import "testing"
 
 func TestCalculateDay19_Part1(t *testing.T) {
-	res, err := solveDay19Part1("day19_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay19Part1(readInput(t, "day19_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 19 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 19 Part 1: %d", res)
+	checkAnswer(t, 19, 1, NumberAnswer(res))
 }
 
 func TestCalculateDay19_Part2(t *testing.T) {
-	res, err := solveDay19Part2("day19_input.txt")
+	defer checkGoroutineLeaks(t)()
+
+	res, err := solveDay19Part2(readInput(t, "day19_input.txt"))
 	if err != nil {
 		t.Fatalf("Day 19 solver failed: %s", err)
 	}
 
-	t.Logf("Solution Day 19 Part 2: %d", res)
+	checkAnswer(t, 19, 2, NumberAnswer(res))
 }
+
+func BenchmarkCalculateDay19_Part1(b *testing.B) { benchmarkSolver(b, 19, 1) }
+func BenchmarkCalculateDay19_Part2(b *testing.B) { benchmarkSolver(b, 19, 2) }
@@ -0,0 +1,293 @@
+package aoc2019
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Point is a position on a grid, Y grows downwards
+type Point struct{ X, Y int }
+
+// Pt is shorthand for Point{x, y}
+func Pt(x, y int) Point { return Point{X: x, Y: y} }
+
+// Add returns the point moved by q
+func (p Point) Add(q Point) Point { return Point{X: p.X + q.X, Y: p.Y + q.Y} }
+
+// Sub returns the vector from q to p
+func (p Point) Sub(q Point) Point { return Point{X: p.X - q.X, Y: p.Y - q.Y} }
+
+// Mul returns the point with both coordinates multiplied by n
+func (p Point) Mul(n int) Point { return Point{X: p.X * n, Y: p.Y * n} }
+
+// Move returns the point one step into the direction
+func (p Point) Move(d Direction) Point { return p.Add(d.Delta()) }
+
+// Neighbors returns the four adjacent points in the order up, right,
+// down, left
+func (p Point) Neighbors() [4]Point {
+	return [4]Point{p.Move(DirUp), p.Move(DirRight), p.Move(DirDown), p.Move(DirLeft)}
+}
+
+// Direction is one of the four directions on a grid, turning right
+// increases it
+type Direction int
+
+const (
+	DirUp Direction = iota
+	DirRight
+	DirDown
+	DirLeft
+)
+
+var directionDeltas = [...]Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+// Delta returns the step taken when moving into the direction
+func (d Direction) Delta() Point { return directionDeltas[d] }
+
+// TurnLeft returns the direction rotated by 90° counter-clockwise
+func (d Direction) TurnLeft() Direction { return (d + 3) % 4 }
+
+// TurnRight returns the direction rotated by 90° clockwise
+func (d Direction) TurnRight() Direction { return (d + 1) % 4 }
+
+// Reverse returns the opposite direction
+func (d Direction) Reverse() Direction { return (d + 2) % 4 }
+
+func (d Direction) String() string { return [...]string{"up", "right", "down", "left"}[d] }
+
+// Bounds is the smallest rectangle containing all points added to it,
+// Min and Max are inclusive. The zero value contains no points.
+type Bounds struct {
+	Min, Max Point
+	set      bool
+}
+
+// NewBounds creates bounds containing the given points
+func NewBounds(points ...Point) Bounds {
+	var b Bounds
+	for _, p := range points {
+		b.Extend(p)
+	}
+	return b
+}
+
+// Extend grows the bounds to contain the point
+func (b *Bounds) Extend(p Point) {
+	if !b.set {
+		b.Min, b.Max, b.set = p, p, true
+		return
+	}
+
+	if p.X < b.Min.X {
+		b.Min.X = p.X
+	}
+	if p.Y < b.Min.Y {
+		b.Min.Y = p.Y
+	}
+	if p.X > b.Max.X {
+		b.Max.X = p.X
+	}
+	if p.Y > b.Max.Y {
+		b.Max.Y = p.Y
+	}
+}
+
+// Contains checks whether the point is inside the bounds
+func (b Bounds) Contains(p Point) bool {
+	return b.set && p.X >= b.Min.X && p.X <= b.Max.X && p.Y >= b.Min.Y && p.Y <= b.Max.Y
+}
+
+// Empty reports whether no point was added to the bounds
+func (b Bounds) Empty() bool { return !b.set }
+
+// Width returns the number of columns inside the bounds
+func (b Bounds) Width() int {
+	if !b.set {
+		return 0
+	}
+	return b.Max.X - b.Min.X + 1
+}
+
+// Height returns the number of rows inside the bounds
+func (b Bounds) Height() int {
+	if !b.set {
+		return 0
+	}
+	return b.Max.Y - b.Min.Y + 1
+}
+
+// Rect converts the bounds into an image rectangle
+func (b Bounds) Rect() image.Rectangle {
+	if !b.set {
+		return image.Rectangle{}
+	}
+	return image.Rect(b.Min.X, b.Min.Y, b.Max.X+1, b.Max.Y+1)
+}
+
+// Grid stores a value for points on a plane
+type Grid interface {
+	// Get returns the value of the point and whether it is part of the
+	// grid
+	Get(p Point) (int64, bool)
+	Set(p Point, v int64)
+	Bounds() Bounds
+	// Each calls fn for every point of the grid
+	Each(fn func(p Point, v int64))
+}
+
+// GridString draws the grid row by row using the runes returned by
+// render, points not part of the grid are rendered with ok=false
+func GridString(g Grid, render func(p Point, v int64, ok bool) rune) string {
+	var (
+		b   = g.Bounds()
+		out strings.Builder
+	)
+
+	for y := b.Min.Y; y <= b.Max.Y && !b.Empty(); y++ {
+		for x := b.Min.X; x <= b.Max.X; x++ {
+			p := Pt(x, y)
+			v, ok := g.Get(p)
+			out.WriteRune(render(p, v, ok))
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.String()
+}
+
+// DenseGrid stores a value for every point of a fixed rectangle row by
+// row
+type DenseGrid struct {
+	bounds Bounds
+	cells  []int64
+}
+
+// NewDenseGrid creates a grid covering the bounds with all values set to
+// zero
+func NewDenseGrid(b Bounds) *DenseGrid {
+	return &DenseGrid{bounds: b, cells: make([]int64, b.Width()*b.Height())}
+}
+
+// ParseDenseGrid reads a grid of characters, one row per line, with
+// the top left character at 0,0. Values are the byte values of the
+// characters, all lines must have the same length.
+func ParseDenseGrid(r io.Reader) (*DenseGrid, error) {
+	var (
+		lines   []string
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Unable to read grid")
+	}
+
+	// Trailing empty lines are not part of the grid
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return NewDenseGrid(Bounds{}), nil
+	}
+
+	if len(lines[0]) == 0 {
+		return nil, errors.New("Grid starts with an empty line")
+	}
+
+	g := NewDenseGrid(NewBounds(Pt(0, 0), Pt(len(lines[0])-1, len(lines)-1)))
+	for y, l := range lines {
+		if len(l) != len(lines[0]) {
+			return nil, errors.Errorf("Line %d has length %d, expected %d", y+1, len(l), len(lines[0]))
+		}
+
+		for x := 0; x < len(l); x++ {
+			g.cells[y*len(l)+x] = int64(l[x])
+		}
+	}
+
+	return g, nil
+}
+
+// Get implements Grid, all points inside the bounds are part of the grid
+func (d *DenseGrid) Get(p Point) (int64, bool) {
+	if !d.bounds.Contains(p) {
+		return 0, false
+	}
+	return d.cells[d.index(p)], true
+}
+
+// Set implements Grid, setting a point outside the bounds panics
+func (d *DenseGrid) Set(p Point, v int64) {
+	if !d.bounds.Contains(p) {
+		panic(errors.Errorf("Point %d,%d is outside the grid", p.X, p.Y))
+	}
+	d.cells[d.index(p)] = v
+}
+
+// Bounds implements Grid
+func (d *DenseGrid) Bounds() Bounds { return d.bounds }
+
+// Each implements Grid, points are visited row by row
+func (d *DenseGrid) Each(fn func(p Point, v int64)) {
+	var w = d.bounds.Width()
+	for i, v := range d.cells {
+		fn(Pt(d.bounds.Min.X+i%w, d.bounds.Min.Y+i/w), v)
+	}
+}
+
+// Clone returns a copy of the grid
+func (d *DenseGrid) Clone() *DenseGrid {
+	var c = &DenseGrid{bounds: d.bounds, cells: make([]int64, len(d.cells))}
+	copy(c.cells, d.cells)
+	return c
+}
+
+func (d *DenseGrid) index(p Point) int {
+	return (p.Y-d.bounds.Min.Y)*d.bounds.Width() + p.X - d.bounds.Min.X
+}
+
+// SparseGrid stores values for arbitrary points, growing its bounds as
+// points are set
+type SparseGrid struct {
+	bounds Bounds
+	cells  map[Point]int64
+}
+
+// NewSparseGrid creates an empty grid
+func NewSparseGrid() *SparseGrid {
+	return &SparseGrid{cells: map[Point]int64{}}
+}
+
+// Get implements Grid
+func (s *SparseGrid) Get(p Point) (int64, bool) {
+	v, ok := s.cells[p]
+	return v, ok
+}
+
+// Set implements Grid
+func (s *SparseGrid) Set(p Point, v int64) {
+	s.cells[p] = v
+	s.bounds.Extend(p)
+}
+
+// Bounds implements Grid
+func (s *SparseGrid) Bounds() Bounds { return s.bounds }
+
+// Each implements Grid, points are visited in no particular order
+func (s *SparseGrid) Each(fn func(p Point, v int64)) {
+	for p, v := range s.cells {
+		fn(p, v)
+	}
+}
+
+// Len returns the number of points set
+func (s *SparseGrid) Len() int { return len(s.cells) }
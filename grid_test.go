@@ -0,0 +1,124 @@
+package aoc2019
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGridDirections(t *testing.T) {
+	var (
+		d   = DirUp
+		pos Point
+	)
+
+	// Walking a square turning right returns to the start
+	for i := 0; i < 4; i++ {
+		pos = pos.Move(d).Move(d)
+		d = d.TurnRight()
+	}
+
+	if pos != (Point{}) || d != DirUp {
+		t.Errorf("Square walk ended at %v facing %s", pos, d)
+	}
+
+	for _, d := range []Direction{DirUp, DirRight, DirDown, DirLeft} {
+		if l := d.TurnRight().TurnLeft(); l != d {
+			t.Errorf("Turning right and left from %s yields %s", d, l)
+		}
+		if r := d.Reverse(); r.Delta() != d.Delta().Mul(-1) {
+			t.Errorf("Reverse of %s is %s", d, r)
+		}
+	}
+
+	if n := Pt(2, 3).Neighbors(); n != [4]Point{{2, 2}, {3, 3}, {2, 4}, {1, 3}} {
+		t.Errorf("Unexpected neighbors: %v", n)
+	}
+}
+
+func TestGridBounds(t *testing.T) {
+	var b Bounds
+	if !b.Empty() || b.Contains(Point{}) || b.Width() != 0 {
+		t.Fatalf("Zero bounds are not empty: %+v", b)
+	}
+
+	b = NewBounds(Pt(3, -2), Pt(-1, 4))
+	b.Extend(Pt(0, 0))
+
+	if b.Min != Pt(-1, -2) || b.Max != Pt(3, 4) {
+		t.Errorf("Unexpected bounds: %v - %v", b.Min, b.Max)
+	}
+
+	if b.Width() != 5 || b.Height() != 7 {
+		t.Errorf("Unexpected size: %dx%d", b.Width(), b.Height())
+	}
+
+	if !b.Contains(Pt(3, 4)) || b.Contains(Pt(4, 4)) {
+		t.Errorf("Contains does not respect inclusive bounds")
+	}
+
+	if r := b.Rect(); r.Dx() != 5 || r.Dy() != 7 || r.Min.X != -1 {
+		t.Errorf("Unexpected rectangle: %v", r)
+	}
+}
+
+func TestGridBackends(t *testing.T) {
+	render := func(p Point, v int64, ok bool) rune {
+		if !ok {
+			return ' '
+		}
+		return rune('0' + v)
+	}
+
+	dense := NewDenseGrid(NewBounds(Pt(-1, -1), Pt(1, 0)))
+	sparse := NewSparseGrid()
+
+	for _, g := range []Grid{dense, sparse} {
+		g.Set(Pt(-1, -1), 1)
+		g.Set(Pt(1, 0), 2)
+
+		if v, ok := g.Get(Pt(1, 0)); !ok || v != 2 {
+			t.Errorf("%T: Unexpected value %d (%v)", g, v, ok)
+		}
+
+		var sum int64
+		g.Each(func(p Point, v int64) { sum += v })
+		if sum != 3 {
+			t.Errorf("%T: Unexpected sum of values: %d", g, sum)
+		}
+	}
+
+	if s := GridString(dense, render); s != "100\n002\n" {
+		t.Errorf("Unexpected dense rendering: %q", s)
+	}
+
+	if s := GridString(sparse, render); s != "1  \n  2\n" {
+		t.Errorf("Unexpected sparse rendering: %q", s)
+	}
+
+	if _, ok := dense.Get(Pt(2, 0)); ok {
+		t.Errorf("Point outside dense grid was found")
+	}
+
+	if sparse.Len() != 2 {
+		t.Errorf("Unexpected number of sparse points: %d", sparse.Len())
+	}
+}
+
+func TestParseDenseGrid(t *testing.T) {
+	g, err := ParseDenseGrid(strings.NewReader("#..\n.#.\n\n"))
+	if err != nil {
+		t.Fatalf("Parsing grid failed: %s", err)
+	}
+
+	if b := g.Bounds(); b.Width() != 3 || b.Height() != 2 {
+		t.Errorf("Unexpected size: %dx%d", b.Width(), b.Height())
+	}
+
+	if v, _ := g.Get(Pt(1, 1)); v != '#' {
+		t.Errorf("Unexpected value at 1,1: %c", v)
+	}
+
+	if _, err = ParseDenseGrid(strings.NewReader("#..\n.#\n")); err == nil {
+		t.Errorf("Ragged grid was accepted")
+	}
+}
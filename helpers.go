@@ -1,6 +1,13 @@
 package aoc2019
 
-import "math"
+import (
+	"image"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+)
 
 func greatestCommonDivisor(a, b int64) int64 {
 	for b != 0 {
@@ -18,3 +25,13 @@ func leastCommonMultiple(a, b int64) int64 {
 func manhattenDistance(x1, y1, x2, y2 int) int {
 	return int(math.Abs(float64(x1-x2)) + math.Abs(float64(y1-y2)))
 }
+
+func savePNG(fileName string, img image.Image) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return errors.Wrap(err, "Unable to open result image file")
+	}
+	defer f.Close()
+
+	return errors.Wrap(png.Encode(f, img), "Unable to store image")
+}
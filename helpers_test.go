@@ -1,6 +1,120 @@
 package aoc2019
 
-import "testing"
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkSolver runs the registered solver of the part on the input
+// of the day
+func benchmarkSolver(b *testing.B, day, part int) {
+	raw, err := ioutil.ReadFile(InputFile(day))
+	if err != nil {
+		b.Fatalf("Unable to read input: %s", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Solve(day, part, bytes.NewReader(raw)); err != nil {
+			b.Fatalf("Solver failed: %s", err)
+		}
+	}
+}
+
+// checkAnswer compares the answer of the part with the answers file of
+// the day's input, unknown answers are logged as unverified
+func checkAnswer(t *testing.T, day, part int, answer Answer) {
+	t.Helper()
+
+	answers, err := LoadAnswers(AnswersFile(InputFile(day)))
+	if err != nil {
+		t.Fatalf("Unable to load answers: %s", err)
+	}
+
+	switch answers.Check(day, part, answer) {
+	case AnswerCorrect:
+		t.Logf("Solution Day %d Part %d: %s", day, part, answer)
+	case AnswerWrong:
+		exp, _ := answers.Get(day, part)
+		t.Errorf("Wrong solution Day %d Part %d: exp=%q got=%q", day, part, exp, answer)
+	default:
+		t.Logf("Solution Day %d Part %d: %s (unverified)", day, part, answer)
+	}
+}
+
+// readInput returns the content of the puzzle input file to be passed
+// to the solvers
+func readInput(t *testing.T, fileName string) io.Reader {
+	t.Helper()
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Unable to read input: %s", err)
+	}
+	return bytes.NewReader(raw)
+}
+
+// checkGoroutineLeaks reports goroutines of this package started during
+// the test and still running when the returned function is called:
+//
+//	defer checkGoroutineLeaks(t)()
+func checkGoroutineLeaks(t *testing.T) func() {
+	var before = packageGoroutines()
+
+	return func() {
+		var leaked []string
+
+		// Stopped goroutines might need a moment to finish
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+			leaked = leaked[:0]
+			for id, stack := range packageGoroutines() {
+				if _, ok := before[id]; !ok {
+					leaked = append(leaked, stack)
+				}
+			}
+
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+		}
+
+		for _, stack := range leaked {
+			t.Errorf("Leaked goroutine:\n%s", stack)
+		}
+	}
+}
+
+// packageGoroutines returns the stacks of all goroutines executing code
+// of this package by their ID, except the calling one
+func packageGoroutines() map[string]string {
+	var buf = make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var stacks = map[string]string{}
+	for i, stack := range strings.Split(string(buf), "\n\n") {
+		if i == 0 || !strings.Contains(stack, "github.com/Luzifer/aoc2019.") {
+			// First one is the calling goroutine
+			continue
+		}
+
+		id := strings.Fields(stack)[1]
+		stacks[id] = stack
+	}
+
+	return stacks
+}
 
 func TestGreatestCommonDivisor(t *testing.T) {
 	for expDivisor, vals := range map[int64][2]int64{
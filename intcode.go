@@ -3,6 +3,8 @@ package aoc2019
 import (
 	"context"
 	"log"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -35,6 +37,20 @@ const (
 	opCodeTypeExit           opCodeType = 99 // Day 02
 )
 
+// IntcodeArithmetic selects how the arithmetic opcodes handle values
+// exceeding the int64 range
+type IntcodeArithmetic int
+
+const (
+	// IntcodeArithmeticWrap silently wraps around on overflow (default)
+	IntcodeArithmeticWrap IntcodeArithmetic = iota
+	// IntcodeArithmeticChecked stops the program with an error on overflow
+	IntcodeArithmeticChecked
+	// IntcodeArithmeticBig executes the program with arbitrary precision,
+	// addresses, jump targets and opcodes still need to fit into int64
+	IntcodeArithmeticBig
+)
+
 type opCode struct {
 	Type  opCodeType
 	flags []opCodeFlag
@@ -69,6 +85,19 @@ func parseOpCode(in int64) opCode {
 	return out
 }
 
+func additionOverflows(a, b int64) bool {
+	var r = a + b
+	return (b > 0 && r < a) || (b < 0 && r > a)
+}
+
+func multiplicationOverflows(a, b int64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	var r = a * b
+	return r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64)
+}
+
 func cloneIntcode(in []int64) []int64 {
 	out := make([]int64, len(in))
 	for i, v := range in {
@@ -77,7 +106,8 @@ func cloneIntcode(in []int64) []int64 {
 	return out
 }
 
-func parseIntcode(code string) ([]int64, error) {
+// ParseIntcode reads a comma separated Intcode program into memory cells
+func ParseIntcode(code string) ([]int64, error) {
 	parts := strings.Split(code, ",")
 
 	var out []int64
@@ -92,19 +122,109 @@ func parseIntcode(code string) ([]int64, error) {
 	return out, nil
 }
 
-type intcodeParams struct {
+// IntcodeParams describes a single run of an Intcode program
+type IntcodeParams struct {
 	// Intcode program to execute
 	Code []int64
-	// Context to execute the program in (program might hang on input if context is closed during input directive)
+	// Context to execute the program in, cancelling it also aborts
+	// blocked channel reads and writes
 	Context context.Context
-	// Channel / Callback to query on input directive
+	// Channel / Callback to query on input directive. An input channel
+	// is owned by the caller, closing it stops the program with
+	// ErrIntcodeInputClosed.
 	In interface{}
-	// Channel to use for output directive
-	Out chan int64
+	// Channel / Callback to use for output directive. An output channel
+	// is owned by the program: nobody else may send to it and it is
+	// closed when the program stops.
+	Out interface{}
+	// Arithmetic mode to use for addition and multiplication
+	Arithmetic IntcodeArithmetic
+	// Optional hook for every input consumed and output produced
+	OnIO func(IntcodeEvent) error
+	// Inputs to consume before querying In
+	Inputs []int64
+	// Maximum number of instructions to execute, 0 for no limit
+	MaxSteps int64
+	// Optional tracker for the labels values depend on
+	Taint *IntcodeTaintTracker
+}
+
+// intcodeInputCallback converts the supported input types into a
+// callback, reads from channels are aborted when the context is cancelled
+func intcodeInputCallback(ctx context.Context, in interface{}) (func() (int64, error), error) {
+	var inCB func() (int64, error)
+
+	switch in := in.(type) {
+	case nil:
+		// Handled below
+	case chan int64:
+		if in != nil {
+			inCB = func() (int64, error) {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						return 0, ErrIntcodeInputClosed
+					}
+					return v, nil
+				case <-ctx.Done():
+					return 0, errors.Wrap(ctx.Err(), "Context closed during input")
+				}
+			}
+		}
+	case func() (int64, error):
+		inCB = in
+	default:
+		return nil, errors.New("Unsupported input type")
+	}
+
+	if inCB == nil {
+		// Channel / Callback was not passed or passed as typed nil
+		inCB = func() (int64, error) { return 0, errors.New("No input available") }
+	}
+
+	return inCB, nil
+}
+
+// intcodeOutputCallback converts the supported output types into a
+// callback and a function to call when the program exits, writes to
+// channels are aborted when the context is cancelled
+func intcodeOutputCallback(ctx context.Context, out interface{}) (func(int64) error, func(), error) {
+	var (
+		closeOut = func() {}
+		outCB    func(int64) error
+	)
+
+	switch out := out.(type) {
+	case nil:
+		// Handled below
+	case chan int64:
+		if out != nil {
+			closeOut = func() { close(out) }
+			outCB = func(v int64) error {
+				select {
+				case out <- v:
+					return nil
+				case <-ctx.Done():
+					return errors.Wrap(ctx.Err(), "Context closed during output")
+				}
+			}
+		}
+	case func(int64) error:
+		outCB = out
+	default:
+		return nil, nil, errors.New("Unsupported output type")
+	}
+
+	if outCB == nil {
+		// Channel / Callback was not passed or passed as typed nil
+		outCB = func(int64) error { return errors.New("No output available") }
+	}
+
+	return outCB, closeOut, nil
 }
 
 func executeIntcode(code []int64, in interface{}, out chan int64) ([]int64, error) {
-	return executeIntcodeWithParams(intcodeParams{
+	return ExecuteIntcodeWithParams(IntcodeParams{
 		Code:    code,
 		Context: context.Background(),
 		In:      in,
@@ -112,158 +232,450 @@ func executeIntcode(code []int64, in interface{}, out chan int64) ([]int64, erro
 	})
 }
 
-func executeIntcodeWithParams(params intcodeParams) ([]int64, error) {
-	var (
-		code         = params.Code
-		inCB         func() (int64, error)
-		pos          int64
-		relativeBase int64
-	)
+// ExecuteIntcodeWithParams runs the program until it exits and returns
+// the final state of its memory. With IntcodeArithmeticBig intermediate
+// results may exceed int64 while inputs, outputs and the final memory
+// must fit into int64 again.
+func ExecuteIntcodeWithParams(params IntcodeParams) ([]int64, error) {
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
 
-	if params.Out != nil {
-		defer close(params.Out)
+	inCB, err := intcodeInputCallback(params.Context, params.In)
+	if err != nil {
+		return nil, err
 	}
 
-	switch params.In.(type) {
-	case nil:
-		inCB = func() (int64, error) { return 0, errors.New("No input available") }
-	case chan int64:
-		inCB = func() (int64, error) { return <-(params.In.(chan int64)), nil }
-	case func() (int64, error):
-		inCB = params.In.(func() (int64, error))
-	default:
-		return nil, errors.New("Unsupported input type")
+	outCB, closeOut, err := intcodeOutputCallback(params.Context, params.Out)
+	if err != nil {
+		return nil, err
 	}
+	defer closeOut()
 
-	transformPos := func(param int64, op opCode, write bool) int64 {
-		var addr int64
+	m := newIntcodeMachineFromParams(params)
+	if err := m.Run(params.Context, inCB, outCB); err != nil {
+		return nil, err
+	}
 
-		switch op.GetFlag(param) {
+	if err := m.checkInt64Memory(); err != nil {
+		return nil, err
+	}
 
-		case opCodeFlagImmediate:
-			if write {
-				addr = code[pos+param]
-			} else {
-				addr = pos + param
-			}
+	return m.memory, nil
+}
 
-		case opCodeFlagPosition:
-			addr = code[pos+param]
+// ErrIntcodeInputExhausted can be returned by an input callback to pause
+// the machine in front of the input directive: the machine can be
+// resumed after more input was fed
+var ErrIntcodeInputExhausted = errors.New("Input exhausted")
+
+// ErrIntcodeInputClosed is returned when the input channel was closed
+// while the program waited for input
+var ErrIntcodeInputClosed = errors.New("Input channel closed")
+
+// ErrIntcodeStepLimit is returned when the machine reached its MaxSteps,
+// the machine can be resumed after raising the limit
+var ErrIntcodeStepLimit = errors.New("Instruction limit reached")
+
+// intcodeAbort carries an error out of the instruction currently
+// executed (i.e. a failed device access)
+type intcodeAbort struct{ err error }
+
+// IntcodeMachine holds the full state of an Intcode program and can be
+// paused and resumed between instructions
+type IntcodeMachine struct {
+	// Arithmetic mode to use for addition and multiplication
+	Arithmetic IntcodeArithmetic
+	// Optional callbacks used instead of the input and output callbacks
+	// with IntcodeArithmeticBig to read and write values exceeding int64.
+	// Outputs exceeding int64 are not part of Outputs and not passed to
+	// OnIO.
+	BigIn  func() (*big.Int, error)
+	BigOut func(*big.Int) error
+	// Optional coverage to record executed instructions into
+	Coverage *IntcodeCoverage
+	// Optional tracker for the labels values depend on
+	Taint *IntcodeTaintTracker
+	// Optional hook called for every input consumed and every output
+	// produced by the program, an error stops the machine
+	OnIO func(IntcodeEvent) error
+	// Maximum number of instructions to execute in total, 0 for no limit
+	MaxSteps int64
+
+	memory       []int64
+	pos          int64
+	relativeBase int64
+
+	// Memory is shared with a program image and must be copied into
+	// buffer before the first write
+	shared bool
+	buffer []int64
+
+	// Inputs fed into the machine but not yet consumed by the program
+	pendingInput []int64
+	// All outputs written by the program
+	outputs []int64
+	// Program reached the exit directive
+	exited bool
+	// Number of instructions executed
+	steps int64
+
+	// Cells holding values exceeding int64, only allocated with
+	// IntcodeArithmeticBig once such a value is stored
+	bigCells map[int64]*big.Int
+
+	// Devices mapped into the memory
+	devices []intcodeDeviceMapping
+}
 
-		case opCodeFlagRelative:
-			addr = code[pos+param] + int64(relativeBase)
+// NewIntcodeMachine creates a machine at the start of the given program,
+// the program is used as memory and will be modified
+func NewIntcodeMachine(code []int64) *IntcodeMachine {
+	return &IntcodeMachine{memory: code}
+}
 
-		default:
-			panic(errors.Errorf("Unexpected opCodeFlag %d", op.GetFlag(param)))
+// Exited reports whether the program reached the exit directive
+func (i *IntcodeMachine) Exited() bool { return i.exited }
 
+// Feed queues inputs to be consumed before querying the input callback
+func (i *IntcodeMachine) Feed(values ...int64) {
+	i.pendingInput = append(i.pendingInput, values...)
+}
+
+// Memory returns the current memory of the machine
+func (i *IntcodeMachine) Memory() []int64 { return i.memory }
+
+// Outputs returns all outputs written by the program so far
+func (i *IntcodeMachine) Outputs() []int64 { return i.outputs }
+
+// Steps returns the number of instructions executed so far
+func (i *IntcodeMachine) Steps() int64 { return i.steps }
+
+// Run executes the program until it exits. When the input callback
+// returns ErrIntcodeInputExhausted or the context is cancelled the
+// machine stops in front of the current instruction and Run can be
+// called again to resume the program.
+func (i *IntcodeMachine) Run(ctx context.Context, in func() (int64, error), out func(int64) error) (err error) {
+	// Device errors, negative addresses and values exceeding int64
+	// abort the current instruction
+	defer func() {
+		if r := recover(); r != nil {
+			abort, ok := r.(intcodeAbort)
+			if !ok {
+				panic(r)
+			}
+			err = abort.err
 		}
+	}()
 
-		return addr
+	if in == nil {
+		in = func() (int64, error) { return 0, ErrIntcodeInputExhausted }
 	}
 
-	getParamValue := func(param int64, op opCode) int64 {
-		var addr = transformPos(param, op, false)
+	if out == nil {
+		out = func(int64) error { return errors.New("No output available") }
+	}
 
-		if addr >= int64(len(code)) {
-			return 0
-		}
+	var (
+		bigArith = i.Arithmetic == IntcodeArithmeticBig
+		checked  = i.Arithmetic == IntcodeArithmeticChecked
+	)
 
-		return code[addr]
+	if bigArith && i.BigOut != nil {
+		out = func(v int64) error { return i.BigOut(big.NewInt(v)) }
 	}
 
-	setParamValue := func(param, value int64, op opCode) {
-		var addr = transformPos(param, op, false)
-
-		if addr >= int64(len(code)) {
-			// Write outside memory, increase memory
-			var tmp = make([]int64, addr+1)
-			for i, v := range code {
-				tmp[i] = v
-			}
-			code = tmp
+	for !i.exited {
+		if i.pos < 0 || i.pos >= int64(len(i.memory)) {
+			return errors.Errorf("Code position out of bounds: %d (len=%d)", i.pos, len(i.memory))
 		}
 
-		code[addr] = value
-	}
-
-	for {
-		if pos >= int64(len(code)) {
-			return nil, errors.Errorf("Code position out of bounds: %d (len=%d)", pos, len(code))
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "Context closed")
 		}
 
-		if err := params.Context.Err(); err != nil {
-			return nil, errors.Wrap(err, "Context closed")
+		if i.MaxSteps > 0 && i.steps >= i.MaxSteps {
+			return ErrIntcodeStepLimit
 		}
 
 		// Position is expected to be an OpCode
-		op := parseOpCode(code[pos])
+		ip := i.pos
+		op := parseOpCode(i.cell(ip))
 
 		if intcodeDebugging {
 			log.Printf("OpCode execution: %#v", op)
 		}
 
+		if i.Taint != nil {
+			switch op.Type {
+			case opCodeTypeAddition, opCodeTypeMultiplication, opCodeTypeLessThan, opCodeTypeEquals:
+				i.Taint.propagate(i, op)
+			}
+		}
+
 		switch op.Type {
 
 		case opCodeTypeAddition: // p1 + p2 => p3
-			setParamValue(3, getParamValue(1, op)+getParamValue(2, op), op)
-			pos += 4
+			if bigArith {
+				i.bigBinaryOp(op)
+				i.pos += 4
+				break
+			}
+			a, b := i.getParamValue(1, op), i.getParamValue(2, op)
+			if checked && additionOverflows(a, b) {
+				return errors.Errorf("Integer overflow at address %d: %d + %d", i.pos, a, b)
+			}
+			i.setParamValue(3, a+b, op)
+			i.pos += 4
 
 		case opCodeTypeMultiplication: // p1 * p2 => p3
-			setParamValue(3, getParamValue(1, op)*getParamValue(2, op), op)
-			pos += 4
+			if bigArith {
+				i.bigBinaryOp(op)
+				i.pos += 4
+				break
+			}
+			a, b := i.getParamValue(1, op), i.getParamValue(2, op)
+			if checked && multiplicationOverflows(a, b) {
+				return errors.Errorf("Integer overflow at address %d: %d * %d", i.pos, a, b)
+			}
+			i.setParamValue(3, a*b, op)
+			i.pos += 4
 
 		case opCodeTypeInput: // in => p1
-			v, err := inCB()
+			var (
+				v   int64
+				bv  *big.Int // Input exceeding int64
+				err error
+			)
+			switch {
+			case len(i.pendingInput) > 0:
+				v, i.pendingInput = i.pendingInput[0], i.pendingInput[1:]
+			case bigArith && i.BigIn != nil:
+				if bv, err = i.BigIn(); err == nil && bv.IsInt64() {
+					v, bv = bv.Int64(), nil
+				}
+			default:
+				v, err = in()
+			}
 			if err != nil {
-				return nil, errors.Wrap(err, "Unable to read input")
+				if errors.Cause(err) == ErrIntcodeInputExhausted {
+					// Instruction will be executed again on resume
+					return ErrIntcodeInputExhausted
+				}
+				return errors.Wrap(err, "Unable to read input")
+			}
+			if i.OnIO != nil && bv == nil {
+				if err := i.OnIO(IntcodeEvent{Step: i.currentStep(), IP: i.pos, Value: v}); err != nil {
+					return err
+				}
 			}
-			setParamValue(1, v, op)
-			pos += 2
+			if bv != nil {
+				i.setBigParamValue(1, new(big.Int).Set(bv), op)
+			} else {
+				i.setParamValue(1, v, op)
+			}
+			if i.Taint != nil {
+				if err := i.Taint.input(i, op); err != nil {
+					return errors.Wrap(err, "Unable to track input")
+				}
+			}
+			i.pos += 2
 
 		case opCodeTypeOutput: // p1 => out
-			params.Out <- getParamValue(1, op)
-			pos += 2
+			if bigArith && i.BigOut != nil {
+				bv := i.bigParamValue(1, op)
+				if !bv.IsInt64() {
+					if err := i.BigOut(new(big.Int).Set(bv)); err != nil {
+						// Instruction will be executed again on resume
+						return errors.Wrap(err, "Unable to write output")
+					}
+					i.pos += 2
+					break
+				}
+			}
+			v := i.getParamValue(1, op)
+			if i.OnIO != nil {
+				if err := i.OnIO(IntcodeEvent{Step: i.currentStep(), IP: i.pos, Output: true, Value: v}); err != nil {
+					return err
+				}
+			}
+			if err := out(v); err != nil {
+				// Output was not delivered, the instruction will be
+				// executed again on resume
+				return errors.Wrap(err, "Unable to write output")
+			}
+			if i.Taint != nil {
+				i.Taint.output(i, op, v)
+			}
+			i.outputs = append(i.outputs, v)
+			i.pos += 2
 
 		case opCodeTypeJumpIfTrue: // p1 != 0 => jmp
-			if getParamValue(1, op) != 0 {
-				pos = getParamValue(2, op)
-				continue
+			cond := i.paramNonZero(op, bigArith)
+			if i.Coverage != nil {
+				i.Coverage.branch(i.pos, cond)
+			}
+			if cond {
+				i.pos = i.getParamValue(2, op)
+			} else {
+				i.pos += 3
 			}
-			pos += 3
 
 		case opCodeTypeJumpIfFalse: // p1 == 0 => jmp
-			if getParamValue(1, op) == 0 {
-				pos = getParamValue(2, op)
-				continue
+			cond := !i.paramNonZero(op, bigArith)
+			if i.Coverage != nil {
+				i.Coverage.branch(i.pos, cond)
+			}
+			if cond {
+				i.pos = i.getParamValue(2, op)
+			} else {
+				i.pos += 3
 			}
-			pos += 3
 
 		case opCodeTypeLessThan: // p1 < p2 => p3
+			if bigArith {
+				i.bigBinaryOp(op)
+				i.pos += 4
+				break
+			}
 			var res int64
-			if getParamValue(1, op) < getParamValue(2, op) {
+			if i.getParamValue(1, op) < i.getParamValue(2, op) {
 				res = 1
 			}
-			setParamValue(3, res, op)
-			pos += 4
+			i.setParamValue(3, res, op)
+			i.pos += 4
 
 		case opCodeTypeEquals: // p1 == p2 => p3
+			if bigArith {
+				i.bigBinaryOp(op)
+				i.pos += 4
+				break
+			}
 			var res int64
-			if getParamValue(1, op) == getParamValue(2, op) {
+			if i.getParamValue(1, op) == i.getParamValue(2, op) {
 				res = 1
 			}
-			setParamValue(3, res, op)
-			pos += 4
+			i.setParamValue(3, res, op)
+			i.pos += 4
 
 		case opCodeTypeAdjRelBase:
-			relativeBase += getParamValue(1, op)
-			pos += 2
+			i.relativeBase += i.getParamValue(1, op)
+			i.pos += 2
 
 		case opCodeTypeExit: // exit
-			return code, nil
+			i.exited = true
 
 		default:
-			return nil, errors.Errorf("Encountered invalid operation %d (parsed %#v)", code[pos], op)
+			return errors.Errorf("Encountered invalid operation %d (parsed %#v)", i.memory[i.pos], op)
 
 		}
+
+		i.complete(ip)
 	}
+
+	return nil
+}
+
+// complete counts the instruction at ip as executed. Only completed
+// instructions are counted, an interrupted one (i.e. input exhausted)
+// is executed again on resume.
+func (i *IntcodeMachine) complete(ip int64) {
+	i.steps++
+	if i.Coverage != nil {
+		i.Coverage.instruction(ip)
+	}
+}
+
+// currentStep returns the number of the instruction being executed,
+// counting from 1
+func (i *IntcodeMachine) currentStep() int64 { return i.steps + 1 }
+
+func (i *IntcodeMachine) transformPos(param int64, op opCode, write bool) int64 {
+	var addr int64
+
+	switch op.GetFlag(param) {
+
+	case opCodeFlagImmediate:
+		if write {
+			addr = i.cell(i.pos + param)
+		} else {
+			addr = i.pos + param
+		}
+
+	case opCodeFlagPosition:
+		addr = i.cell(i.pos + param)
+
+	case opCodeFlagRelative:
+		addr = i.cell(i.pos+param) + i.relativeBase
+
+	default:
+		panic(intcodeAbort{errors.Errorf("Unexpected opCodeFlag %d at address %d", op.GetFlag(param), i.pos)})
+
+	}
+
+	if addr < 0 {
+		panic(intcodeAbort{errors.Errorf("Negative address %d used by instruction at address %d", addr, i.pos)})
+	}
+
+	return addr
+}
+
+func (i *IntcodeMachine) getParamValue(param int64, op opCode) int64 {
+	var addr = i.transformPos(param, op, false)
+
+	if dev, offset := i.device(addr); dev != nil {
+		v, err := dev.Read(i, offset)
+		if err != nil {
+			panic(intcodeAbort{errors.Wrapf(err, "Device read of address %d at address %d failed", addr, i.pos)})
+		}
+		return v
+	}
+
+	if addr >= int64(len(i.memory)) {
+		return 0
+	}
+
+	return i.cell(addr)
+}
+
+// cell returns the value of a memory cell within the memory, cells
+// holding a value exceeding int64 abort the instruction
+func (i *IntcodeMachine) cell(addr int64) int64 {
+	if i.bigCells != nil {
+		if v, ok := i.bigCells[addr]; ok {
+			panic(intcodeAbort{errors.Errorf("Value %s at address %d used by instruction at address %d exceeds int64", v, addr, i.pos)})
+		}
+	}
+	return i.memory[addr]
+}
+
+func (i *IntcodeMachine) setParamValue(param, value int64, op opCode) {
+	var addr = i.transformPos(param, op, false)
+
+	if dev, offset := i.device(addr); dev != nil {
+		if err := dev.Write(i, offset, value); err != nil {
+			panic(intcodeAbort{errors.Wrapf(err, "Device write of address %d at address %d failed", addr, i.pos)})
+		}
+		return
+	}
+
+	if i.shared {
+		i.buffer = append(i.buffer[:0], i.memory...)
+		i.memory = i.buffer
+		i.shared = false
+	}
+
+	if addr >= int64(len(i.memory)) {
+		// Write outside memory, increase memory
+		var tmp = make([]int64, addr+1)
+		copy(tmp, i.memory)
+		i.memory = tmp
+		i.buffer = tmp
+	}
+
+	if i.bigCells != nil {
+		delete(i.bigCells, addr)
+	}
+
+	i.memory[addr] = value
 }
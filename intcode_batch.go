@@ -0,0 +1,199 @@
+package aoc2019
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// intcodeBatch runs one program against many input vectors. Machines are
+// taken from a pool and reset to the program image without copying it:
+// the image is shared until the first write of a run, which copies it
+// into the memory buffer the machine kept from its previous run.
+type intcodeBatch struct {
+	image []int64
+	pool  sync.Pool
+
+	// Number of parallel runs, defaults to number of CPUs
+	Workers int
+	// Instruction limit of every run, see IntcodeMachine.MaxSteps
+	MaxSteps int64
+	// Return a copy of the final memory of every run
+	KeepMemory bool
+}
+
+type intcodeBatchResult struct {
+	Inputs []int64
+	Result IntcodeResult
+	// Error of the run, Result.Reason is IntcodeHaltError if set
+	Err error
+}
+
+func newIntcodeBatch(code []int64) *intcodeBatch {
+	return &intcodeBatch{image: cloneIntcode(code)}
+}
+
+// reset puts the machine into the initial state of the image, the image
+// is never modified by the machine
+func (i *IntcodeMachine) reset(image []int64) {
+	i.memory = image
+	i.shared = true
+	i.pos = 0
+	i.relativeBase = 0
+	i.pendingInput = i.pendingInput[:0]
+	i.outputs = i.outputs[:0]
+	i.exited = false
+	i.steps = 0
+	i.bigCells = nil
+}
+
+// acquire returns a machine in the initial state of the program, it
+// must be passed to release after its results were read
+func (i *intcodeBatch) acquire() *IntcodeMachine {
+	m, ok := i.pool.Get().(*IntcodeMachine)
+	if !ok {
+		m = &IntcodeMachine{}
+	}
+
+	m.reset(i.image)
+	m.MaxSteps = i.MaxSteps
+	return m
+}
+
+func (i *intcodeBatch) release(m *IntcodeMachine) { i.pool.Put(m) }
+
+// runOne executes the program with the given inputs on a pooled machine
+func (i *intcodeBatch) runOne(ctx context.Context, inputs []int64) (IntcodeResult, error) {
+	m := i.acquire()
+	defer i.release(m)
+
+	m.Feed(inputs...)
+	res, err := intcodeResultOf(ctx, m, m.Run(ctx, nil, func(int64) error { return nil }))
+
+	// Buffers of the machine are reused by the next run
+	res.Outputs = append([]int64(nil), res.Outputs...)
+	res.Memory = nil
+	if i.KeepMemory {
+		res.Memory = cloneIntcode(m.memory)
+	}
+
+	return res, err
+}
+
+// stream runs the program for every input vector emitted by inputs and
+// passes the results to handle in the order of the inputs. The inputs
+// generator must stop as soon as emit returns false, returning false
+// from handle stops the batch.
+func (i *intcodeBatch) stream(ctx context.Context, inputs func(emit func([]int64) bool), handle func(intcodeBatchResult) bool) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var workers = i.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		index  int
+		inputs []int64
+	}
+
+	type done struct {
+		index int
+		res   intcodeBatchResult
+	}
+
+	var (
+		jobs    = make(chan job, workers)
+		results = make(chan done, workers)
+		wg      sync.WaitGroup
+	)
+
+	go func() {
+		defer close(jobs)
+
+		var index int
+		inputs(func(in []int64) bool {
+			select {
+			case <-runCtx.Done():
+				return false
+			case jobs <- job{index: index, inputs: in}:
+				index++
+				return true
+			}
+		})
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				res, err := i.runOne(runCtx, j.inputs)
+				results <- done{index: j.index, res: intcodeBatchResult{Inputs: j.inputs, Result: res, Err: err}}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		next    int
+		pending = map[int]intcodeBatchResult{}
+		stopped bool
+	)
+
+	for d := range results {
+		if stopped {
+			// Drain results of runs in flight
+			continue
+		}
+
+		pending[d.index] = d.res
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			delete(pending, next)
+			next++
+
+			if !handle(r) {
+				stopped = true
+				cancel()
+				break
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "Batch was cancelled")
+	}
+
+	return nil
+}
+
+// run executes the program for all input vectors and returns the results
+// in the same order
+func (i *intcodeBatch) run(ctx context.Context, inputs [][]int64) ([]intcodeBatchResult, error) {
+	var results = make([]intcodeBatchResult, 0, len(inputs))
+
+	err := i.stream(ctx, func(emit func([]int64) bool) {
+		for _, in := range inputs {
+			if !emit(in) {
+				return
+			}
+		}
+	}, func(r intcodeBatchResult) bool {
+		results = append(results, r)
+		return true
+	})
+
+	return results, err
+}
@@ -0,0 +1,109 @@
+package aoc2019
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIntcodeBatch(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	// Stores input * 2 into the data cell behind the code and outputs it
+	code, _ := ParseIntcode("3,9,1002,9,2,9,4,9,99,0")
+	image := cloneIntcode(code)
+
+	batch := newIntcodeBatch(code)
+	batch.Workers = 4
+	batch.KeepMemory = true
+
+	var inputs [][]int64
+	for i := int64(0); i < 100; i++ {
+		inputs = append(inputs, []int64{i})
+	}
+
+	results, err := batch.run(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("Batch failed: %s", err)
+	}
+
+	if len(results) != len(inputs) {
+		t.Fatalf("Unexpected number of results: exp=%d got=%d", len(inputs), len(results))
+	}
+
+	for i, r := range results {
+		if r.Err != nil || r.Result.Reason != IntcodeHaltExit {
+			t.Fatalf("Run %d failed: reason=%s err=%v", i, r.Result.Reason, r.Err)
+		}
+
+		if r.Inputs[0] != int64(i) || !reflect.DeepEqual(r.Result.Outputs, []int64{2 * int64(i)}) {
+			t.Errorf("Result %d out of order or wrong: inputs=%v outputs=%v", i, r.Inputs, r.Result.Outputs)
+		}
+
+		if r.Result.Memory[9] != 2*int64(i) {
+			t.Errorf("Memory of run %d not kept: %v", i, r.Result.Memory)
+		}
+	}
+
+	if !reflect.DeepEqual(batch.image, image) || !reflect.DeepEqual(code, image) {
+		t.Errorf("Program image was modified: %v", batch.image)
+	}
+}
+
+func TestIntcodeBatchStop(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	code, _ := ParseIntcode("3,9,1002,9,2,9,4,9,99,0")
+	batch := newIntcodeBatch(code)
+
+	var (
+		emitted int64
+		handled []int64
+	)
+
+	err := batch.stream(context.Background(), func(emit func([]int64) bool) {
+		for emit([]int64{emitted}) {
+			emitted++
+		}
+	}, func(r intcodeBatchResult) bool {
+		handled = append(handled, r.Result.Outputs[0])
+		return len(handled) < 10
+	})
+	if err != nil {
+		t.Fatalf("Batch failed: %s", err)
+	}
+
+	for i, v := range handled {
+		if v != 2*int64(i) {
+			t.Errorf("Unexpected result %d: %d", i, v)
+		}
+	}
+
+	if len(handled) != 10 {
+		t.Errorf("Batch did not stop: %d results handled", len(handled))
+	}
+}
+
+func TestIntcodeBatchReset(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	// Reads two values, the second one is only read by the first run
+	code, _ := ParseIntcode("3,7,1005,7,6,99,3,0,99")
+	batch := newIntcodeBatch(code)
+
+	m := batch.acquire()
+	m.Feed(1, 5)
+	if err := m.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("First run failed: %s", err)
+	}
+	batch.release(m)
+
+	m = batch.acquire()
+	if m.Steps() != 0 || m.pos != 0 || m.Exited() || len(m.pendingInput) != 0 {
+		t.Fatalf("Machine was not reset: steps=%d ip=%d exited=%v pending=%v", m.Steps(), m.pos, m.Exited(), m.pendingInput)
+	}
+
+	if !reflect.DeepEqual(m.Memory(), code) {
+		t.Errorf("Memory was not reset: %v", m.Memory())
+	}
+}
@@ -0,0 +1,177 @@
+package aoc2019
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BigIntcodeParams describes a single run of an Intcode program with
+// arbitrary precision memory cells
+type BigIntcodeParams struct {
+	// Intcode program to execute
+	Code []*big.Int
+	// Context to execute the program in
+	Context context.Context
+	// Callback to query on input directive
+	In func() (*big.Int, error)
+	// Callback to use for output directive
+	Out func(*big.Int) error
+}
+
+// ParseBigIntcode reads a comma separated Intcode program into arbitrary
+// precision memory cells
+func ParseBigIntcode(code string) ([]*big.Int, error) {
+	var out []*big.Int
+
+	for _, n := range strings.Split(code, ",") {
+		v, ok := new(big.Int).SetString(n, 10)
+		if !ok {
+			return nil, errors.Errorf("Invalid number %q", n)
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// NewBigIntcodeMachine creates a machine with IntcodeArithmeticBig at
+// the start of the given program, cells may exceed int64
+func NewBigIntcodeMachine(code []*big.Int) *IntcodeMachine {
+	var m = &IntcodeMachine{
+		Arithmetic: IntcodeArithmeticBig,
+		memory:     make([]int64, len(code)),
+	}
+
+	for addr, v := range code {
+		switch {
+		case v == nil:
+		case v.IsInt64():
+			m.memory[addr] = v.Int64()
+		default:
+			if m.bigCells == nil {
+				m.bigCells = map[int64]*big.Int{}
+			}
+			m.bigCells[int64(addr)] = new(big.Int).Set(v)
+		}
+	}
+
+	return m
+}
+
+// ExecuteBigIntcode runs the program with arbitrary precision until it
+// exits and returns the final state of its memory. Addresses, jump
+// targets and opcodes still need to fit into int64.
+func ExecuteBigIntcode(params BigIntcodeParams) ([]*big.Int, error) {
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
+
+	m := NewBigIntcodeMachine(params.Code)
+	m.BigIn = params.In
+	m.BigOut = params.Out
+
+	if m.BigIn == nil {
+		m.BigIn = func() (*big.Int, error) { return nil, errors.New("No input available") }
+	}
+
+	if err := m.Run(params.Context, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return m.BigMemory(), nil
+}
+
+// BigMemory returns a copy of the current memory of the machine
+// including values exceeding int64
+func (i *IntcodeMachine) BigMemory() []*big.Int {
+	var out = make([]*big.Int, len(i.memory))
+	for addr, v := range i.memory {
+		if b, ok := i.bigCells[int64(addr)]; ok {
+			out[addr] = new(big.Int).Set(b)
+			continue
+		}
+		out[addr] = big.NewInt(v)
+	}
+	return out
+}
+
+// checkInt64Memory reports an error if a memory cell holds a value
+// exceeding int64
+func (i *IntcodeMachine) checkInt64Memory() error {
+	var lowest int64 = -1
+	for addr := range i.bigCells {
+		if lowest < 0 || addr < lowest {
+			lowest = addr
+		}
+	}
+
+	if lowest < 0 {
+		return nil
+	}
+	return errors.Errorf("Memory cell %d exceeds int64: %s", lowest, i.bigCells[lowest])
+}
+
+// paramNonZero reports whether the first parameter of a jump is not
+// zero, in big arithmetic also for values exceeding int64
+func (i *IntcodeMachine) paramNonZero(op opCode, bigArith bool) bool {
+	if bigArith {
+		return i.bigParamValue(1, op).Sign() != 0
+	}
+	return i.getParamValue(1, op) != 0
+}
+
+func (i *IntcodeMachine) bigParamValue(param int64, op opCode) *big.Int {
+	if v, ok := i.bigCells[i.transformPos(param, op, false)]; ok {
+		return v
+	}
+	return big.NewInt(i.getParamValue(param, op))
+}
+
+func (i *IntcodeMachine) setBigParamValue(param int64, value *big.Int, op opCode) {
+	if value.IsInt64() {
+		i.setParamValue(param, value.Int64(), op)
+		return
+	}
+
+	var addr = i.transformPos(param, op, false)
+	if dev, _ := i.device(addr); dev != nil {
+		panic(intcodeAbort{errors.Errorf("Device write of %s to address %d at address %d exceeds int64", value, addr, i.pos)})
+	}
+
+	// Grows the memory as needed, the value is kept aside
+	i.setParamValue(param, 0, op)
+
+	if i.bigCells == nil {
+		i.bigCells = map[int64]*big.Int{}
+	}
+	i.bigCells[addr] = value
+}
+
+// bigBinaryOp executes the arithmetic and comparison opcodes
+// (p1 x p2 => p3) with arbitrary precision
+func (i *IntcodeMachine) bigBinaryOp(op opCode) {
+	var (
+		a, b = i.bigParamValue(1, op), i.bigParamValue(2, op)
+		res  = new(big.Int)
+	)
+
+	switch op.Type {
+	case opCodeTypeAddition:
+		res.Add(a, b)
+	case opCodeTypeMultiplication:
+		res.Mul(a, b)
+	case opCodeTypeLessThan:
+		if a.Cmp(b) < 0 {
+			res.SetInt64(1)
+		}
+	case opCodeTypeEquals:
+		if a.Cmp(b) == 0 {
+			res.SetInt64(1)
+		}
+	}
+
+	i.setBigParamValue(3, res, op)
+}
@@ -0,0 +1,193 @@
+package aoc2019
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestExecuteIntcodeCheckedOverflow(t *testing.T) {
+	for codeStr, expAddr := range map[string]string{
+		"1101,9223372036854775807,1,5,99,0":               "address 0",
+		"1,0,0,0,1102,4611686018427387904,2,9,99,0":       "address 4",
+		"1101,-9223372036854775807,-1,9,1001,9,-1,9,99,0": "address 4",
+	} {
+		code, _ := ParseIntcode(codeStr)
+
+		_, err := ExecuteIntcodeWithParams(IntcodeParams{
+			Code:       code,
+			Context:    context.Background(),
+			Arithmetic: IntcodeArithmeticChecked,
+		})
+		if err == nil {
+			t.Errorf("Program %q did not report overflow", codeStr)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), expAddr) {
+			t.Errorf("Program %q reported unexpected error: %s", codeStr, err)
+		}
+	}
+
+	// Large numbers within range must still work in checked mode
+	code, _ := ParseIntcode("1102,34915192,34915192,7,4,7,99,0")
+	var out = make(chan int64, 1)
+	if _, err := ExecuteIntcodeWithParams(IntcodeParams{
+		Code:       code,
+		Context:    context.Background(),
+		Out:        out,
+		Arithmetic: IntcodeArithmeticChecked,
+	}); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if r := <-out; r != 1219070632396864 {
+		t.Errorf("Execute yield unexpected result: exp=1219070632396864 got=%d", r)
+	}
+}
+
+func TestExecuteIntcodeBigArithmetic(t *testing.T) {
+	// 2^62 * 4 overflows int64 but is only compared to itself before
+	// the output, the final memory cannot be returned as int64
+	code, _ := ParseIntcode("1102,4611686018427387904,4,13,8,13,13,14,4,14,99,0,0,0,0")
+
+	var out = make(chan int64, 1)
+	if _, err := ExecuteIntcodeWithParams(IntcodeParams{
+		Code:       code,
+		Context:    context.Background(),
+		Out:        out,
+		Arithmetic: IntcodeArithmeticBig,
+	}); err == nil {
+		t.Errorf("Big memory cell was returned as int64 without error")
+	}
+
+	if r := <-out; r != 1 {
+		t.Errorf("Execute yield unexpected result: exp=1 got=%d", r)
+	}
+}
+
+func TestExecuteIntcodeBigLowestOverflow(t *testing.T) {
+	// Two cells exceed int64, the error must consistently name the lower one
+	code, _ := ParseIntcode("1102,4611686018427387904,4,14,1102,4611686018427387904,4,13,99,0,0,0,0,0,0")
+
+	for n := 0; n < 20; n++ {
+		_, err := ExecuteIntcodeWithParams(IntcodeParams{
+			Code:       append([]int64(nil), code...),
+			Arithmetic: IntcodeArithmeticBig,
+		})
+		if err == nil || !strings.Contains(err.Error(), "Memory cell 13 ") {
+			t.Fatalf("Unexpected error for big memory cells: %v", err)
+		}
+	}
+}
+
+func TestExecuteBigIntcodeJumpCondition(t *testing.T) {
+	// The jump condition at address 4 exceeds int64 and is not zero,
+	// the jump skips the output of 0
+	code, err := ParseBigIntcode("1002,12,10,12,1005,12,9,104,0,104,1,99,9999999999999999999")
+	if err != nil {
+		t.Fatalf("Parsing big Intcode failed: %s", err)
+	}
+
+	var outputs []*big.Int
+	if _, err := ExecuteBigIntcode(BigIntcodeParams{
+		Code: code,
+		Out: func(v *big.Int) error {
+			outputs = append(outputs, v)
+			return nil
+		},
+	}); err != nil {
+		t.Fatalf("Big Intcode execution failed: %s", err)
+	}
+
+	if len(outputs) != 1 || outputs[0].Int64() != 1 {
+		t.Errorf("Execute yield unexpected result: exp=[1] got=%v", outputs)
+	}
+}
+
+func TestExecuteBigIntcode(t *testing.T) {
+	code, err := ParseBigIntcode("1102,4611686018427387904,4611686018427387904,7,4,7,99,0")
+	if err != nil {
+		t.Fatalf("Parsing big Intcode failed: %s", err)
+	}
+
+	var outputs []*big.Int
+	mem, err := ExecuteBigIntcode(BigIntcodeParams{
+		Code: code,
+		Out: func(v *big.Int) error {
+			outputs = append(outputs, v)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Big Intcode execution failed: %s", err)
+	}
+
+	exp, _ := new(big.Int).SetString("21267647932558653966460912964485513216", 10)
+	if len(outputs) != 1 || outputs[0].Cmp(exp) != 0 {
+		t.Errorf("Execute yield unexpected result: exp=%s got=%v", exp, outputs)
+	}
+
+	if mem[7].Cmp(exp) != 0 {
+		t.Errorf("Memory contains unexpected result: exp=%s got=%s", exp, mem[7])
+	}
+}
+
+func TestIntcodeMachineBigArithmetic(t *testing.T) {
+	// Stores 2^62 * 4 into 100, reads an input and outputs 100
+	code, _ := ParseIntcode("1102,4611686018427387904,4,100,3,101,4,100,99")
+
+	m := NewIntcodeMachine(code)
+	m.Arithmetic = IntcodeArithmeticBig
+	m.Coverage = NewIntcodeCoverage()
+	m.MaxSteps = 10
+
+	if err := m.Run(context.Background(), nil, nil); err != ErrIntcodeInputExhausted {
+		t.Fatalf("Machine did not pause on exhausted input: %v", err)
+	}
+
+	var buf = new(bytes.Buffer)
+	if err := m.SaveState(buf); err != nil {
+		t.Fatalf("Unable to save state: %s", err)
+	}
+
+	m, err := LoadIntcodeMachine(buf)
+	if err != nil {
+		t.Fatalf("Unable to load state: %s", err)
+	}
+
+	var outputs []*big.Int
+	m.BigOut = func(v *big.Int) error {
+		outputs = append(outputs, v)
+		return nil
+	}
+	m.Feed(1)
+
+	if err := m.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Resumed machine failed: %s", err)
+	}
+
+	exp, _ := new(big.Int).SetString("18446744073709551616", 10)
+	if len(outputs) != 1 || outputs[0].Cmp(exp) != 0 {
+		t.Errorf("Unexpected outputs: exp=%s got=%v", exp, outputs)
+	}
+
+	if s := m.Steps(); s != 4 {
+		t.Errorf("Unexpected number of steps: exp=4 got=%d", s)
+	}
+
+	if v := m.BigMemory()[100]; v.Cmp(exp) != 0 {
+		t.Errorf("Unexpected memory cell: exp=%s got=%s", exp, v)
+	}
+
+	// Big values cannot be used as jump target
+	code, _ = ParseIntcode("1102,4611686018427387904,4,7,106,0,7,0")
+	if _, err := ExecuteIntcodeWithParams(IntcodeParams{
+		Code:       code,
+		Arithmetic: IntcodeArithmeticBig,
+	}); err == nil || !strings.Contains(err.Error(), "exceeds int64") {
+		t.Errorf("Big jump target did not fail: %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package aoc2019
+
+import "sort"
+
+// intcodeBlock is a sequence of instructions always executed from its
+// start to its end, only the last instruction might be a jump
+type intcodeBlock struct {
+	Start        int64
+	Instructions []intcodeInstruction
+	// Successors of the block, -1 if not existing: Jump is the static
+	// target of the final jump, Next the following block reached when
+	// the final jump is not taken or the block ends without a jump
+	Jump, Next int64
+	// Block ends with a jump to a target read from memory
+	Indirect bool
+	// Block ends with the exit directive
+	Exit bool
+}
+
+// End returns the address behind the last instruction of the block
+func (i intcodeBlock) End() int64 {
+	last := i.Instructions[len(i.Instructions)-1]
+	return last.Addr + last.Len()
+}
+
+// intcodeCFG is the control flow graph of all instructions reachable
+// from address 0. Targets of indirect jumps are unknown: if the program
+// contains such jumps every instruction behind a jump whose address is
+// used as immediate value is assumed to be a possible target, which
+// holds for return addresses pushed by function calls.
+type intcodeCFG struct {
+	Blocks []*intcodeBlock
+	// Program contains jumps to targets read from memory
+	Indirect bool
+	// Reachable addresses not containing a valid instruction, ordered
+	// by address. Usually these are modified before being executed.
+	Invalid []int64
+	// Instructions starting inside the previous instruction, ordered by
+	// address
+	Overlaps []int64
+
+	code    map[int64]bool
+	returns map[int64]bool
+	starts  map[int64]*intcodeBlock
+}
+
+// jumpBehavior returns whether a jump instruction can be taken and
+// whether it can be skipped based on an immediate condition
+func jumpBehavior(inst intcodeInstruction) (mayJump, mayContinue bool) {
+	if inst.Op.GetFlag(1) != opCodeFlagImmediate {
+		return true, true
+	}
+
+	var taken = inst.Params[0] != 0
+	if inst.Op.Type == opCodeTypeJumpIfFalse {
+		taken = !taken
+	}
+	return taken, !taken
+}
+
+func isIntcodeJump(t opCodeType) bool {
+	return t == opCodeTypeJumpIfTrue || t == opCodeTypeJumpIfFalse
+}
+
+// buildIntcodeCFG decodes all reachable instructions of the program and
+// splits them into basic blocks ordered by address. Blocks continuing
+// into an invalid instruction have their Next set to its address.
+func buildIntcodeCFG(code []int64) *intcodeCFG {
+	var (
+		insts    = map[int64]intcodeInstruction{}
+		invalid  = map[int64]bool{}
+		leaders  = map[int64]bool{0: true}
+		returns  = map[int64]bool{}
+		indirect bool
+		queue    = []int64{0}
+	)
+
+	explore := func() {
+		for len(queue) > 0 {
+			addr := queue[0]
+			queue = queue[1:]
+
+			if _, ok := insts[addr]; ok || invalid[addr] {
+				continue
+			}
+
+			inst, ok := decodeIntcodeInstruction(code, addr)
+			if !ok {
+				invalid[addr] = true
+				leaders[addr] = true
+				continue
+			}
+			insts[addr] = inst
+
+			switch {
+			case inst.Op.Type == opCodeTypeExit:
+				// No successor
+
+			case isIntcodeJump(inst.Op.Type):
+				mayJump, mayContinue := jumpBehavior(inst)
+				if mayJump {
+					if inst.Op.GetFlag(2) == opCodeFlagImmediate {
+						leaders[inst.Params[1]] = true
+						queue = append(queue, inst.Params[1])
+					} else {
+						indirect = true
+					}
+				}
+				if mayContinue {
+					leaders[addr+inst.Len()] = true
+					queue = append(queue, addr+inst.Len())
+				}
+
+			default:
+				queue = append(queue, addr+inst.Len())
+			}
+		}
+	}
+
+	explore()
+
+	if indirect {
+		// Instructions behind jumps whose address is used as value are
+		// possible return addresses
+		for {
+			var (
+				added  bool
+				pushed = map[int64]bool{}
+			)
+
+			for _, inst := range insts {
+				for n, p := range inst.Params {
+					param := int64(n) + 1
+					if inst.Op.GetFlag(param) == opCodeFlagImmediate && !(isIntcodeJump(inst.Op.Type) && param == 2) {
+						pushed[p] = true
+					}
+				}
+			}
+
+			for addr, inst := range insts {
+				next := addr + inst.Len()
+				if returns[next] || !isIntcodeJump(inst.Op.Type) || !pushed[next] {
+					continue
+				}
+				if _, ok := decodeIntcodeInstruction(code, next); !ok {
+					continue
+				}
+				returns[next] = true
+				leaders[next] = true
+				if _, ok := insts[next]; !ok {
+					queue = append(queue, next)
+					added = true
+				}
+			}
+
+			if !added {
+				break
+			}
+			explore()
+		}
+	}
+
+	var addrs []int64
+	for addr := range insts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	var cfg = &intcodeCFG{
+		Indirect: indirect,
+		code:     map[int64]bool{},
+		returns:  returns,
+		starts:   map[int64]*intcodeBlock{},
+	}
+
+	for addr := range invalid {
+		cfg.Invalid = append(cfg.Invalid, addr)
+	}
+	sort.Slice(cfg.Invalid, func(i, j int) bool { return cfg.Invalid[i] < cfg.Invalid[j] })
+
+	for i, addr := range addrs {
+		inst := insts[addr]
+		if i+1 < len(addrs) && addrs[i+1] < addr+inst.Len() {
+			cfg.Overlaps = append(cfg.Overlaps, addrs[i+1])
+		}
+		for a := addr; a < addr+inst.Len(); a++ {
+			cfg.code[a] = true
+		}
+	}
+
+	var block *intcodeBlock
+	for _, addr := range addrs {
+		inst := insts[addr]
+
+		if block != nil && (leaders[addr] || block.End() != addr) {
+			// Block continues into the next one or an invalid instruction
+			block.Next = block.End()
+			block = nil
+		}
+
+		if block == nil {
+			block = &intcodeBlock{Start: addr, Jump: -1, Next: -1}
+			cfg.Blocks = append(cfg.Blocks, block)
+			cfg.starts[addr] = block
+		}
+		block.Instructions = append(block.Instructions, inst)
+
+		switch {
+		case inst.Op.Type == opCodeTypeExit:
+			block.Exit = true
+
+		case isIntcodeJump(inst.Op.Type):
+			mayJump, mayContinue := jumpBehavior(inst)
+			if mayJump {
+				if inst.Op.GetFlag(2) == opCodeFlagImmediate {
+					block.Jump = inst.Params[1]
+				} else {
+					block.Indirect = true
+				}
+			}
+			if mayContinue {
+				block.Next = addr + inst.Len()
+			}
+
+		default:
+			continue
+		}
+
+		block = nil
+	}
+
+	if block != nil {
+		// Last instruction continues into an invalid one
+		block.Next = block.End()
+	}
+
+	return cfg
+}
+
+// block returns the block starting at the address
+func (i *intcodeCFG) block(addr int64) *intcodeBlock { return i.starts[addr] }
+
+// isCode reports whether the memory cell belongs to a reachable
+// instruction
+func (i *intcodeCFG) isCode(addr int64) bool { return i.code[addr] }
+
+// isReturn reports whether the address is assumed to be a target of
+// indirect jumps
+func (i *intcodeCFG) isReturn(addr int64) bool { return i.returns[addr] }
+
+// predecessors returns the starts of all blocks which might continue
+// with the block starting at the address, indirect jumps are not
+// considered
+func (i *intcodeCFG) predecessors(addr int64) []int64 {
+	var out []int64
+	for _, b := range i.Blocks {
+		if b.Jump == addr || b.Next == addr {
+			out = append(out, b.Start)
+		}
+	}
+	return out
+}
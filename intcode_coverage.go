@@ -0,0 +1,221 @@
+package aoc2019
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// IntcodeCoverage records which addresses were executed as instructions
+// and which directions the conditional jumps took. The same coverage
+// can be attached to multiple machines running the same program to
+// collect the combined coverage of all runs, also concurrently.
+type IntcodeCoverage struct {
+	mu       sync.Mutex
+	executed map[int64]int64
+	branches map[int64]*intcodeBranchCoverage
+}
+
+type intcodeBranchCoverage struct {
+	Taken, NotTaken int64
+}
+
+type intcodeCoverageLine struct {
+	Addr        int64
+	Count       int64
+	Instruction string
+	// One of "data", "covered", "partial" or "uncovered"
+	Class  string
+	Branch string
+}
+
+// IntcodeCoverageSummary holds the numbers of covered instructions and
+// branch directions
+type IntcodeCoverageSummary struct {
+	Instructions, InstructionsCovered int
+	Branches, BranchesCovered         int
+}
+
+func (i IntcodeCoverageSummary) String() string {
+	percent := func(a, b int) float64 {
+		if b == 0 {
+			return 100
+		}
+		return float64(a) / float64(b) * 100
+	}
+
+	return fmt.Sprintf("instructions: %d/%d (%.1f%%), branch directions: %d/%d (%.1f%%)",
+		i.InstructionsCovered, i.Instructions, percent(i.InstructionsCovered, i.Instructions),
+		i.BranchesCovered, i.Branches, percent(i.BranchesCovered, i.Branches))
+}
+
+// NewIntcodeCoverage creates an empty coverage to attach to a machine
+func NewIntcodeCoverage() *IntcodeCoverage {
+	return &IntcodeCoverage{
+		executed: map[int64]int64{},
+		branches: map[int64]*intcodeBranchCoverage{},
+	}
+}
+
+func (i *IntcodeCoverage) branch(addr int64, taken bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	b, ok := i.branches[addr]
+	if !ok {
+		b = &intcodeBranchCoverage{}
+		i.branches[addr] = b
+	}
+
+	if taken {
+		b.Taken++
+	} else {
+		b.NotTaken++
+	}
+}
+
+func (i *IntcodeCoverage) instruction(addr int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.executed[addr]++
+}
+
+func (i *IntcodeCoverage) lines(code []int64) ([]intcodeCoverageLine, IntcodeCoverageSummary) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var (
+		out     []intcodeCoverageLine
+		summary IntcodeCoverageSummary
+	)
+
+	for _, inst := range disassembleIntcode(code, func(addr int64) bool { return i.executed[addr] > 0 }) {
+		var line = intcodeCoverageLine{
+			Addr:        inst.Addr,
+			Count:       i.executed[inst.Addr],
+			Instruction: inst.String(),
+			Class:       "uncovered",
+		}
+
+		if inst.Data && line.Count > 0 {
+			// Self-modifying program changed the cell before executing it
+			line.Instruction += " (modified before execution)"
+		}
+
+		if inst.Data && line.Count == 0 {
+			line.Class = "data"
+			out = append(out, line)
+			continue
+		}
+
+		summary.Instructions++
+		if line.Count > 0 {
+			summary.InstructionsCovered++
+			line.Class = "covered"
+		}
+
+		if !inst.Data && (inst.Op.Type == opCodeTypeJumpIfTrue || inst.Op.Type == opCodeTypeJumpIfFalse) {
+			var b = i.branches[inst.Addr]
+			if b == nil {
+				b = &intcodeBranchCoverage{}
+			}
+
+			summary.Branches += 2
+			for _, n := range []int64{b.Taken, b.NotTaken} {
+				if n > 0 {
+					summary.BranchesCovered++
+				}
+			}
+
+			line.Branch = fmt.Sprintf("taken %d, not taken %d", b.Taken, b.NotTaken)
+			if line.Count > 0 && (b.Taken == 0 || b.NotTaken == 0) {
+				line.Class = "partial"
+			}
+		}
+
+		out = append(out, line)
+	}
+
+	return out, summary
+}
+
+// Summary returns the number of covered instructions and branch
+// directions of the program
+func (i *IntcodeCoverage) Summary(code []int64) IntcodeCoverageSummary {
+	_, summary := i.lines(code)
+	return summary
+}
+
+// WriteText renders an annotated disassembly of the program: execution
+// count of every instruction ("-" if never executed) and the branch
+// directions taken by conditional jumps. The program should be passed
+// in its initial state as memory might have been modified during the
+// execution.
+func (i *IntcodeCoverage) WriteText(w io.Writer, code []int64) error {
+	lines, summary := i.lines(code)
+
+	if _, err := fmt.Fprintf(w, "# %s\n", summary); err != nil {
+		return errors.Wrap(err, "Unable to write coverage")
+	}
+
+	for _, l := range lines {
+		var count = "-"
+		switch {
+		case l.Class == "data":
+			count = ""
+		case l.Count > 0:
+			count = fmt.Sprintf("%d", l.Count)
+		}
+
+		var line = fmt.Sprintf("%6d %8s  %s", l.Addr, count, l.Instruction)
+		if l.Branch != "" {
+			line = fmt.Sprintf("%-50s # %s", line, l.Branch)
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return errors.Wrap(err, "Unable to write coverage")
+		}
+	}
+
+	return nil
+}
+
+// WriteHTML renders the annotated disassembly as HTML page, colored
+// like the output of `go tool cover -html`
+func (i *IntcodeCoverage) WriteHTML(w io.Writer, code []int64) error {
+	lines, summary := i.lines(code)
+
+	return errors.Wrap(intcodeCoverageTemplate.Execute(w, map[string]interface{}{
+		"Lines":   lines,
+		"Summary": summary.String(),
+	}), "Unable to render coverage")
+}
+
+var intcodeCoverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Intcode coverage</title>
+<style>
+body { background: black; color: rgb(80, 80, 80); font-family: monospace; }
+.covered { color: rgb(44, 212, 149); }
+.partial { color: rgb(224, 196, 80); }
+.uncovered { color: rgb(192, 0, 0); }
+.data { color: rgb(128, 128, 128); }
+td { padding: 0 1em 0 0; white-space: pre; }
+</style>
+</head>
+<body>
+<p>{{ .Summary }}</p>
+<table>
+{{- range .Lines }}
+<tr class="{{ .Class }}"><td>{{ .Addr }}</td><td>{{ if ne .Class "data" }}{{ .Count }}{{ end }}</td><td>{{ .Instruction }}</td><td>{{ .Branch }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,150 @@
+package aoc2019
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Outputs 1 for a positive input, 0 otherwise
+const intcodeCoverageTestProgram = "3,15,1007,15,1,16,1005,16,12,104,1,99,104,0,99,0,0"
+
+func TestIntcodeCoverage(t *testing.T) {
+	code, _ := ParseIntcode(intcodeCoverageTestProgram)
+
+	cov := NewIntcodeCoverage()
+	m := NewIntcodeMachine(cloneIntcode(code))
+	m.Coverage = cov
+	m.Feed(5)
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if s := cov.Summary(code); s.Instructions != 7 || s.InstructionsCovered != 5 || s.BranchesCovered != 1 {
+		t.Errorf("Unexpected coverage: %s", s)
+	}
+
+	var buf = new(bytes.Buffer)
+	if err := cov.WriteText(buf, code); err != nil {
+		t.Fatalf("Unable to write coverage: %s", err)
+	}
+
+	for _, exp := range []string{
+		"     6        1  jnz [16], 12",
+		"# taken 0, not taken 1",
+		"    12        -  out 0",
+		"    15           data 0",
+	} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Errorf("Text coverage is missing %q:\n%s", exp, buf.String())
+		}
+	}
+
+	// Second run on the same coverage takes the other direction
+	m = NewIntcodeMachine(cloneIntcode(code))
+	m.Coverage = cov
+	m.Feed(0)
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if s := cov.Summary(code); s.InstructionsCovered != s.Instructions || s.BranchesCovered != s.Branches {
+		t.Errorf("Combined runs did not cover the program: %s", s)
+	}
+
+	buf.Reset()
+	if err := cov.WriteHTML(buf, code); err != nil {
+		t.Fatalf("Unable to write coverage: %s", err)
+	}
+
+	if !strings.Contains(buf.String(), `<tr class="covered"><td>6</td><td>2</td><td>jnz [16], 12</td>`) {
+		t.Errorf("HTML coverage is missing the branch:\n%s", buf.String())
+	}
+}
+
+func TestIntcodeCoverageResume(t *testing.T) {
+	code, _ := ParseIntcode(intcodeCoverageTestProgram)
+
+	cov := NewIntcodeCoverage()
+	m := NewIntcodeMachine(cloneIntcode(code))
+	m.Coverage = cov
+	if err := m.Run(context.Background(), nil, nil); err != ErrIntcodeInputExhausted {
+		t.Fatalf("Expected input exhaustion, got: %v", err)
+	}
+
+	m.Feed(5)
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	// The interrupted input instruction is only counted once completed
+	if lines, _ := cov.lines(code); lines[0].Count != 1 {
+		t.Errorf("Unexpected count for input instruction: %d", lines[0].Count)
+	}
+}
+
+func TestIntcodeCoverageConcurrent(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	code, _ := ParseIntcode(intcodeCoverageTestProgram)
+
+	var (
+		cov = NewIntcodeCoverage()
+		wg  sync.WaitGroup
+	)
+
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(in int64) {
+			defer wg.Done()
+
+			m := NewIntcodeMachine(cloneIntcode(code))
+			m.Coverage = cov
+			m.Feed(in)
+			if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+				t.Errorf("Intcode execution failed: %s", err)
+			}
+		}(int64(n % 2))
+	}
+	wg.Wait()
+
+	if lines, s := cov.lines(code); lines[0].Count != 8 || s.BranchesCovered != s.Branches {
+		t.Errorf("Unexpected combined coverage: count=%d %s", lines[0].Count, s)
+	}
+}
+
+func TestDay05Coverage(t *testing.T) {
+	raw, err := ioutil.ReadFile("day05_input.txt")
+	if err != nil {
+		t.Fatalf("Unable to read input: %s", err)
+	}
+
+	code, err := ParseIntcode(strings.TrimSpace(string(raw)))
+	if err != nil {
+		t.Fatalf("Parsing Intcode failed: %s", err)
+	}
+
+	var (
+		cov       = NewIntcodeCoverage()
+		summaries []IntcodeCoverageSummary
+	)
+
+	for _, system := range []int64{1, 5} {
+		m := NewIntcodeMachine(cloneIntcode(code))
+		m.Coverage = cov
+		m.Feed(system)
+		if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+			t.Fatalf("Intcode execution failed: %s", err)
+		}
+
+		summaries = append(summaries, cov.Summary(code))
+		t.Logf("Coverage after system %d: %s", system, summaries[len(summaries)-1])
+	}
+
+	if summaries[1].InstructionsCovered <= summaries[0].InstructionsCovered {
+		t.Errorf("Running system 5 did not cover additional instructions")
+	}
+}
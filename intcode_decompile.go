@@ -0,0 +1,664 @@
+package aoc2019
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The decompiler turns the control flow graph of a program into
+// C-like pseudocode. Functions are detected by the calling convention
+// of the known puzzle programs: the caller stores the return address
+// into [rb+0] and the arguments into [rb+1...], then jumps to the
+// function which allocates its frame using "arb" and returns through
+// "jz 0, [rb+0]" after restoring the relative base.
+//
+// Variables are named by their location: gN for absolute cells (and
+// relative cells in main as the relative base starts at 0 there),
+// argN / localN / outN for the arguments, the local variables and the
+// slots of outgoing calls (i.e. the results) inside functions and
+// mem[...] for cells containing code or unknown relative addresses.
+
+type intcodeCall struct {
+	Target, Return int64
+	// Index of the instruction storing the return address
+	Store int
+}
+
+type intcodeLoop struct {
+	header, follow int64
+	body           map[int64]bool
+}
+
+type intcodeLine struct {
+	depth int
+	text  string
+	// Start of the block beginning at this line, -1 for statements
+	label int64
+}
+
+type intcodeDecompiler struct {
+	cfg   *intcodeCFG
+	calls map[int64]intcodeCall
+	// Number of arguments passed to the function starting at the address
+	params map[int64]int64
+	// Cells read before being written in any block
+	liveIn map[int64]bool
+	// Address of the instruction occupying a code cell, invalid
+	// instructions occupy their address
+	cellInst map[int64]int64
+	// Code cells written by instructions, mapped to the writing
+	// instruction
+	patched map[int64]int64
+	// Blocks contained in a decompiled function
+	covered map[int64]bool
+	// Program contains indirect jumps not being returns
+	dynamic bool
+}
+
+type intcodeFunc struct {
+	d     *intcodeDecompiler
+	entry int64
+	frame int64
+	// Code reached through indirect jumps only, the relative base is
+	// unknown
+	fragment bool
+
+	nodes []int64
+	succ  map[int64][]int64
+	preds map[int64][]int64
+	// Relative base at the block start relative to the one at the entry,
+	// missing if not known
+	delta map[int64]int64
+
+	idom        map[int64]int64
+	ipdom       map[int64]int64
+	ipdomStrict map[int64]int64
+	loops       map[int64]*intcodeLoop
+
+	done    map[int64]bool
+	labels  map[int64]bool
+	pending []int64
+	lines   []intcodeLine
+	locals  map[int64]bool
+}
+
+// DecompileIntcode converts the program into structured pseudocode.
+// Loops and conditions are reconstructed from the jumps, functions
+// from relative base adjustments around calls. Self-modifying code is
+// decompiled as found in the program image and annotated.
+func DecompileIntcode(code []int64) string {
+	d := &intcodeDecompiler{
+		cfg:      buildIntcodeCFG(code),
+		calls:    map[int64]intcodeCall{},
+		params:   map[int64]int64{},
+		liveIn:   map[int64]bool{},
+		cellInst: map[int64]int64{},
+		patched:  map[int64]int64{},
+		covered:  map[int64]bool{},
+	}
+	d.analyze()
+
+	var (
+		entries []int64
+		seen    = map[int64]bool{0: true}
+	)
+	for _, c := range d.calls {
+		if !seen[c.Target] {
+			seen[c.Target] = true
+			entries = append(entries, c.Target)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+
+	var out []string
+	for _, e := range append([]int64{0}, entries...) {
+		out = append(out, d.function(e, false).render())
+	}
+
+	for _, b := range d.cfg.Blocks {
+		if d.dynamic && !d.covered[b.Start] {
+			out = append(out, d.function(b.Start, true).render())
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// analyze collects calls, their arguments and the cells used by the
+// program
+func (d *intcodeDecompiler) analyze() {
+	for _, b := range d.cfg.Blocks {
+		for _, inst := range b.Instructions {
+			for a := inst.Addr; a < inst.Addr+inst.Len(); a++ {
+				d.cellInst[a] = inst.Addr
+			}
+		}
+	}
+	for _, addr := range d.cfg.Invalid {
+		d.cellInst[addr] = addr
+	}
+
+	for _, b := range d.cfg.Blocks {
+		var (
+			written = map[int64]bool{}
+			last    = len(b.Instructions) - 1
+		)
+
+		for i, inst := range b.Instructions {
+			var info = intcodeOps[inst.Op.Type]
+
+			for n, p := range inst.Params {
+				param := int64(n) + 1
+				if inst.Op.GetFlag(param) != opCodeFlagPosition {
+					continue
+				}
+
+				if param == info.Write {
+					written[p] = true
+					if d.isCode(p) {
+						d.patched[p] = inst.Addr
+					}
+					continue
+				}
+
+				if i == last && param == 1 && isIntcodeJump(inst.Op.Type) && i > 0 && d.writes(b.Instructions[i-1], p) {
+					// Condition computed right before the jump
+					continue
+				}
+
+				if !written[p] {
+					d.liveIn[p] = true
+				}
+			}
+		}
+
+		lastInst := b.Instructions[last]
+		if !isIntcodeJump(lastInst.Op.Type) || b.Jump < 0 || b.Next >= 0 || d.cfg.block(b.End()) == nil {
+			continue
+		}
+
+		// Unconditional jump after storing the address behind the block
+		// into [rb+0] is a call
+		deltas, end, _ := intcodeBlockDeltas(b)
+		for i, inst := range b.Instructions[:last] {
+			if v, ok := intcodeConstantStore(inst); ok && v == b.End() && inst.Op.GetFlag(3) == opCodeFlagRelative && deltas[i]+inst.Params[2] == end {
+				d.calls[b.Start] = intcodeCall{Target: b.Jump, Return: b.End(), Store: i}
+			}
+		}
+
+		if _, ok := d.calls[b.Start]; !ok {
+			continue
+		}
+		for i, inst := range b.Instructions[:last] {
+			if intcodeOps[inst.Op.Type].Write == 3 && inst.Op.GetFlag(3) == opCodeFlagRelative {
+				if j := deltas[i] + inst.Params[2] - end; j > d.params[b.Jump] {
+					d.params[b.Jump] = j
+				}
+			}
+		}
+	}
+}
+
+// isCode reports whether the cell belongs to a reachable instruction
+func (d *intcodeDecompiler) isCode(addr int64) bool {
+	_, ok := d.cellInst[addr]
+	return ok
+}
+
+// writes reports whether the instruction writes the absolute cell
+func (d *intcodeDecompiler) writes(inst intcodeInstruction, cell int64) bool {
+	w := intcodeOps[inst.Op.Type].Write
+	return w > 0 && inst.Op.GetFlag(w) == opCodeFlagPosition && inst.Params[w-1] == cell
+}
+
+// inlineCondition reports whether the condition of the jump ending the
+// block can be replaced by the expression computed right before it
+func (d *intcodeDecompiler) inlineCondition(b *intcodeBlock) bool {
+	n := len(b.Instructions)
+	if n < 2 {
+		return false
+	}
+
+	jump := b.Instructions[n-1]
+	return isIntcodeJump(jump.Op.Type) && jump.Op.GetFlag(1) == opCodeFlagPosition &&
+		d.writes(b.Instructions[n-2], jump.Params[0]) && !d.liveIn[jump.Params[0]]
+}
+
+// successors returns the blocks following the block within the same
+// function, calls continue at their return address
+func (d *intcodeDecompiler) successors(addr int64) []int64 {
+	b := d.cfg.block(addr)
+	if b == nil {
+		return nil
+	}
+
+	if c, ok := d.calls[addr]; ok {
+		return []int64{c.Return}
+	}
+
+	var out []int64
+	if b.Jump >= 0 {
+		out = append(out, b.Jump)
+	}
+	if b.Next >= 0 && b.Next != b.Jump {
+		out = append(out, b.Next)
+	}
+	return out
+}
+
+// intcodeBlockDeltas returns the changes of the relative base at every
+// instruction and at the end of the block relative to its start, known
+// is false if the base is changed by a value read from memory
+func intcodeBlockDeltas(b *intcodeBlock) (deltas []int64, end int64, known bool) {
+	known = true
+	for _, inst := range b.Instructions {
+		deltas = append(deltas, end)
+		if inst.Op.Type != opCodeTypeAdjRelBase {
+			continue
+		}
+		if inst.Op.GetFlag(1) != opCodeFlagImmediate {
+			known = false
+			continue
+		}
+		end += inst.Params[0]
+	}
+	return deltas, end, known
+}
+
+// intcodeConstantStore returns the value stored by an addition or
+// multiplication of two immediate values
+func intcodeConstantStore(inst intcodeInstruction) (int64, bool) {
+	if inst.Op.GetFlag(1) != opCodeFlagImmediate || inst.Op.GetFlag(2) != opCodeFlagImmediate {
+		return 0, false
+	}
+
+	switch inst.Op.Type {
+	case opCodeTypeAddition:
+		return inst.Params[0] + inst.Params[1], true
+	case opCodeTypeMultiplication:
+		return inst.Params[0] * inst.Params[1], true
+	}
+	return 0, false
+}
+
+// function collects the blocks of the function starting at the entry
+// and reconstructs its structure
+func (d *intcodeDecompiler) function(entry int64, fragment bool) *intcodeFunc {
+	f := &intcodeFunc{
+		d:        d,
+		entry:    entry,
+		fragment: fragment,
+		succ:     map[int64][]int64{},
+		preds:    map[int64][]int64{},
+		delta:    map[int64]int64{entry: 0},
+		loops:    map[int64]*intcodeLoop{},
+		done:     map[int64]bool{},
+		labels:   map[int64]bool{},
+		locals:   map[int64]bool{},
+	}
+
+	if b := d.cfg.block(entry); entry != 0 && b != nil {
+		if first := b.Instructions[0]; first.Op.Type == opCodeTypeAdjRelBase && first.Op.GetFlag(1) == opCodeFlagImmediate && first.Params[0] > 0 {
+			f.frame = first.Params[0]
+		}
+	}
+
+	var (
+		seen    = map[int64]bool{entry: true}
+		unknown = map[int64]bool{entry: fragment}
+		queue   = []int64{entry}
+	)
+	f.nodes = []int64{entry}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		var (
+			end   = f.delta[n]
+			known = !unknown[n]
+		)
+		if b := d.cfg.block(n); b != nil {
+			_, bEnd, bKnown := intcodeBlockDeltas(b)
+			end, known = end+bEnd, known && bKnown
+		}
+
+		for _, s := range d.successors(n) {
+			if !seen[s] {
+				seen[s] = true
+				f.nodes = append(f.nodes, s)
+			}
+
+			// Propagate the relative base, conflicting bases are unknown
+			prev, ok := f.delta[s]
+			switch {
+			case unknown[s]:
+				continue
+			case !known || (ok && prev != end):
+				unknown[s] = true
+				delete(f.delta, s)
+			case !ok:
+				f.delta[s] = end
+			default:
+				continue
+			}
+			queue = append(queue, s)
+		}
+	}
+
+	for _, n := range f.nodes {
+		d.covered[n] = true
+		f.succ[n] = d.successors(n)
+		for _, s := range f.succ[n] {
+			f.preds[s] = append(f.preds[s], n)
+		}
+	}
+
+	f.analyze()
+	// Placeholder for the declaration of the local variables
+	f.lines = append(f.lines, intcodeLine{label: -1})
+	f.seq(entry, -1, nil, 1, false)
+	for len(f.pending) > 0 {
+		n := f.pending[0]
+		f.pending = f.pending[1:]
+		if !f.done[n] {
+			f.seq(n, -1, nil, 1, false)
+		}
+	}
+
+	return f
+}
+
+// analyze computes dominators, loops and post-dominators used to
+// structure the function
+func (f *intcodeFunc) analyze() {
+	f.idom = intcodeDominators(f.entry, func(n int64) []int64 { return f.succ[n] })
+
+	var backEdges = map[[2]int64]bool{}
+	for _, n := range f.nodes {
+		for _, s := range f.succ[n] {
+			if f.dominates(s, n) {
+				backEdges[[2]int64{n, s}] = true
+				f.loop(s).addBody(n, f.preds)
+			}
+		}
+	}
+
+	// Post-dominators on the graph without back edges, the virtual exit
+	// -1 follows all blocks without successors. Halting paths are
+	// ignored first to not let error exits prevent joining branches.
+	postdom := func(halts bool) map[int64]int64 {
+		var exits []int64
+		acyclic := map[int64][]int64{}
+		for _, n := range f.nodes {
+			for _, s := range f.succ[n] {
+				if !backEdges[[2]int64{n, s}] {
+					acyclic[n] = append(acyclic[n], s)
+				}
+			}
+
+			b := f.d.cfg.block(n)
+			if len(acyclic[n]) == 0 && (halts || (b != nil && !b.Exit)) {
+				exits = append(exits, n)
+			}
+		}
+
+		reverse := map[int64][]int64{-1: exits}
+		for n, succ := range acyclic {
+			for _, s := range succ {
+				reverse[s] = append(reverse[s], n)
+			}
+		}
+		return intcodeDominators(-1, func(n int64) []int64 { return reverse[n] })
+	}
+	f.ipdom, f.ipdomStrict = postdom(false), postdom(true)
+
+	for h, l := range f.loops {
+		var exits []int64
+		for n := range l.body {
+			for _, s := range f.succ[n] {
+				if !l.body[s] {
+					exits = append(exits, s)
+				}
+			}
+		}
+		sort.Slice(exits, func(i, j int) bool { return exits[i] < exits[j] })
+
+		l.follow = -1
+		if j := f.join(h); j >= 0 && !l.body[j] {
+			l.follow = j
+		} else if len(exits) > 0 {
+			l.follow = exits[0]
+		}
+	}
+}
+
+// intcodeDominators returns the immediate dominators of all nodes
+// reachable from the entry using the algorithm by Cooper, Harvey and
+// Kennedy. The entry dominates itself.
+func intcodeDominators(entry int64, succ func(int64) []int64) map[int64]int64 {
+	var (
+		order []int64
+		index = map[int64]int{}
+		preds = map[int64][]int64{}
+		seen  = map[int64]bool{}
+		visit func(int64)
+	)
+
+	visit = func(n int64) {
+		seen[n] = true
+		for _, s := range succ(n) {
+			preds[s] = append(preds[s], n)
+			if !seen[s] {
+				visit(s)
+			}
+		}
+		index[n] = len(order)
+		order = append(order, n)
+	}
+	visit(entry)
+
+	var (
+		idom      = map[int64]int64{entry: entry}
+		intersect = func(a, b int64) int64 {
+			for a != b {
+				for index[a] < index[b] {
+					a = idom[a]
+				}
+				for index[b] < index[a] {
+					b = idom[b]
+				}
+			}
+			return a
+		}
+	)
+
+	for changed := true; changed; {
+		changed = false
+		// Reverse postorder, the entry is the last node
+		for i := len(order) - 2; i >= 0; i-- {
+			var (
+				n     = order[i]
+				dom   int64
+				found bool
+			)
+
+			for _, p := range preds[n] {
+				if _, ok := idom[p]; !ok {
+					continue
+				}
+				if !found {
+					dom, found = p, true
+					continue
+				}
+				dom = intersect(p, dom)
+			}
+
+			if cur, ok := idom[n]; found && (!ok || cur != dom) {
+				idom[n] = dom
+				changed = true
+			}
+		}
+	}
+
+	return idom
+}
+
+// dominates reports whether every path from the entry to b passes a
+func (f *intcodeFunc) dominates(a, b int64) bool {
+	for {
+		if a == b {
+			return true
+		}
+		next, ok := f.idom[b]
+		if !ok || next == b {
+			return false
+		}
+		b = next
+	}
+}
+
+func (f *intcodeFunc) loop(header int64) *intcodeLoop {
+	if l, ok := f.loops[header]; ok {
+		return l
+	}
+	l := &intcodeLoop{header: header, body: map[int64]bool{header: true}}
+	f.loops[header] = l
+	return l
+}
+
+// addBody adds all nodes reaching the source of a back edge without
+// passing the header to the loop
+func (l *intcodeLoop) addBody(source int64, preds map[int64][]int64) {
+	stack := []int64{source}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if l.body[n] {
+			continue
+		}
+		l.body[n] = true
+		stack = append(stack, preds[n]...)
+	}
+}
+
+// join returns the node where both branches of the conditional jump at
+// the end of the block meet again, -1 if they do not
+func (f *intcodeFunc) join(n int64) int64 {
+	if j, ok := f.ipdom[n]; ok && j >= 0 {
+		return j
+	}
+	if j, ok := f.ipdomStrict[n]; ok {
+		return j
+	}
+	return -1
+}
+
+func (f *intcodeFunc) line(depth int, format string, args ...interface{}) {
+	f.lines = append(f.lines, intcodeLine{depth: depth, text: fmt.Sprintf(format, args...), label: -1})
+}
+
+func (f *intcodeFunc) jump(depth int, n int64) {
+	f.line(depth, "goto L%d", n)
+	f.labels[n] = true
+	if !f.done[n] {
+		f.pending = append(f.pending, n)
+	}
+}
+
+// seq emits the blocks starting at n until the stop node is reached or
+// the control flow leaves the current loop. enter is set when n is the
+// header of the loop being entered.
+func (f *intcodeFunc) seq(n, stop int64, lp *intcodeLoop, depth int, enter bool) {
+	for first := enter; n >= 0 && n != stop; first = false {
+		if lp != nil && !first {
+			switch {
+			case n == lp.header:
+				f.line(depth, "continue")
+				return
+			case !lp.body[n] && n == lp.follow:
+				f.line(depth, "break")
+				return
+			case !lp.body[n]:
+				f.jump(depth, n)
+				return
+			}
+		}
+
+		if f.done[n] {
+			f.jump(depth, n)
+			return
+		}
+
+		if l := f.loops[n]; l != nil && l != lp {
+			f.line(depth, "while (true) {")
+			f.seq(n, -1, l, depth+1, true)
+			if last := f.lines[len(f.lines)-1]; last.depth == depth+1 && last.text == "continue" {
+				f.lines = f.lines[:len(f.lines)-1]
+			}
+			f.line(depth, "}")
+			n = l.follow
+			continue
+		}
+
+		f.done[n] = true
+		f.lines = append(f.lines, intcodeLine{depth: depth, label: n})
+
+		b := f.d.cfg.block(n)
+		if b == nil {
+			f.line(depth, "invalid() // no valid instruction at %d before modification", n)
+			return
+		}
+
+		cond, target := f.statements(b, depth)
+		_, call := f.d.calls[n]
+
+		switch {
+		case b.Exit:
+			return
+
+		case call:
+			n = f.d.calls[n].Return
+
+		case b.Indirect && b.Next < 0:
+			f.d.dynamic = f.d.dynamic || target != "return"
+			f.line(depth, "%s", target)
+			return
+
+		case b.Indirect:
+			f.d.dynamic = f.d.dynamic || target != "return"
+			f.line(depth, "if (%s) {", cond)
+			f.line(depth+1, "%s", target)
+			f.line(depth, "}")
+			n = b.Next
+
+		case b.Jump >= 0 && b.Next >= 0 && b.Jump != b.Next:
+			var (
+				join        = f.join(n)
+				then, other = b.Jump, b.Next
+			)
+			if b.Instructions[len(b.Instructions)-1].Op.Type == opCodeTypeJumpIfFalse {
+				// Instructions skipped by the jump run if the condition holds
+				cond, then, other = intcodeNegate(cond), other, then
+			}
+			if then == join {
+				cond, then, other = intcodeNegate(cond), other, then
+			}
+
+			f.line(depth, "if (%s) {", cond)
+			f.seq(then, join, lp, depth+1, false)
+			if other != join {
+				f.line(depth, "} else {")
+				f.seq(other, join, lp, depth+1, false)
+			}
+			f.line(depth, "}")
+			n = join
+
+		case b.Jump >= 0:
+			n = b.Jump
+
+		default:
+			n = b.Next
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package aoc2019
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rendering of the decompiled functions: statements, expressions and
+// variable names and the final cleanup of the structured output
+
+// statements emits the instructions of the block not being part of
+// control flow and returns the condition and the target of the final
+// jump
+func (f *intcodeFunc) statements(b *intcodeBlock, depth int) (cond, target string) {
+	var (
+		deltas, end, _ = intcodeBlockDeltas(b)
+		base, known    = f.delta[b.Start]
+		last           = len(b.Instructions) - 1
+		skip           = map[int]bool{}
+		args           []string
+		call, isCall   = f.d.calls[b.Start]
+	)
+
+	if f.d.inlineCondition(b) {
+		skip[last-1] = true
+	}
+
+	// Relative base is known up to the first adjustment by a value read
+	// from memory
+	knownAt := func(i int) bool {
+		if !known {
+			return false
+		}
+		for _, inst := range b.Instructions[:i] {
+			if inst.Op.Type == opCodeTypeAdjRelBase && inst.Op.GetFlag(1) != opCodeFlagImmediate {
+				return false
+			}
+		}
+		return true
+	}
+
+	if isCall {
+		skip[call.Store] = true
+
+		args = make([]string, f.d.params[call.Target])
+		for j := range args {
+			args[j] = f.slot(int64(j)+1, base+end, knownAt(last))
+		}
+
+		for i, inst := range b.Instructions[:last] {
+			if intcodeOps[inst.Op.Type].Write != 3 || inst.Op.GetFlag(3) != opCodeFlagRelative || skip[i] {
+				continue
+			}
+
+			j := deltas[i] + inst.Params[2] - end
+			if j < 1 || j > int64(len(args)) || f.readsSlot(b, i+1, deltas[i]+inst.Params[2]) {
+				continue
+			}
+
+			args[j-1], _ = f.expr(inst, base+deltas[i], knownAt(i))
+			skip[i] = true
+		}
+	}
+
+	for i, inst := range b.Instructions {
+		var (
+			delta   = base + deltas[i]
+			kn      = knownAt(i)
+			comment string
+		)
+
+		if i == last && isIntcodeJump(inst.Op.Type) {
+			cond, target = f.jumpParts(b, delta, kn)
+			break
+		}
+
+		if skip[i] {
+			continue
+		}
+
+		if w := intcodeOps[inst.Op.Type].Write; w > 0 && inst.Op.GetFlag(w) == opCodeFlagPosition && f.d.isCode(inst.Params[w-1]) {
+			comment = fmt.Sprintf(" // modifies instruction at %d", f.d.cellInst[inst.Params[w-1]])
+		}
+		for a := inst.Addr + 1; a < inst.Addr+inst.Len(); a++ {
+			if by, ok := f.d.patched[a]; ok {
+				comment = fmt.Sprintf(" // operand modified by instruction at %d", by)
+			}
+		}
+
+		switch inst.Op.Type {
+		case opCodeTypeAdjRelBase:
+			if inst.Op.GetFlag(1) == opCodeFlagImmediate && kn {
+				// Tracked in the variable names
+				continue
+			}
+			f.line(depth, "rb += %s%s", f.operand(inst, 0, delta, kn), comment)
+
+		case opCodeTypeOutput:
+			f.line(depth, "output(%s)%s", f.operand(inst, 0, delta, kn), comment)
+
+		case opCodeTypeExit:
+			f.line(depth, "halt()%s", comment)
+
+		default:
+			expr, _ := f.expr(inst, delta, kn)
+			w := intcodeOps[inst.Op.Type].Write
+			f.line(depth, "%s = %s%s", f.operand(inst, int(w-1), delta, kn), expr, comment)
+		}
+	}
+
+	if isCall {
+		name := fmt.Sprintf("f%d", call.Target)
+		if call.Target == 0 {
+			name = "main"
+		}
+		f.line(depth, "%s(%s)", name, strings.Join(args, ", "))
+	}
+
+	return cond, target
+}
+
+// readsSlot reports whether an instruction of the block starting at the
+// index reads the relative slot (offset relative to the block start)
+func (f *intcodeFunc) readsSlot(b *intcodeBlock, from int, slot int64) bool {
+	deltas, _, _ := intcodeBlockDeltas(b)
+	for i := from; i < len(b.Instructions); i++ {
+		inst := b.Instructions[i]
+		for n, p := range inst.Params {
+			param := int64(n) + 1
+			if param != intcodeOps[inst.Op.Type].Write && inst.Op.GetFlag(param) == opCodeFlagRelative && deltas[i]+p == slot {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jumpParts renders the condition and the target of the jump ending
+// the block
+func (f *intcodeFunc) jumpParts(b *intcodeBlock, delta int64, known bool) (cond, target string) {
+	var (
+		last   = len(b.Instructions) - 1
+		jump   = b.Instructions[last]
+		value  = f.operand(jump, 0, delta, known)
+		isComp bool
+	)
+
+	if f.d.inlineCondition(b) {
+		// Preceding instruction does not adjust the relative base
+		value, isComp = f.expr(b.Instructions[last-1], delta, known)
+	}
+
+	switch {
+	case isComp:
+		cond = value
+	default:
+		cond = value + " != 0"
+	}
+	if jump.Op.Type == opCodeTypeJumpIfFalse {
+		cond = intcodeNegate(cond)
+	}
+
+	target = "goto *" + f.operand(jump, 1, delta, known)
+	if f.entry != 0 && known && jump.Op.GetFlag(2) == opCodeFlagRelative && delta+jump.Params[1] == 0 {
+		target = "return"
+	}
+
+	return cond, target
+}
+
+// intcodeNegate negates a comparison
+func intcodeNegate(cond string) string {
+	for _, r := range [][2]string{{" == ", " != "}, {" < ", " >= "}} {
+		switch {
+		case strings.Contains(cond, r[0]):
+			return strings.Replace(cond, r[0], r[1], 1)
+		case strings.Contains(cond, r[1]):
+			return strings.Replace(cond, r[1], r[0], 1)
+		}
+	}
+	return "!(" + cond + ")"
+}
+
+// expr renders the value computed by the instruction and whether it is
+// a comparison
+func (f *intcodeFunc) expr(inst intcodeInstruction, delta int64, known bool) (string, bool) {
+	if inst.Op.Type == opCodeTypeInput {
+		return "input()", false
+	}
+
+	if v, ok := intcodeConstantStore(inst); ok {
+		return fmt.Sprintf("%d", v), false
+	}
+
+	var (
+		a, b   = f.operand(inst, 0, delta, known), f.operand(inst, 1, delta, known)
+		aConst = inst.Op.GetFlag(1) == opCodeFlagImmediate
+		bConst = inst.Op.GetFlag(2) == opCodeFlagImmediate
+		bValue = inst.Params[1]
+	)
+
+	switch inst.Op.Type {
+	case opCodeTypeLessThan:
+		return a + " < " + b, true
+	case opCodeTypeEquals:
+		return a + " == " + b, true
+	}
+
+	if aConst && !bConst {
+		// Constants go to the right
+		a, b, bConst, bValue = b, a, true, inst.Params[0]
+	}
+
+	switch {
+	case inst.Op.Type == opCodeTypeAddition && bConst && bValue == 0:
+		return a, false
+	case inst.Op.Type == opCodeTypeAddition && bConst && bValue < 0:
+		return fmt.Sprintf("%s - %d", a, -bValue), false
+	case inst.Op.Type == opCodeTypeAddition:
+		return a + " + " + b, false
+	case bConst && bValue == 1:
+		return a, false
+	case bConst && bValue == 0:
+		return "0", false
+	case bConst && bValue == -1:
+		return "-" + a, false
+	}
+	return a + " * " + b, false
+}
+
+// operand renders the parameter with the given index
+func (f *intcodeFunc) operand(inst intcodeInstruction, n int, delta int64, known bool) string {
+	p := inst.Params[n]
+
+	switch inst.Op.GetFlag(int64(n) + 1) {
+	case opCodeFlagImmediate:
+		return fmt.Sprintf("%d", p)
+	case opCodeFlagRelative:
+		return f.slot(p, delta, known)
+	}
+	return f.cell(p)
+}
+
+func (f *intcodeFunc) cell(addr int64) string {
+	if f.d.isCode(addr) || addr < 0 {
+		return fmt.Sprintf("mem[%d]", addr)
+	}
+	return fmt.Sprintf("g%d", addr)
+}
+
+// slot names the relative cell [rb+k] based on the offset to the
+// relative base at the entry of the function
+func (f *intcodeFunc) slot(k, delta int64, known bool) string {
+	if !known {
+		return fmt.Sprintf("mem[rb%+d]", k)
+	}
+
+	var (
+		off    = delta + k
+		params = f.d.params[f.entry]
+	)
+
+	switch {
+	case f.entry == 0:
+		// Relative base starts at 0
+		return f.cell(off)
+	case off == 0:
+		return "retaddr"
+	case off > 0 && off <= params:
+		return fmt.Sprintf("arg%d", off)
+	case off > params && (f.frame == 0 || off < f.frame):
+		f.locals[off-params] = true
+		return fmt.Sprintf("local%d", off-params)
+	case f.frame > 0 && off >= f.frame:
+		return fmt.Sprintf("out%d", off-f.frame)
+	}
+	return fmt.Sprintf("mem[rb%+d]", k)
+}
+
+// render prints the function after simplifying the generated
+// control structures
+func (f *intcodeFunc) render() string {
+	var (
+		params []string
+		locals []string
+		out    []string
+	)
+
+	for i := int64(1); i <= f.d.params[f.entry]; i++ {
+		params = append(params, fmt.Sprintf("arg%d", i))
+	}
+
+	var ids []int64
+	for l := range f.locals {
+		ids = append(ids, l)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, l := range ids {
+		locals = append(locals, fmt.Sprintf("local%d", l))
+	}
+
+	switch {
+	case f.entry == 0:
+		out = append(out, "func main() {")
+	case f.fragment:
+		out = append(out, fmt.Sprintf("// Entered through indirect jumps\nfunc L%d() {", f.entry))
+	default:
+		out = append(out, fmt.Sprintf("func f%d(%s) {", f.entry, strings.Join(params, ", ")))
+	}
+
+	var lines []intcodeLine
+	for _, l := range f.lines {
+		if l.label < 0 || f.labels[l.label] {
+			lines = append(lines, l)
+		}
+	}
+
+	for _, l := range simplifyIntcodeLines(lines) {
+		switch {
+		case l.label >= 0:
+			out = append(out, fmt.Sprintf("L%d:", l.label))
+		case l.text == "":
+			if len(locals) > 0 {
+				out = append(out, "\tvar "+strings.Join(locals, ", "))
+			}
+		default:
+			out = append(out, strings.Repeat("\t", l.depth)+l.text)
+		}
+	}
+
+	return strings.Join(append(out, "}"), "\n") + "\n"
+}
+
+func (l intcodeLine) is(depth int, text string) bool {
+	return l.label < 0 && l.depth == depth && l.text == text
+}
+
+// condition returns the condition of an "if (...) {" line
+func (l intcodeLine) condition(depth int) (string, bool) {
+	if l.label >= 0 || l.depth != depth || !strings.HasPrefix(l.text, "if (") || !strings.HasSuffix(l.text, ") {") {
+		return "", false
+	}
+	return l.text[4 : len(l.text)-3], true
+}
+
+// simplifyIntcodeLines removes empty branches, joins nested conditions
+// into "else if" chains and converts endless loops with conditional
+// exits at their start or end into while and do-while loops
+func simplifyIntcodeLines(lines []intcodeLine) []intcodeLine {
+	for changed := true; changed; {
+		changed = false
+
+		for i := 0; i < len(lines)-1 && !changed; i++ {
+			var (
+				l     = lines[i]
+				depth = l.depth
+			)
+
+			switch {
+			case l.is(depth, "} else {") && lines[i+1].is(depth, "}"):
+				lines = removeIntcodeLines(lines, i, 1)
+				changed = true
+
+			case l.label < 0 && strings.HasPrefix(l.text, "if (") && lines[i+1].is(depth, "} else {"):
+				cond, _ := l.condition(depth)
+				lines[i].text = "if (" + intcodeNegate(cond) + ") {"
+				lines = removeIntcodeLines(lines, i+1, 1)
+				changed = true
+
+			case l.is(depth, "} else {"):
+				lines, changed = collapseIntcodeElse(lines, i)
+
+			case l.is(depth, "while (true) {"):
+				lines, changed = shapeIntcodeLoop(lines, i)
+			}
+		}
+	}
+
+	return lines
+}
+
+func removeIntcodeLines(lines []intcodeLine, from, n int) []intcodeLine {
+	return append(lines[:from], lines[from+n:]...)
+}
+
+// collapseIntcodeElse turns an else branch only containing a condition
+// into an "else if"
+func collapseIntcodeElse(lines []intcodeLine, i int) ([]intcodeLine, bool) {
+	depth := lines[i].depth
+
+	cond, ok := lines[i+1].condition(depth + 1)
+	if !ok {
+		return lines, false
+	}
+
+	end := -1
+	for k := i + 2; k < len(lines) && end < 0; k++ {
+		switch l := lines[k]; {
+		case l.depth > depth+1:
+		case l.is(depth+1, "}"):
+			end = k
+		case l.label < 0 && l.depth == depth+1 && strings.HasPrefix(l.text, "} else"):
+		default:
+			return lines, false
+		}
+	}
+
+	if end < 0 || end+1 >= len(lines) || !lines[end+1].is(depth, "}") {
+		return lines, false
+	}
+
+	lines[i].text = "} else if (" + cond + ") {"
+	for k := i + 2; k <= end; k++ {
+		lines[k].depth--
+	}
+	lines = removeIntcodeLines(lines, end+1, 1)
+	return removeIntcodeLines(lines, i+1, 1), true
+}
+
+// shapeIntcodeLoop converts the endless loop opened at the line into a
+// while loop if it starts with a conditional break or into a do-while
+// loop if it ends with a conditional continue or break
+func shapeIntcodeLoop(lines []intcodeLine, i int) ([]intcodeLine, bool) {
+	var (
+		depth = lines[i].depth
+		end   = -1
+	)
+
+	for k := i + 1; k < len(lines) && end < 0; k++ {
+		if lines[k].is(depth, "}") {
+			end = k
+		}
+	}
+	if end < 0 {
+		return lines, false
+	}
+
+	if cond, ok := lines[i+1].condition(depth + 1); ok && i+3 < end && lines[i+2].is(depth+2, "break") && lines[i+3].is(depth+1, "}") {
+		lines[i].text = "while (" + intcodeNegate(cond) + ") {"
+		return removeIntcodeLines(lines, i+1, 3), true
+	}
+
+	var cond string
+	switch {
+	case end-4 > i && lines[end-3].is(depth+2, "continue") && lines[end-2].is(depth+1, "}") && lines[end-1].is(depth+1, "break"):
+		c, ok := lines[end-4].condition(depth + 1)
+		if !ok || continuesIntcodeLoop(lines[i+1:end-4], depth+1) {
+			return lines, false
+		}
+		cond = c
+		lines = removeIntcodeLines(lines, end-4, 4)
+
+	case end-3 > i && lines[end-2].is(depth+2, "break") && lines[end-1].is(depth+1, "}"):
+		c, ok := lines[end-3].condition(depth + 1)
+		if !ok || continuesIntcodeLoop(lines[i+1:end-3], depth+1) {
+			return lines, false
+		}
+		cond = intcodeNegate(c)
+		lines = removeIntcodeLines(lines, end-3, 3)
+
+	default:
+		return lines, false
+	}
+
+	lines[i].text = "do {"
+	for k := i + 1; k < len(lines); k++ {
+		if lines[k].is(depth, "}") {
+			lines[k].text = "} while (" + cond + ")"
+			break
+		}
+	}
+	return lines, true
+}
+
+// continuesIntcodeLoop reports whether the lines of a loop body contain
+// a continue statement not belonging to a nested loop
+func continuesIntcodeLoop(lines []intcodeLine, depth int) bool {
+	var nested = -1
+	for _, l := range lines {
+		switch {
+		case l.label >= 0:
+		case nested >= 0 && l.depth == nested && (l.text == "}" || strings.HasPrefix(l.text, "} while (")):
+			nested = -1
+		case nested >= 0:
+		case l.text == "do {" || (strings.HasPrefix(l.text, "while (") && strings.HasSuffix(l.text, "{")):
+			nested = l.depth
+		case l.text == "continue":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package aoc2019
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecompileIntcode(t *testing.T) {
+	code, _ := ParseIntcode(strings.Join([]string{
+		"109,100",        //  0: arb 100
+		"3,70",           //  2: in -> [70]
+		"1007,70,10,71",  //  4: lt [70], 10 -> [71]
+		"1006,71,31",     //  8: jz [71], 31
+		"21001,70,0,1",   // 11: add [70], 0 -> [rb+1]
+		"21101,22,0,0",   // 15: add 22, 0 -> [rb+0]
+		"1105,1,32",      // 19: jnz 1, 32
+		"204,1",          // 22: out [rb+1]
+		"1001,70,1,70",   // 24: add [70], 1 -> [70]
+		"1105,1,4",       // 28: jnz 1, 4
+		"99",             // 31: hlt
+		"109,2",          // 32: arb 2
+		"1208,-1,3,72",   // 34: eq [rb-1], 3 -> [72]
+		"1006,72,48",     // 38: jz [72], 48
+		"22201,-1,-1,-1", // 41: add [rb-1], [rb-1] -> [rb-1]
+		"1106,0,52",      // 45: jz 0, 52
+		"21202,-1,-1,-1", // 48: mul [rb-1], -1 -> [rb-1]
+		"109,-2",         // 52: arb -2
+		"2106,0,0",       // 54: jz 0, [rb+0]
+	}, ","))
+
+	exp := strings.Join([]string{
+		"func main() {",
+		"\tg70 = input()",
+		"\twhile (g70 < 10) {",
+		"\t\tf32(g70)",
+		"\t\toutput(g101)",
+		"\t\tg70 = g70 + 1",
+		"\t}",
+		"\thalt()",
+		"}",
+		"",
+		"func f32(arg1) {",
+		"\tif (arg1 == 3) {",
+		"\t\targ1 = arg1 + arg1",
+		"\t} else {",
+		"\t\targ1 = -arg1",
+		"\t}",
+		"\treturn",
+		"}",
+		"",
+	}, "\n")
+
+	if got := DecompileIntcode(code); got != exp {
+		t.Errorf("Unexpected pseudocode:\n%s", got)
+	}
+}
+
+func TestDecompileIntcodeInputs(t *testing.T) {
+	for day, expect := range map[string][]string{
+		// Recursive function with local variable
+		"09": {"func f922(arg1) {", "\tvar local1", "f922(arg1 - 1)", "arg1 = out1 + local1"},
+		// Nested loops and patched instructions
+		"13": {"func f456(arg1, arg2, arg3, arg4) {", "} while (g382 < 45)", "// modifies instruction at 563"},
+		// Dispatch of the movement commands
+		"15": {"} else if (g1033 == 2) {", "output(g1044)"},
+		// Immediately patches its second instruction
+		"05": {"invalid() // no valid instruction at 6 before modification"},
+	} {
+		raw, err := ioutil.ReadFile("day" + day + "_input.txt")
+		if err != nil {
+			t.Fatalf("Unable to read input: %s", err)
+		}
+
+		code, err := ParseIntcode(strings.TrimSpace(string(raw)))
+		if err != nil {
+			t.Fatalf("Unable to parse input: %s", err)
+		}
+
+		out := DecompileIntcode(code)
+		for _, e := range expect {
+			if !strings.Contains(out, e) {
+				t.Errorf("Day %s: pseudocode does not contain %q", day, e)
+			}
+		}
+	}
+}
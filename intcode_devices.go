@@ -0,0 +1,198 @@
+package aoc2019
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// IntcodeDevice handles reads and writes of the memory cells it is
+// mapped to, the offset is relative to the start of the mapping
+type IntcodeDevice interface {
+	Read(m *IntcodeMachine, offset int64) (int64, error)
+	Write(m *IntcodeMachine, offset, value int64) error
+}
+
+type intcodeDeviceMapping struct {
+	Start, Size int64
+	Device      IntcodeDevice
+}
+
+// Map attaches the device to the memory cells start...start+size-1.
+// Reads and writes of parameters are passed to the device instead of
+// the memory, instructions cannot be executed from devices.
+func (i *IntcodeMachine) Map(start, size int64, dev IntcodeDevice) error {
+	if start < 0 || size < 1 {
+		return errors.Errorf("Invalid device range %d+%d", start, size)
+	}
+
+	for _, d := range i.devices {
+		if start < d.Start+d.Size && d.Start < start+size {
+			return errors.Errorf("Device range %d+%d overlaps device at %d+%d", start, size, d.Start, d.Size)
+		}
+	}
+
+	i.devices = append(i.devices, intcodeDeviceMapping{Start: start, Size: size, Device: dev})
+	return nil
+}
+
+func (i *IntcodeMachine) device(addr int64) (IntcodeDevice, int64) {
+	for _, d := range i.devices {
+		if addr >= d.Start && addr < d.Start+d.Size {
+			return d.Device, addr - d.Start
+		}
+	}
+	return nil, 0
+}
+
+// IntcodeConsole is a single cell device: writes put the value as a
+// character to the output, reads return the next character of the
+// input or -1 at the end of the input
+type IntcodeConsole struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// NewIntcodeConsole creates a console on the given input and output,
+// both are optional
+func NewIntcodeConsole(r io.Reader, w io.Writer) *IntcodeConsole {
+	var c = &IntcodeConsole{w: w}
+	if r != nil {
+		c.r = bufio.NewReader(r)
+	}
+	return c
+}
+
+// Read implements IntcodeDevice
+func (i *IntcodeConsole) Read(m *IntcodeMachine, offset int64) (int64, error) {
+	if i.r == nil {
+		return -1, nil
+	}
+
+	c, err := i.r.ReadByte()
+	switch {
+	case err == io.EOF:
+		return -1, nil
+	case err != nil:
+		return 0, errors.Wrap(err, "Unable to read console")
+	}
+
+	return int64(c), nil
+}
+
+// Write implements IntcodeDevice
+func (i *IntcodeConsole) Write(m *IntcodeMachine, offset, value int64) error {
+	if i.w == nil {
+		return errors.New("Console has no output")
+	}
+
+	if value < 0 || value > 255 {
+		return errors.Errorf("Value %d is no character", value)
+	}
+
+	_, err := i.w.Write([]byte{byte(value)})
+	return errors.Wrap(err, "Unable to write console")
+}
+
+// IntcodeTicker is a read-only single cell device returning the number
+// of instructions the machine executed so far including the one reading
+// the ticker
+type IntcodeTicker struct{}
+
+// Read implements IntcodeDevice
+func (IntcodeTicker) Read(m *IntcodeMachine, offset int64) (int64, error) {
+	return m.currentStep(), nil
+}
+
+// Write implements IntcodeDevice
+func (IntcodeTicker) Write(m *IntcodeMachine, offset, value int64) error {
+	return errors.New("Ticker is read-only")
+}
+
+// IntcodeRandom is a single cell device returning a non-negative
+// pseudo-random number on every read, writing a value reseeds it
+type IntcodeRandom struct {
+	rnd *rand.Rand
+}
+
+// NewIntcodeRandom creates a random source producing the same numbers
+// for the same seed
+func NewIntcodeRandom(seed int64) *IntcodeRandom {
+	return &IntcodeRandom{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// Read implements IntcodeDevice
+func (i *IntcodeRandom) Read(m *IntcodeMachine, offset int64) (int64, error) {
+	return i.rnd.Int63(), nil
+}
+
+// Write implements IntcodeDevice
+func (i *IntcodeRandom) Write(m *IntcodeMachine, offset, value int64) error {
+	i.rnd.Seed(value)
+	return nil
+}
+
+// IntcodeFramebuffer maps one cell per pixel of the given bounds row by
+// row, the values are color indices rendered through a palette
+type IntcodeFramebuffer struct {
+	bounds image.Rectangle
+	pixels []int64
+}
+
+// NewIntcodeFramebuffer creates a framebuffer for the bounds with all
+// pixels set to color 0
+func NewIntcodeFramebuffer(bounds image.Rectangle) *IntcodeFramebuffer {
+	return &IntcodeFramebuffer{
+		bounds: bounds,
+		pixels: make([]int64, bounds.Dx()*bounds.Dy()),
+	}
+}
+
+// Size returns the number of cells to map the framebuffer to
+func (i *IntcodeFramebuffer) Size() int64 { return int64(len(i.pixels)) }
+
+// Read implements IntcodeDevice
+func (i *IntcodeFramebuffer) Read(m *IntcodeMachine, offset int64) (int64, error) {
+	return i.pixels[offset], nil
+}
+
+// Write implements IntcodeDevice
+func (i *IntcodeFramebuffer) Write(m *IntcodeMachine, offset, value int64) error {
+	i.pixels[offset] = value
+	return nil
+}
+
+// Fill sets all pixels to the given color
+func (i *IntcodeFramebuffer) Fill(value int64) {
+	for idx := range i.pixels {
+		i.pixels[idx] = value
+	}
+}
+
+// Set sets the color of a pixel, pixels outside the bounds are ignored
+func (i *IntcodeFramebuffer) Set(x, y int, value int64) {
+	if !image.Pt(x, y).In(i.bounds) {
+		return
+	}
+	i.pixels[(y-i.bounds.Min.Y)*i.bounds.Dx()+x-i.bounds.Min.X] = value
+}
+
+// Image renders the framebuffer, colors missing in the palette are
+// left transparent
+func (i *IntcodeFramebuffer) Image(palette map[int64]color.Color) image.Image {
+	img := image.NewRGBA(i.bounds)
+
+	for idx, v := range i.pixels {
+		c, ok := palette[v]
+		if !ok {
+			continue
+		}
+		img.Set(i.bounds.Min.X+idx%i.bounds.Dx(), i.bounds.Min.Y+idx/i.bounds.Dx(), c)
+	}
+
+	return img
+}
@@ -0,0 +1,104 @@
+package aoc2019
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestIntcodeConsoleDevice(t *testing.T) {
+	// Writes "Hi" and echoes one character read from the console
+	code, _ := ParseIntcode("1101,72,0,1000,1101,105,0,1000,1001,1000,0,1000,99")
+
+	var buf = new(bytes.Buffer)
+	m := NewIntcodeMachine(code)
+	if err := m.Map(1000, 1, NewIntcodeConsole(strings.NewReader("x"), buf)); err != nil {
+		t.Fatalf("Unable to map console: %s", err)
+	}
+
+	if err := m.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if buf.String() != "Hix" {
+		t.Errorf("Unexpected console output: %q", buf.String())
+	}
+
+	if len(m.Memory()) != len(code) {
+		t.Errorf("Device access grew the memory to %d cells", len(m.Memory()))
+	}
+
+	// Echoing the end of input (-1) is no character
+	code, _ = ParseIntcode("1001,1000,0,1000,99")
+	m = NewIntcodeMachine(code)
+	m.Map(1000, 1, NewIntcodeConsole(strings.NewReader(""), buf))
+	err := m.Run(context.Background(), nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "Device write of address 1000 at address 0 failed") {
+		t.Errorf("Unexpected error for invalid character: %v", err)
+	}
+}
+
+func TestIntcodeTickerAndRandomDevices(t *testing.T) {
+	// Outputs the tick count, two random numbers, reseeds and outputs
+	// another random number
+	code, _ := ParseIntcode("4,2000,4,3000,4,3000,1101,42,0,3000,4,3000,99")
+
+	var outputs []int64
+	m := NewIntcodeMachine(code)
+	m.Map(2000, 1, IntcodeTicker{})
+	m.Map(3000, 1, NewIntcodeRandom(42))
+	if err := m.Run(context.Background(), nil, func(v int64) error {
+		outputs = append(outputs, v)
+		return nil
+	}); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	var (
+		rnd = rand.New(rand.NewSource(42))
+		exp = []int64{1, rnd.Int63(), rnd.Int63(), rand.New(rand.NewSource(42)).Int63()}
+	)
+
+	for i := range exp {
+		if i >= len(outputs) || outputs[i] != exp[i] {
+			t.Fatalf("Unexpected outputs: exp=%v got=%v", exp, outputs)
+		}
+	}
+
+	if err := m.Map(2000, 10, IntcodeTicker{}); err == nil {
+		t.Error("Overlapping device was mapped")
+	}
+}
+
+func TestIntcodeFramebufferDevice(t *testing.T) {
+	// Draws a diagonal line into a 2x2 framebuffer at 100
+	code, _ := ParseIntcode("1101,1,0,100,1101,1,0,103,99")
+
+	fb := NewIntcodeFramebuffer(image.Rect(0, 0, 2, 2))
+	m := NewIntcodeMachine(code)
+	m.Map(100, fb.Size(), fb)
+	if err := m.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	var (
+		black = color.RGBA{0x0, 0x0, 0x0, 0xff}
+		white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+		img   = fb.Image(map[int64]color.Color{0: black, 1: white})
+	)
+
+	for pt, exp := range map[image.Point]color.Color{
+		{0, 0}: white,
+		{1, 0}: black,
+		{0, 1}: black,
+		{1, 1}: white,
+	} {
+		if img.At(pt.X, pt.Y) != exp {
+			t.Errorf("Unexpected color at %s: exp=%v got=%v", pt, exp, img.At(pt.X, pt.Y))
+		}
+	}
+}
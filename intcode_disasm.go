@@ -0,0 +1,146 @@
+package aoc2019
+
+import (
+	"fmt"
+	"strings"
+)
+
+type intcodeOpInfo struct {
+	Name   string
+	Params int64
+	// Index of the parameter written to, 0 for none
+	Write int64
+}
+
+var intcodeOps = map[opCodeType]intcodeOpInfo{
+	opCodeTypeAddition:       {"add", 3, 3},
+	opCodeTypeMultiplication: {"mul", 3, 3},
+	opCodeTypeInput:          {"in", 1, 1},
+	opCodeTypeOutput:         {"out", 1, 0},
+	opCodeTypeJumpIfTrue:     {"jnz", 2, 0},
+	opCodeTypeJumpIfFalse:    {"jz", 2, 0},
+	opCodeTypeLessThan:       {"lt", 3, 3},
+	opCodeTypeEquals:         {"eq", 3, 3},
+	opCodeTypeAdjRelBase:     {"arb", 1, 0},
+	opCodeTypeExit:           {"hlt", 0, 0},
+}
+
+// intcodeInstruction is a decoded instruction or, if Data is set, a
+// single memory cell not considered to be code
+type intcodeInstruction struct {
+	Addr   int64
+	Op     opCode
+	Params []int64
+	Data   bool
+	Value  int64
+}
+
+// Len returns the number of memory cells used by the instruction
+func (i intcodeInstruction) Len() int64 {
+	if i.Data {
+		return 1
+	}
+	return int64(len(i.Params)) + 1
+}
+
+func (i intcodeInstruction) String() string {
+	if i.Data {
+		return fmt.Sprintf("data %d", i.Value)
+	}
+
+	var (
+		info  = intcodeOps[i.Op.Type]
+		out   = info.Name
+		reads []string
+		write string
+	)
+
+	for n, v := range i.Params {
+		var p string
+
+		switch i.Op.GetFlag(int64(n) + 1) {
+		case opCodeFlagImmediate:
+			p = fmt.Sprintf("%d", v)
+		case opCodeFlagRelative:
+			p = fmt.Sprintf("[rb%+d]", v)
+		default:
+			p = fmt.Sprintf("[%d]", v)
+		}
+
+		if int64(n)+1 == info.Write {
+			write = p
+		} else {
+			reads = append(reads, p)
+		}
+	}
+
+	if len(reads) > 0 {
+		out += " " + strings.Join(reads, ", ")
+	}
+
+	if write != "" {
+		out += " -> " + write
+	}
+
+	return out
+}
+
+// decodeIntcodeInstruction reads the instruction at the given address,
+// fails if the cell does not contain a valid opcode or the parameters
+// exceed the memory
+func decodeIntcodeInstruction(code []int64, addr int64) (intcodeInstruction, bool) {
+	if addr < 0 || addr >= int64(len(code)) || code[addr] < 0 {
+		return intcodeInstruction{}, false
+	}
+
+	op := parseOpCode(code[addr])
+	info, ok := intcodeOps[op.Type]
+	if !ok || int64(len(op.flags)) > info.Params || addr+info.Params >= int64(len(code)) {
+		return intcodeInstruction{}, false
+	}
+
+	for _, f := range op.flags {
+		if f > opCodeFlagRelative {
+			return intcodeInstruction{}, false
+		}
+	}
+
+	return intcodeInstruction{
+		Addr:   addr,
+		Op:     op,
+		Params: code[addr+1 : addr+1+info.Params],
+	}, true
+}
+
+// disassembleIntcode splits the program into instructions and data
+// cells. Addresses reported by isCode (optional) are known to be
+// instructions, other cells are decoded as instructions when possible
+// without overlapping a known instruction.
+func disassembleIntcode(code []int64, isCode func(addr int64) bool) []intcodeInstruction {
+	var out []intcodeInstruction
+
+	if isCode == nil {
+		isCode = func(int64) bool { return false }
+	}
+
+	for addr := int64(0); addr < int64(len(code)); {
+		inst, ok := decodeIntcodeInstruction(code, addr)
+		if ok && !isCode(addr) {
+			for a := addr + 1; a < addr+inst.Len(); a++ {
+				if isCode(a) {
+					ok = false
+					break
+				}
+			}
+		}
+
+		if !ok {
+			inst = intcodeInstruction{Addr: addr, Data: true, Value: code[addr]}
+		}
+
+		out = append(out, inst)
+		addr += inst.Len()
+	}
+
+	return out
+}
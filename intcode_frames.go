@@ -0,0 +1,118 @@
+package aoc2019
+
+import "github.com/pkg/errors"
+
+// intcodeFrame is a group of consecutive outputs forming one record of
+// a multi-value output protocol (i.e. x, y, tile)
+type intcodeFrame []int64
+
+// intcodeFrameProtocol describes a multi-value output protocol: the
+// number of outputs per frame and how a frame is decoded into the typed
+// record of the protocol
+type intcodeFrameProtocol struct {
+	size     int
+	decode   func(intcodeFrame) (interface{}, error)
+	specials []intcodeFrameSpecial
+}
+
+type intcodeFrameSpecial struct {
+	match  func(intcodeFrame) bool
+	decode func(intcodeFrame) (interface{}, error)
+}
+
+// withSpecial returns the protocol with frames carrying a special
+// meaning decoded into their own record type. Specials take precedence
+// over the default decoding and are checked in the order of
+// registration.
+func (p intcodeFrameProtocol) withSpecial(match func(intcodeFrame) bool, decode func(intcodeFrame) (interface{}, error)) intcodeFrameProtocol {
+	p.specials = append(append([]intcodeFrameSpecial(nil), p.specials...), intcodeFrameSpecial{match: match, decode: decode})
+	return p
+}
+
+// decodeFrame converts a complete frame into its record
+func (p intcodeFrameProtocol) decodeFrame(f intcodeFrame) (interface{}, error) {
+	for _, s := range p.specials {
+		if s.match(f) {
+			return s.decode(f)
+		}
+	}
+	return p.decode(f)
+}
+
+// intcodeFrameDecoder groups the outputs of a program into frames of
+// its protocol and dispatches the decoded records to a handler. It can
+// be used as output callback (push) or to drain an output channel
+// (decode).
+type intcodeFrameDecoder struct {
+	buffer   intcodeFrame
+	handler  func(interface{}) error
+	protocol intcodeFrameProtocol
+}
+
+func newIntcodeFrameDecoder(protocol intcodeFrameProtocol, handler func(interface{}) error) *intcodeFrameDecoder {
+	return &intcodeFrameDecoder{
+		buffer:   make(intcodeFrame, 0, protocol.size),
+		handler:  handler,
+		protocol: protocol,
+	}
+}
+
+// decode reads all outputs from the channel until it is closed
+func (d *intcodeFrameDecoder) decode(out <-chan int64) error {
+	for v := range out {
+		if err := d.push(v); err != nil {
+			// Drain the channel to not block the program
+			for range out {
+			}
+			return err
+		}
+	}
+
+	return d.finish()
+}
+
+// finish checks no incomplete frame is left in the buffer
+func (d *intcodeFrameDecoder) finish() error {
+	if len(d.buffer) > 0 {
+		return errors.Errorf("Incomplete frame at end of output: %v", d.buffer)
+	}
+	return nil
+}
+
+// push adds a single output value and dispatches the record as soon as
+// its frame is complete
+func (d *intcodeFrameDecoder) push(v int64) error {
+	d.buffer = append(d.buffer, v)
+	if len(d.buffer) < d.protocol.size {
+		return nil
+	}
+
+	frame := make(intcodeFrame, d.protocol.size)
+	copy(frame, d.buffer)
+	d.buffer = d.buffer[:0]
+
+	rec, err := d.protocol.decodeFrame(frame)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to decode frame %v", frame)
+	}
+
+	return errors.Wrapf(d.handler(rec), "Unable to handle frame %v", frame)
+}
+
+// matchIntcodeFramePrefix creates a matcher for frames starting with the
+// given values (i.e. the score frame -1, 0, score in day 13)
+func matchIntcodeFramePrefix(prefix ...int64) func(intcodeFrame) bool {
+	return func(f intcodeFrame) bool {
+		if len(f) < len(prefix) {
+			return false
+		}
+
+		for i, v := range prefix {
+			if f[i] != v {
+				return false
+			}
+		}
+
+		return true
+	}
+}
@@ -0,0 +1,75 @@
+package aoc2019
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIntcodeFrameDecoder(t *testing.T) {
+	// Outputs two tiles and a score frame in between
+	code, _ := ParseIntcode("104,1,104,2,104,3,104,-1,104,0,104,500,104,4,104,5,104,2,99")
+
+	var (
+		tiles []day13TileRecord
+		score int64
+	)
+
+	decoder := newIntcodeFrameDecoder(day13ScreenProtocol, func(rec interface{}) error {
+		switch rec := rec.(type) {
+		case day13TileRecord:
+			tiles = append(tiles, rec)
+		case day13ScoreRecord:
+			score = rec.Score
+		}
+		return nil
+	})
+
+	if _, err := ExecuteIntcodeWithParams(IntcodeParams{
+		Code:    code,
+		Context: context.Background(),
+		Out:     decoder.push,
+	}); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if err := decoder.finish(); err != nil {
+		t.Errorf("Decoder reported error: %s", err)
+	}
+
+	if exp := []day13TileRecord{{Pt(1, 2), day13TileTypeHPaddle}, {Pt(4, 5), day13TileTypeBlock}}; !reflect.DeepEqual(tiles, exp) {
+		t.Errorf("Unexpected tiles: exp=%v got=%v", exp, tiles)
+	}
+
+	if score != 500 {
+		t.Errorf("Unexpected score: exp=500 got=%d", score)
+	}
+}
+
+func TestIntcodeFrameDecoderIncomplete(t *testing.T) {
+	var (
+		decoder = newIntcodeFrameDecoder(day11PaintProtocol, func(interface{}) error { return nil })
+		out     = make(chan int64, 3)
+	)
+
+	out <- 1
+	out <- 0
+	out <- 1
+	close(out)
+
+	if err := decoder.decode(out); err == nil {
+		t.Error("Decoder did not report incomplete frame")
+	}
+}
+
+func TestIntcodeFrameDecoderInvalidRecord(t *testing.T) {
+	var decoder = newIntcodeFrameDecoder(day11PaintProtocol, func(interface{}) error { return nil })
+
+	if err := decoder.push(1); err != nil {
+		t.Fatalf("Incomplete frame was decoded: %s", err)
+	}
+
+	if err := decoder.push(5); err == nil {
+		t.Error("Decoder accepted invalid turn")
+	}
+}
@@ -0,0 +1,870 @@
+package aoc2019
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Intermediate representation of Intcode programs: every instruction
+// writing a result defines a value, reads of cells known to hold a value
+// refer to it. Values are carried into a block from its only
+// predecessor, other blocks start with all memory unknown.
+//
+// Self-modification is tracked per cell: instructions whose operands
+// are written at runtime are pinned, their patched operands are
+// pointers and writes into them follow the instruction when its block
+// is rewritten. Code cells read as data keep their address and value.
+// Programs patching opcodes or jump targets or containing cells which
+// cannot be decoded execute code not known statically, all of their
+// blocks are volatile and emitted unchanged.
+//
+// The representation assumes cells addressed relative to the base never
+// belong to the code, cells addressed through pointers are never
+// accessed in any other way and indirect jumps only target return
+// addresses (see intcodeCFG). All memory is observable when the program
+// exits.
+
+type intcodeIROperandKind int
+
+const (
+	intcodeIRConst intcodeIROperandKind = iota
+	intcodeIRMem
+	intcodeIRRel
+	// Operand written at runtime, Value holds the operand stored in the
+	// program and Flag its mode. In position mode it is a pointer.
+	intcodeIRPatched
+)
+
+type intcodeIROperand struct {
+	Kind intcodeIROperandKind
+	Flag opCodeFlag
+	// Constant, address or offset to the relative base
+	Value int64
+	// ID of the value held by the cell when read, 0 if unknown
+	Def int64
+}
+
+func (i intcodeIROperand) String() string {
+	if i.Def > 0 {
+		return fmt.Sprintf("v%d", i.Def)
+	}
+
+	switch i.Kind {
+	case intcodeIRMem:
+		return fmt.Sprintf("[%d]", i.Value)
+	case intcodeIRRel:
+		return fmt.Sprintf("[rb%+d]", i.Value)
+	case intcodeIRPatched:
+		switch i.Flag {
+		case opCodeFlagImmediate:
+			return fmt.Sprintf("*%d", i.Value)
+		case opCodeFlagRelative:
+			return fmt.Sprintf("[rb*%+d]", i.Value)
+		}
+		return fmt.Sprintf("[*%d]", i.Value)
+	}
+	return fmt.Sprintf("%d", i.Value)
+}
+
+type intcodeIRInst struct {
+	Op opCodeType
+	// Address of the instruction in the original program
+	Addr int64
+	// ID of the value defined by the instruction, 0 for none
+	Value int64
+	// Operands read by the instruction in parameter order
+	Args []intcodeIROperand
+	// Cell the result is written to, only set if Value is set
+	Dest intcodeIROperand
+	// Result is known without executing the program
+	Known bool
+	Const int64
+	// Operation depending on memory is replaced by the known result
+	Folded bool
+	// Operands of the instruction are written at runtime
+	Pinned bool
+	// Pinned instruction the result is written into
+	Target *intcodeIRInst
+}
+
+// taken reports whether a jump with a constant condition is taken,
+// ok is false for conditions not known
+func (i intcodeIRInst) taken() (taken, ok bool) {
+	if i.Args[0].Kind != intcodeIRConst {
+		return false, false
+	}
+
+	taken = i.Args[0].Value != 0
+	if i.Op == opCodeTypeJumpIfFalse {
+		taken = !taken
+	}
+	return taken, true
+}
+
+// constArgs reports whether all operands are constants
+func (i intcodeIRInst) constArgs() bool {
+	for _, a := range i.Args {
+		if a.Kind != intcodeIRConst {
+			return false
+		}
+	}
+	return true
+}
+
+// terminates reports whether execution never continues behind the
+// instruction
+func (i intcodeIRInst) terminates() bool {
+	if i.Op == opCodeTypeExit {
+		return true
+	}
+	taken, ok := i.taken()
+	return isIntcodeJump(i.Op) && ok && taken
+}
+
+// reads returns the cells read in position mode, all is set if the
+// instruction might read any cell
+func (i intcodeIRInst) reads() (cells []int64, all bool) {
+	if i.Folded {
+		return nil, false
+	}
+
+	for _, a := range i.Args {
+		switch a.Kind {
+		case intcodeIRMem:
+			cells = append(cells, a.Value)
+		case intcodeIRRel:
+			all = true
+		case intcodeIRPatched:
+			all = all || a.Flag == opCodeFlagRelative
+		}
+	}
+	return cells, all
+}
+
+// len returns the number of cells of the emitted instruction
+func (i intcodeIRInst) len() int64 {
+	if i.Folded {
+		return 4
+	}
+	return intcodeOps[i.Op].Params + 1
+}
+
+type intcodeIRBlock struct {
+	// Memory cells Start...End-1 are occupied by the block
+	Start, End int64
+	Insts      []*intcodeIRInst
+	// Program executes code not known statically, the block is emitted
+	// unchanged
+	Volatile bool
+
+	src *intcodeBlock
+	// Cells read by the original code before being written, any cell
+	// if readsAll is set
+	reads    map[int64]bool
+	readsAll bool
+	// Values held by the cells at the end of the block
+	out *intcodeIRState
+	// Cells live at the end of the block
+	liveOut intcodeIRCells
+}
+
+// intcodeIRState maps the cells to the values they are known to hold
+type intcodeIRState struct {
+	mem, rel map[int64]int64
+}
+
+func newIntcodeIRState() *intcodeIRState {
+	return &intcodeIRState{mem: map[int64]int64{}, rel: map[int64]int64{}}
+}
+
+func (i *intcodeIRState) clone() *intcodeIRState {
+	var out = newIntcodeIRState()
+	for k, v := range i.mem {
+		out.mem[k] = v
+	}
+	for k, v := range i.rel {
+		out.rel[k] = v
+	}
+	return out
+}
+
+// lookup returns the value held by the cell, 0 if unknown
+func (i *intcodeIRState) lookup(o intcodeIROperand) int64 {
+	switch o.Kind {
+	case intcodeIRMem:
+		return i.mem[o.Value]
+	case intcodeIRRel:
+		return i.rel[o.Value]
+	}
+	return 0
+}
+
+func (i *intcodeIRState) write(o intcodeIROperand, v int64) {
+	switch o.Kind {
+	case intcodeIRMem:
+		// Cells addressed relative to the base might be the same
+		i.rel = map[int64]int64{}
+		i.mem[o.Value] = v
+	case intcodeIRRel:
+		i.mem = map[int64]int64{}
+		i.rel = map[int64]int64{o.Value: v}
+	default:
+		// Pointer might point to any cell addressed relative to the base,
+		// a patched offset to any cell
+		if o.Flag == opCodeFlagRelative {
+			i.mem = map[int64]int64{}
+		}
+		i.rel = map[int64]int64{}
+	}
+}
+
+// adjust moves the relative base by the operand
+func (i *intcodeIRState) adjust(o intcodeIROperand) {
+	var rel = map[int64]int64{}
+	if o.Kind == intcodeIRConst {
+		for k, v := range i.rel {
+			rel[k-o.Value] = v
+		}
+	}
+	i.rel = rel
+}
+
+// intcodeIRCells is a set of memory cells. If all is set it contains
+// all cells except the ones in cells.
+type intcodeIRCells struct {
+	all   bool
+	cells map[int64]bool
+}
+
+func (i intcodeIRCells) has(addr int64) bool { return i.all != i.cells[addr] }
+
+func (i *intcodeIRCells) add(addr int64) {
+	if i.all {
+		delete(i.cells, addr)
+	} else {
+		i.cells[addr] = true
+	}
+}
+
+func (i *intcodeIRCells) remove(addr int64) {
+	if i.all {
+		i.cells[addr] = true
+	} else {
+		delete(i.cells, addr)
+	}
+}
+
+func (i intcodeIRCells) clone() intcodeIRCells {
+	var out = intcodeIRCells{all: i.all, cells: map[int64]bool{}}
+	for c := range i.cells {
+		out.cells[c] = true
+	}
+	return out
+}
+
+// union adds all cells of o and reports whether the set changed
+func (i *intcodeIRCells) union(o intcodeIRCells) bool {
+	var out = intcodeIRCells{all: i.all || o.all, cells: map[int64]bool{}}
+
+	switch {
+	case i.all && o.all:
+		for c := range i.cells {
+			if o.cells[c] {
+				out.cells[c] = true
+			}
+		}
+	case i.all || o.all:
+		all, other := *i, o
+		if o.all {
+			all, other = o, *i
+		}
+		for c := range all.cells {
+			if !other.cells[c] {
+				out.cells[c] = true
+			}
+		}
+	default:
+		for c := range i.cells {
+			out.cells[c] = true
+		}
+		for c := range o.cells {
+			out.cells[c] = true
+		}
+	}
+
+	var changed = out.all != i.all || len(out.cells) != len(i.cells)
+	*i = out
+	return changed
+}
+
+type intcodeIR struct {
+	Blocks []*intcodeIRBlock
+	// Program executes code not known statically
+	Unknown bool
+
+	cfg    *intcodeCFG
+	image  []int64
+	defs   map[int64]*intcodeIRInst
+	starts map[int64]*intcodeIRBlock
+	// Blocks of the instructions
+	blocks map[*intcodeIRInst]*intcodeIRBlock
+	// Code cells read as data
+	pinned map[int64]bool
+	// Instructions writing into pinned instructions
+	writers map[*intcodeIRInst][]*intcodeIRInst
+}
+
+// intcodeIRStats counts the changes of the optimization passes
+type intcodeIRStats struct {
+	// Instructions of the program before and after the optimization
+	Instructions, Optimized int
+	// Operations replaced by their constant result
+	Folded int
+	// Conditional jumps with a constant condition
+	Branches int
+	// Operations writing the value already held by the cell
+	Identities int
+	// Jumps retargeted to skip jumps with a known outcome
+	Threaded int
+	// Additions of constants merged into the following addition
+	Combined int
+	// Writes removed as the cell is not read before being overwritten
+	DeadStores int
+	// Instructions no longer reachable
+	Unreachable int
+}
+
+// lowerIntcode builds the representation of all reachable code
+func lowerIntcode(code []int64) *intcodeIR {
+	var (
+		cfg = buildIntcodeCFG(code)
+		ir  = &intcodeIR{
+			Unknown: len(cfg.Invalid) > 0 || len(cfg.Overlaps) > 0,
+			cfg:     cfg,
+			image:   cloneIntcode(code),
+			defs:    map[int64]*intcodeIRInst{},
+			starts:  map[int64]*intcodeIRBlock{},
+			blocks:  map[*intcodeIRInst]*intcodeIRBlock{},
+			pinned:  map[int64]bool{},
+			writers: map[*intcodeIRInst][]*intcodeIRInst{},
+		}
+		owner     = map[int64]intcodeInstruction{}
+		blockOf   = map[int64]*intcodeBlock{}
+		insts     = map[int64]*intcodeIRInst{}
+		nextValue int64
+	)
+
+	for _, b := range cfg.Blocks {
+		irb := &intcodeIRBlock{Start: b.Start, End: b.End(), src: b, reads: map[int64]bool{}}
+		ir.Blocks = append(ir.Blocks, irb)
+		ir.starts[b.Start] = irb
+
+		for _, inst := range b.Instructions {
+			blockOf[inst.Addr] = b
+			for a := inst.Addr; a < inst.Addr+inst.Len(); a++ {
+				owner[a] = inst
+			}
+		}
+	}
+
+	var patched = ir.patchedCells(owner, blockOf)
+
+	for _, b := range cfg.Blocks {
+		var (
+			irb     = ir.starts[b.Start]
+			state   = ir.entryState(b)
+			written = map[int64]bool{}
+		)
+
+		for _, inst := range b.Instructions {
+			var (
+				info = intcodeOps[inst.Op.Type]
+				iri  = &intcodeIRInst{Op: inst.Op.Type, Addr: inst.Addr}
+			)
+
+			for n, p := range inst.Params {
+				var (
+					param = int64(n) + 1
+					flag  = inst.Op.GetFlag(param)
+					o     = intcodeIROperand{Kind: intcodeIRMem, Value: p}
+				)
+
+				switch {
+				case patched[inst.Addr+param]:
+					o.Kind, o.Flag = intcodeIRPatched, flag
+					iri.Pinned = true
+				case flag == opCodeFlagRelative:
+					o.Kind = intcodeIRRel
+				case flag == opCodeFlagImmediate && param != info.Write:
+					o.Kind = intcodeIRConst
+				case param != info.Write && cfg.isCode(p):
+					ir.pinned[p] = true
+				}
+
+				if param == info.Write {
+					iri.Dest = o
+					continue
+				}
+
+				switch o.Kind {
+				case intcodeIRMem:
+					if !written[p] {
+						irb.reads[p] = true
+					}
+				case intcodeIRRel:
+					irb.readsAll = true
+				case intcodeIRPatched:
+					irb.readsAll = irb.readsAll || flag == opCodeFlagRelative
+				}
+
+				o.Def = state.lookup(o)
+				iri.Args = append(iri.Args, o)
+			}
+
+			if info.Write > 0 {
+				nextValue++
+				iri.Value = nextValue
+				ir.defs[iri.Value] = iri
+				state.write(iri.Dest, iri.Value)
+
+				if iri.Dest.Kind == intcodeIRMem {
+					written[iri.Dest.Value] = true
+				}
+			}
+
+			if iri.Op == opCodeTypeAdjRelBase {
+				state.adjust(iri.Args[0])
+			}
+
+			irb.Insts = append(irb.Insts, iri)
+			ir.blocks[iri] = irb
+			insts[iri.Addr] = iri
+		}
+
+		irb.out = state
+	}
+
+	for _, b := range ir.Blocks {
+		b.Volatile = ir.Unknown
+
+		for _, inst := range b.Insts {
+			if inst.Dest.Kind != intcodeIRMem || !patched[inst.Dest.Value] {
+				continue
+			}
+			inst.Target = insts[owner[inst.Dest.Value].Addr]
+			ir.writers[inst.Target] = append(ir.writers[inst.Target], inst)
+		}
+	}
+
+	return ir
+}
+
+// patchedCells returns the code cells written at runtime before being
+// executed and marks the program unknown if opcodes or jump targets are
+// among them. Writes into the entry block are ignored if it is executed
+// only once and the write happens after it.
+func (i *intcodeIR) patchedCells(owner map[int64]intcodeInstruction, blockOf map[int64]*intcodeBlock) map[int64]bool {
+	var (
+		entry     = i.cfg.block(0)
+		entryOnce = entry != nil && len(i.cfg.predecessors(0)) == 0
+		patched   = map[int64]bool{}
+	)
+
+	for _, b := range i.cfg.Blocks {
+		for _, inst := range b.Instructions {
+			write := intcodeOps[inst.Op.Type].Write
+			if write == 0 || inst.Op.GetFlag(write) == opCodeFlagRelative {
+				continue
+			}
+
+			target, ok := owner[inst.Params[write-1]]
+			if !ok || (entryOnce && blockOf[target.Addr] == entry && b != entry) {
+				continue
+			}
+
+			var (
+				addr  = inst.Params[write-1]
+				param = addr - target.Addr
+			)
+			patched[addr] = true
+
+			if param == 0 || (isIntcodeJump(target.Op.Type) && (param != 1 || target.Op.GetFlag(1) != opCodeFlagPosition)) {
+				i.Unknown = true
+			}
+		}
+	}
+
+	return patched
+}
+
+// entryState returns the values known at the start of the block: the
+// state at the end of its predecessor if it is the only way to reach
+// the block and was lowered before
+func (i *intcodeIR) entryState(b *intcodeBlock) *intcodeIRState {
+	var preds = i.cfg.predecessors(b.Start)
+	if b.Start == 0 || len(preds) != 1 || i.cfg.isReturn(b.Start) {
+		return newIntcodeIRState()
+	}
+
+	if pb := i.starts[preds[0]]; pb.out != nil {
+		return pb.out.clone()
+	}
+	return newIntcodeIRState()
+}
+
+// successors returns the blocks the block might continue with, the
+// original ones for blocks emitted unchanged
+func (i *intcodeIR) successors(b *intcodeIRBlock, rewritten bool) []*intcodeIRBlock {
+	var (
+		addrs    []int64
+		indirect bool
+	)
+
+	if rewritten {
+		var last *intcodeIRInst
+		if len(b.Insts) > 0 {
+			last = b.Insts[len(b.Insts)-1]
+		}
+
+		if last != nil && isIntcodeJump(last.Op) {
+			if last.Args[1].Kind == intcodeIRConst {
+				addrs = append(addrs, last.Args[1].Value)
+			} else {
+				indirect = true
+			}
+		}
+
+		if last == nil || !last.terminates() {
+			addrs = append(addrs, b.End)
+		}
+	} else {
+		addrs = append(addrs, b.src.Jump, b.src.Next)
+		indirect = b.src.Indirect
+	}
+
+	if indirect {
+		for addr := range i.cfg.returns {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	var out []*intcodeIRBlock
+	for _, addr := range addrs {
+		if s := i.starts[addr]; s != nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// reachable returns the blocks reachable from the entry point or as
+// return address
+func (i *intcodeIR) reachable(rewrite map[*intcodeIRBlock]bool) map[*intcodeIRBlock]bool {
+	var (
+		seen  = map[*intcodeIRBlock]bool{}
+		queue []*intcodeIRBlock
+	)
+
+	for addr := range i.cfg.returns {
+		queue = append(queue, i.starts[addr])
+	}
+	queue = append(queue, i.starts[0])
+
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+
+		if b == nil || seen[b] {
+			continue
+		}
+		seen[b] = true
+		queue = append(queue, i.successors(b, rewrite[b])...)
+	}
+
+	return seen
+}
+
+func (i *intcodeIR) String() string {
+	var out []string
+
+	for _, b := range i.Blocks {
+		var head = fmt.Sprintf("block %d-%d:", b.Start, b.End-1)
+		if b.Volatile {
+			head += " (volatile)"
+		}
+		out = append(out, head)
+
+		for _, inst := range b.Insts {
+			out = append(out, "  "+i.formatInst(inst))
+		}
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+func (i *intcodeIR) formatInst(inst *intcodeIRInst) string {
+	var args []string
+	for _, a := range inst.Args {
+		args = append(args, a.String())
+	}
+
+	var s = intcodeOps[inst.Op].Name
+	if len(args) > 0 {
+		s += " " + strings.Join(args, ", ")
+	}
+
+	if inst.Folded {
+		s = fmt.Sprintf("%d", inst.Const)
+	}
+
+	if inst.Value > 0 {
+		var dest = inst.Dest
+		dest.Def = 0
+		s = fmt.Sprintf("v%d = %s -> %s", inst.Value, s, dest)
+	}
+
+	return s
+}
+
+// operand returns the parameter mode and value to emit for the operand
+func (i *intcodeIR) operand(o intcodeIROperand) (opCodeFlag, int64) {
+	switch o.Kind {
+	case intcodeIRMem:
+		return opCodeFlagPosition, o.Value
+	case intcodeIRPatched:
+		return o.Flag, o.Value
+	case intcodeIRRel:
+		return opCodeFlagRelative, o.Value
+	}
+	return opCodeFlagImmediate, o.Value
+}
+
+// encode returns the cells of the instruction, writes into pinned
+// instructions are redirected to their address in addrs
+func (i *intcodeIR) encode(inst *intcodeIRInst, addrs map[*intcodeIRInst]int64) []int64 {
+	var (
+		args  = inst.Args
+		op    = inst.Op
+		write = intcodeOps[inst.Op].Write
+		dest  = inst.Dest
+	)
+
+	if inst.Folded {
+		// Store the constant result
+		args = []intcodeIROperand{{Value: inst.Const}, {Value: 0}}
+		op = opCodeTypeAddition
+	}
+
+	if t := inst.Target; t != nil {
+		if addr, ok := addrs[t]; ok {
+			dest.Value += addr - t.Addr
+		}
+	}
+
+	var (
+		out    = []int64{int64(op)}
+		factor = int64(100)
+	)
+
+	for n := int64(1); n <= int64(len(args)) || n == write; n++ {
+		var o intcodeIROperand
+		switch {
+		case n == write:
+			o = dest
+		default:
+			o = args[n-1]
+		}
+
+		flag, v := i.operand(o)
+		out[0] += int64(flag) * factor
+		out = append(out, v)
+		factor *= 10
+	}
+
+	return out
+}
+
+// continues reports whether execution might continue behind the last
+// instruction of the block
+func (i *intcodeIRBlock) continues() bool {
+	return len(i.Insts) == 0 || !i.Insts[len(i.Insts)-1].terminates()
+}
+
+// layout assigns the addresses of the instructions in the rewritten
+// blocks and drops blocks whose instructions do not fit
+func (i *intcodeIR) layout(rewrite map[*intcodeIRBlock]bool) map[*intcodeIRInst]int64 {
+	var addrs = map[*intcodeIRInst]int64{}
+
+	for _, b := range i.Blocks {
+		if !rewrite[b] {
+			continue
+		}
+
+		var addr = b.Start
+		for _, inst := range b.Insts {
+			addrs[inst] = addr
+			addr += inst.len()
+		}
+
+		if b.continues() && addr < b.End {
+			// Jump to the block behind the original one
+			addr += 3
+		}
+
+		if addr > b.End {
+			delete(rewrite, b)
+			for _, inst := range b.Insts {
+				delete(addrs, inst)
+			}
+		}
+	}
+
+	return addrs
+}
+
+// assemble returns the cells and the number of instructions of a
+// rewritten block
+func (i *intcodeIR) assemble(b *intcodeIRBlock, addrs map[*intcodeIRInst]int64) ([]int64, int) {
+	var cells []int64
+	for _, inst := range b.Insts {
+		cells = append(cells, i.encode(inst, addrs)...)
+	}
+
+	if b.continues() && int64(len(cells)) < b.End-b.Start {
+		cells = append(cells, int64(opCodeTypeJumpIfTrue)+1100, 1, b.End)
+		return cells, len(b.Insts) + 1
+	}
+	return cells, len(b.Insts)
+}
+
+// relocatable reports whether the cells of the rewritten block keep the
+// values read as data and its pinned instructions are either in place
+// or only patched by rewritten blocks
+func (i *intcodeIR) relocatable(b *intcodeIRBlock, cells []int64, addrs map[*intcodeIRInst]int64, rewrite map[*intcodeIRBlock]bool) bool {
+	for a := b.Start; a < b.End; a++ {
+		if !i.pinned[a] {
+			continue
+		}
+		if off := a - b.Start; off >= int64(len(cells)) || cells[off] != i.image[a] {
+			return false
+		}
+	}
+
+	for _, inst := range b.Insts {
+		if !inst.Pinned || addrs[inst] == inst.Addr {
+			continue
+		}
+
+		for a := inst.Addr; a < inst.Addr+inst.len(); a++ {
+			if i.pinned[a] {
+				return false
+			}
+		}
+
+		for _, w := range i.writers[inst] {
+			if !rewrite[i.blocks[w]] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// emit creates the program from the representation. Blocks no longer
+// reachable and blocks which cannot be rewritten keep their original
+// code, all other cells keep their values.
+func (i *intcodeIR) emit(stats *intcodeIRStats) []int64 {
+	var (
+		out     = cloneIntcode(i.image)
+		rewrite = map[*intcodeIRBlock]bool{}
+		addrs   map[*intcodeIRInst]int64
+	)
+
+	for _, b := range i.Blocks {
+		if !b.Volatile {
+			rewrite[b] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		var reach = i.reachable(rewrite)
+		for b := range rewrite {
+			if !reach[b] {
+				delete(rewrite, b)
+			}
+		}
+
+		var n = len(rewrite)
+		addrs = i.layout(rewrite)
+		changed = len(rewrite) < n
+
+		for _, b := range i.Blocks {
+			if !rewrite[b] {
+				continue
+			}
+			if cells, _ := i.assemble(b, addrs); !i.relocatable(b, cells, addrs, rewrite) {
+				delete(rewrite, b)
+				changed = true
+			}
+		}
+	}
+
+	var reach = i.reachable(rewrite)
+	for _, b := range i.Blocks {
+		switch {
+		case b.Volatile:
+			continue
+
+		case rewrite[b]:
+			cells, n := i.assemble(b, addrs)
+			copy(out[b.Start:], cells)
+			stats.Optimized += n
+
+		case reach[b]:
+			stats.Optimized += len(b.src.Instructions)
+
+		default:
+			stats.Unreachable += len(b.Insts)
+		}
+	}
+
+	return out
+}
+
+// instructions returns the number of instructions in non-volatile
+// blocks
+func (i *intcodeIR) instructions() int {
+	var n int
+	for _, b := range i.Blocks {
+		if !b.Volatile {
+			n += len(b.Insts)
+		}
+	}
+	return n
+}
+
+// optimizeIntcode lowers the program, runs all optimization passes and
+// emits the optimized program. Programs executing code not known
+// statically are returned unchanged.
+func optimizeIntcode(code []int64) ([]int64, intcodeIRStats) {
+	var (
+		ir    = lowerIntcode(code)
+		stats = intcodeIRStats{Instructions: ir.instructions()}
+	)
+
+	if ir.Unknown {
+		return cloneIntcode(code), stats
+	}
+
+	ir.foldConstants(&stats)
+	ir.removeIdentities(&stats)
+	ir.threadJumps(&stats)
+	ir.analyzeLiveness()
+	ir.combineAdditions(&stats)
+	ir.eliminateDeadStores(&stats)
+
+	return ir.emit(&stats), stats
+}
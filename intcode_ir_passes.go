@@ -0,0 +1,391 @@
+package aoc2019
+
+// Optimization passes on the intermediate representation, all of them
+// skip volatile blocks and keep pinned instructions
+
+// foldConstants replaces operands holding known values by constants and
+// operations on constants by their result and resolves conditional
+// jumps on constant conditions: jumps always taken become
+// unconditional, jumps never taken are removed
+func (i *intcodeIR) foldConstants(stats *intcodeIRStats) {
+	for _, b := range i.Blocks {
+		if b.Volatile {
+			continue
+		}
+
+		var insts []*intcodeIRInst
+		for _, inst := range b.Insts {
+			if inst.Pinned {
+				insts = append(insts, inst)
+				continue
+			}
+
+			var (
+				constArgs = inst.constArgs()
+				constCond = len(inst.Args) > 0 && inst.Args[0].Kind == intcodeIRConst
+			)
+
+			for n, a := range inst.Args {
+				if a.Def == 0 || !i.defs[a.Def].Known {
+					continue
+				}
+
+				v := i.defs[a.Def].Const
+				if isIntcodeJump(inst.Op) && n == 1 && i.starts[v] == nil {
+					// Jump targets have to stay block starts
+					continue
+				}
+				inst.Args[n] = intcodeIROperand{Kind: intcodeIRConst, Value: v}
+			}
+
+			switch inst.Op {
+			case opCodeTypeAddition, opCodeTypeMultiplication, opCodeTypeLessThan, opCodeTypeEquals:
+				if v, ok := foldIntcodeOperation(inst); ok {
+					inst.Known, inst.Const = true, v
+					if !constArgs {
+						inst.Folded = true
+						stats.Folded++
+					}
+				}
+
+			case opCodeTypeJumpIfTrue, opCodeTypeJumpIfFalse:
+				taken, ok := inst.taken()
+				if !ok {
+					break
+				}
+
+				if !taken {
+					// Execution always continues behind the jump
+					stats.Branches++
+					continue
+				}
+
+				if !constCond {
+					stats.Branches++
+					inst.Op = opCodeTypeJumpIfTrue
+					inst.Args[0] = intcodeIROperand{Kind: intcodeIRConst, Value: 1}
+				}
+			}
+
+			insts = append(insts, inst)
+		}
+
+		b.Insts = insts
+	}
+}
+
+// foldIntcodeOperation calculates the result of an arithmetic or
+// comparison instruction if it does not depend on memory
+func foldIntcodeOperation(inst *intcodeIRInst) (int64, bool) {
+	var (
+		a, b   = inst.Args[0], inst.Args[1]
+		aC, bC = a.Kind == intcodeIRConst, b.Kind == intcodeIRConst
+	)
+
+	if inst.Op == opCodeTypeMultiplication && ((aC && a.Value == 0) || (bC && b.Value == 0)) {
+		return 0, true
+	}
+
+	if !aC || !bC {
+		return 0, false
+	}
+
+	switch inst.Op {
+	case opCodeTypeAddition:
+		return a.Value + b.Value, true
+	case opCodeTypeMultiplication:
+		return a.Value * b.Value, true
+	case opCodeTypeLessThan:
+		if a.Value < b.Value {
+			return 1, true
+		}
+	case opCodeTypeEquals:
+		if a.Value == b.Value {
+			return 1, true
+		}
+	}
+
+	return 0, true
+}
+
+// constOperand splits the operands of an operation into a constant and
+// a cell, ok is false unless exactly one of them is constant
+func constOperand(inst *intcodeIRInst) (c int64, o intcodeIROperand, ok bool) {
+	var a, b = inst.Args[0], inst.Args[1]
+	if a.Kind == intcodeIRConst {
+		a, b = b, a
+	}
+
+	if a.Kind == intcodeIRConst || b.Kind != intcodeIRConst {
+		return 0, intcodeIROperand{}, false
+	}
+	return b.Value, a, true
+}
+
+// removeIdentities removes additions of 0 and multiplications by 1
+// writing the result back into the cell of the other operand
+func (i *intcodeIR) removeIdentities(stats *intcodeIRStats) {
+	for _, b := range i.Blocks {
+		if b.Volatile {
+			continue
+		}
+
+		var insts []*intcodeIRInst
+		for _, inst := range b.Insts {
+			if i.isIdentity(inst) {
+				stats.Identities++
+				continue
+			}
+			insts = append(insts, inst)
+		}
+		b.Insts = insts
+	}
+}
+
+func (i *intcodeIR) isIdentity(inst *intcodeIRInst) bool {
+	var neutral int64
+	switch {
+	case inst.Pinned || inst.Known || inst.Target != nil:
+		return false
+	case inst.Op == opCodeTypeMultiplication:
+		neutral = 1
+	case inst.Op != opCodeTypeAddition:
+		return false
+	}
+
+	c, o, ok := constOperand(inst)
+	if !ok || c != neutral || o.Kind != inst.Dest.Kind || o.Value != inst.Dest.Value {
+		return false
+	}
+
+	return o.Kind == intcodeIRRel || (o.Kind == intcodeIRMem && !i.cfg.isCode(o.Value))
+}
+
+// threadJumps retargets jumps to the final target if the target block
+// is empty or only consists of a jump whose outcome is known from the
+// values at the end of the jumping block
+func (i *intcodeIR) threadJumps(stats *intcodeIRStats) {
+	for _, b := range i.Blocks {
+		if b.Volatile || len(b.Insts) == 0 {
+			continue
+		}
+
+		var jump = b.Insts[len(b.Insts)-1]
+		if !isIntcodeJump(jump.Op) || jump.Pinned || jump.Args[1].Kind != intcodeIRConst {
+			continue
+		}
+
+		var target = jump.Args[1].Value
+		// Limit the steps to not run into cycles of jumps
+		for n := 0; n < len(i.Blocks); n++ {
+			next, ok := i.passThrough(b.out, target)
+			if !ok || next == target {
+				break
+			}
+			target = next
+		}
+
+		if target != jump.Args[1].Value {
+			jump.Args[1].Value = target
+			stats.Threaded++
+		}
+	}
+}
+
+// passThrough returns the block execution continues with after entering
+// the block at the address if the block contains nothing but a jump
+// whose outcome is known from the values in state
+func (i *intcodeIR) passThrough(state *intcodeIRState, addr int64) (int64, bool) {
+	var b = i.starts[addr]
+	switch {
+	case b == nil || b.Volatile || len(b.Insts) > 1:
+		return 0, false
+	case len(b.Insts) == 0:
+		return b.End, i.starts[b.End] != nil
+	}
+
+	var jump = b.Insts[0]
+	if !isIntcodeJump(jump.Op) || jump.Pinned {
+		return 0, false
+	}
+
+	var cond = jump.Args[0]
+	if v := state.lookup(cond); v > 0 && i.defs[v].Known {
+		cond = intcodeIROperand{Kind: intcodeIRConst, Value: i.defs[v].Const}
+	}
+
+	taken, ok := (&intcodeIRInst{Op: jump.Op, Args: []intcodeIROperand{cond}}).taken()
+	switch {
+	case !ok:
+		return 0, false
+	case !taken:
+		return b.End, i.starts[b.End] != nil
+	case jump.Args[1].Kind != intcodeIRConst:
+		return 0, false
+	}
+	return jump.Args[1].Value, i.starts[jump.Args[1].Value] != nil
+}
+
+// analyzeLiveness determines the cells read before being overwritten
+// after every block. Reads and successors of the original code are
+// included as blocks might end up emitted unchanged.
+func (i *intcodeIR) analyzeLiveness() {
+	var (
+		liveIn = map[*intcodeIRBlock]*intcodeIRCells{}
+		succs  = map[*intcodeIRBlock][]*intcodeIRBlock{}
+	)
+
+	for _, b := range i.Blocks {
+		liveIn[b] = &intcodeIRCells{cells: map[int64]bool{}}
+		succs[b] = append(i.successors(b, true), i.successors(b, false)...)
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for n := len(i.Blocks) - 1; n >= 0; n-- {
+			var (
+				b   = i.Blocks[n]
+				out = intcodeIRCells{cells: map[int64]bool{}}
+			)
+
+			for _, s := range succs[b] {
+				out.union(*liveIn[s])
+			}
+			b.liveOut = out
+
+			var in = out.clone()
+			for _, inst := range b.Insts {
+				if inst.Value > 0 && inst.Dest.Kind == intcodeIRMem {
+					in.remove(inst.Dest.Value)
+				}
+			}
+			for c := range b.reads {
+				in.add(c)
+			}
+			if b.src.Exit || b.readsAll {
+				in = intcodeIRCells{all: true, cells: map[int64]bool{}}
+			}
+
+			if liveIn[b].union(in) {
+				changed = true
+			}
+		}
+	}
+}
+
+// transfer updates the cells live after an instruction to the ones
+// live before it
+func (i *intcodeIR) transfer(live *intcodeIRCells, inst *intcodeIRInst) {
+	if inst.Op == opCodeTypeExit {
+		*live = intcodeIRCells{all: true, cells: map[int64]bool{}}
+	}
+
+	if inst.Value > 0 && inst.Dest.Kind == intcodeIRMem {
+		live.remove(inst.Dest.Value)
+	}
+
+	cells, all := inst.reads()
+	if all {
+		*live = intcodeIRCells{all: true, cells: map[int64]bool{}}
+	}
+	for _, c := range cells {
+		live.add(c)
+	}
+}
+
+// combineAdditions merges an addition of a constant into the following
+// addition of a constant using its result, if the result is not read
+// otherwise: c1 + (c2 + x) becomes (c1 + c2) + x
+func (i *intcodeIR) combineAdditions(stats *intcodeIRStats) {
+	for _, b := range i.Blocks {
+		if b.Volatile {
+			continue
+		}
+
+		var (
+			live  = b.liveOut.clone()
+			after = make([]intcodeIRCells, len(b.Insts))
+		)
+		for n := len(b.Insts) - 1; n >= 0; n-- {
+			after[n] = live.clone()
+			i.transfer(&live, b.Insts[n])
+		}
+
+		var insts []*intcodeIRInst
+		for n, inst := range b.Insts {
+			if n+1 < len(b.Insts) && i.combine(inst, b.Insts[n+1], after[n+1]) {
+				stats.Combined++
+				continue
+			}
+			insts = append(insts, inst)
+		}
+		b.Insts = insts
+	}
+}
+
+// combine rewrites inst to not use the result of def, live are the
+// cells live after inst
+func (i *intcodeIR) combine(def, inst *intcodeIRInst, live intcodeIRCells) bool {
+	if def.Op != opCodeTypeAddition || inst.Op != opCodeTypeAddition || def.Known || inst.Known || !i.removable(def) || inst.Pinned {
+		return false
+	}
+
+	c1, a, ok := constOperand(inst)
+	if !ok || a.Kind != intcodeIRMem || a.Value != def.Dest.Value || a.Def != def.Value {
+		return false
+	}
+
+	c2, x, ok := constOperand(def)
+	if !ok || (x.Kind != intcodeIRMem && x.Kind != intcodeIRRel) {
+		return false
+	}
+
+	if (inst.Dest.Kind != intcodeIRMem || inst.Dest.Value != def.Dest.Value) && live.has(def.Dest.Value) {
+		return false
+	}
+
+	inst.Args = []intcodeIROperand{{Kind: intcodeIRConst, Value: c1 + c2}, x}
+	return true
+}
+
+// removable reports whether the instruction has no effect besides
+// writing its result to a cell outside of the code
+func (i *intcodeIR) removable(inst *intcodeIRInst) bool {
+	return inst.Value > 0 && inst.Op != opCodeTypeInput && !inst.Pinned && inst.Target == nil &&
+		inst.Dest.Kind == intcodeIRMem && !i.cfg.isCode(inst.Dest.Value)
+}
+
+// eliminateDeadStores removes operations whose result is not read before
+// being overwritten or the program exits
+func (i *intcodeIR) eliminateDeadStores(stats *intcodeIRStats) {
+	for _, b := range i.Blocks {
+		if b.Volatile {
+			continue
+		}
+
+		var (
+			live = b.liveOut.clone()
+			keep = make([]bool, len(b.Insts))
+		)
+
+		for n := len(b.Insts) - 1; n >= 0; n-- {
+			inst := b.Insts[n]
+
+			if i.removable(inst) && !live.has(inst.Dest.Value) {
+				stats.DeadStores++
+				continue
+			}
+			keep[n] = true
+			i.transfer(&live, inst)
+		}
+
+		var insts []*intcodeIRInst
+		for n, inst := range b.Insts {
+			if keep[n] {
+				insts = append(insts, inst)
+			}
+		}
+		b.Insts = insts
+	}
+}
@@ -0,0 +1,351 @@
+package aoc2019
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestIntcodeIRPasses(t *testing.T) {
+	code, _ := ParseIntcode(strings.Join([]string{
+		"1101,2,3,100",    //  0: [100] = 5
+		"1002,100,4,101",  //  4: [101] = [100] * 4, folded to 20
+		"1007,101,10,102", //  8: [102] = [101] < 10, folded to 0
+		"1005,102,28",     // 12: never taken
+		"1101,0,7,103",    // 15: dead store
+		"1101,0,8,103",    // 19
+		"4,103",           // 23: out 8
+		"1105,1,29",       // 25: threaded to 32
+		"99",              // 28: unreachable
+		"1105,1,32",       // 29: unreachable
+		"4,101",           // 32: out 20
+		"99",              // 34
+	}, ","))
+
+	opt, stats := optimizeIntcode(code)
+
+	exp := intcodeIRStats{Instructions: 12, Optimized: 9, Folded: 2, Branches: 1, Threaded: 1, DeadStores: 1, Unreachable: 2}
+	if stats != exp {
+		t.Errorf("Unexpected stats: exp=%+v got=%+v", exp, stats)
+	}
+
+	orig, err := RunIntcode(IntcodeParams{Code: code})
+	if err != nil {
+		t.Fatalf("Original program failed: %s", err)
+	}
+
+	res, err := RunIntcode(IntcodeParams{Code: opt})
+	if err != nil {
+		t.Fatalf("Optimized program failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(res.Outputs, []int64{8, 20}) || !reflect.DeepEqual(res.Outputs, orig.Outputs) {
+		t.Errorf("Outputs differ: original=%v optimized=%v", orig.Outputs, res.Outputs)
+	}
+
+	if res.Steps >= orig.Steps {
+		t.Errorf("Optimized program is not faster: original=%d optimized=%d", orig.Steps, res.Steps)
+	}
+}
+
+func TestIntcodeIRLowering(t *testing.T) {
+	// Reads its own first instruction, the cell needs to stay unchanged
+	code, _ := ParseIntcode("1101,2,3,7,4,0,99,0")
+	ir := lowerIntcode(code)
+
+	if exp := "block 0-6:\n  v1 = add 2, 3 -> [7]\n  out [0]\n  hlt\n"; ir.String() != exp {
+		t.Errorf("Unexpected representation:\n%s", ir)
+	}
+
+	// Patches its exit directive
+	code, _ = ParseIntcode("1101,1,1,4,99")
+	if ir = lowerIntcode(code); !ir.Unknown {
+		t.Errorf("Patched opcode was not detected")
+	}
+
+	if opt, _ := optimizeIntcode(code); !reflect.DeepEqual(opt, code) {
+		t.Errorf("Program executing unknown code was changed: %v", opt)
+	}
+}
+
+func TestIntcodeIRPatchedOperand(t *testing.T) {
+	var code = make([]int64, 51)
+	copy(code, []int64{
+		1101, 5, 0, 50, //  0: dead store
+		1101, 6, 0, 50, //  4
+		1101, 40, 0, 13, //  8: patches the operand of the output
+		4, 0, // 12: out [[13]]
+		99, // 14
+	})
+	code[40] = 77
+
+	ir := lowerIntcode(code)
+	if exp := "block 0-14:\n  v1 = add 5, 0 -> [50]\n  v2 = add 6, 0 -> [50]\n  v3 = add 40, 0 -> [13]\n  out [*0]\n  hlt\n"; ir.String() != exp {
+		t.Errorf("Unexpected representation:\n%s", ir)
+	}
+
+	opt, stats := optimizeIntcode(code)
+	if stats.DeadStores != 1 || stats.Optimized != 4 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	res, err := RunIntcode(IntcodeParams{Code: opt})
+	if err != nil {
+		t.Fatalf("Optimized program failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(res.Outputs, []int64{77}) || res.Memory[50] != 6 {
+		t.Errorf("Unexpected result: outputs=%v [50]=%d", res.Outputs, res.Memory[50])
+	}
+}
+
+var errIntcodeIRDriverDone = errors.New("Driver finished")
+
+// intcodeIRDrivers run the inputs of the days to compare the results
+// and the number of instructions executed of the original and the
+// optimized program
+var intcodeIRDrivers = map[string]func(t *testing.T, code []int64) (interface{}, int64){
+	"02": func(t *testing.T, code []int64) (interface{}, int64) {
+		code = cloneIntcode(code)
+		code[1], code[2] = 12, 2
+
+		res := runIntcodeIRDriver(t, IntcodeParams{Code: code})
+		return res.Memory[0], res.Steps
+	},
+
+	"05": func(t *testing.T, code []int64) (interface{}, int64) {
+		return runIntcodeIRInputs(t, code, []int64{1}, []int64{5})
+	},
+
+	"07": func(t *testing.T, code []int64) (interface{}, int64) {
+		var (
+			signals []int64
+			steps   int64
+		)
+
+		for _, seq := range [][]int64{{0, 1, 2, 3, 4}, {4, 3, 2, 1, 0}, {1, 0, 4, 3, 2}} {
+			var signal int64
+			for _, phase := range seq {
+				res := runIntcodeIRDriver(t, IntcodeParams{Code: code, Inputs: []int64{phase, signal}})
+				signal = res.Outputs[0]
+				steps += res.Steps
+			}
+			signals = append(signals, signal)
+		}
+
+		return signals, steps
+	},
+
+	"09": func(t *testing.T, code []int64) (interface{}, int64) {
+		return runIntcodeIRInputs(t, code, []int64{1}, []int64{2})
+	},
+
+	"11": func(t *testing.T, code []int64) (interface{}, int64) {
+		var (
+			dir     = DirUp
+			painted = map[Point]int64{}
+			pos     Point
+			turn    bool
+		)
+
+		res := runIntcodeIRDriver(t, IntcodeParams{
+			Code: code,
+			In:   func() (int64, error) { return painted[pos], nil },
+			Out: func(v int64) error {
+				if !turn {
+					painted[pos] = v
+				} else {
+					if v == 0 {
+						dir = dir.TurnLeft()
+					} else {
+						dir = dir.TurnRight()
+					}
+					pos = pos.Move(dir)
+				}
+				turn = !turn
+				return nil
+			},
+		})
+
+		return painted, res.Steps
+	},
+
+	"13": func(t *testing.T, code []int64) (interface{}, int64) {
+		part1 := runIntcodeIRDriver(t, IntcodeParams{Code: code})
+
+		var (
+			ball, paddle, score int64
+			frame               []int64
+		)
+
+		code = cloneIntcode(code)
+		code[0] = 2
+
+		part2 := runIntcodeIRDriver(t, IntcodeParams{
+			Code: code,
+			In: func() (int64, error) {
+				switch {
+				case ball < paddle:
+					return -1, nil
+				case ball > paddle:
+					return 1, nil
+				}
+				return 0, nil
+			},
+			Out: func(v int64) error {
+				if frame = append(frame, v); len(frame) < 3 {
+					return nil
+				}
+
+				switch {
+				case frame[0] == -1:
+					score = frame[2]
+				case frame[2] == 3:
+					paddle = frame[0]
+				case frame[2] == 4:
+					ball = frame[0]
+				}
+				frame = frame[:0]
+				return nil
+			},
+		})
+
+		return []interface{}{part1.Outputs, score}, part1.Steps + part2.Steps
+	},
+
+	"15": func(t *testing.T, code []int64) (interface{}, int64) {
+		var (
+			commands = map[Direction]int64{DirUp: 1, DirDown: 2, DirLeft: 3, DirRight: 4}
+			dir      = DirUp
+			moves    int
+			statuses []int64
+		)
+
+		// Follow the wall on the right hand side
+		res, err := RunIntcode(IntcodeParams{
+			Code: cloneIntcode(code),
+			In: func() (int64, error) {
+				if moves++; moves > 2000 {
+					return 0, errIntcodeIRDriverDone
+				}
+				return commands[dir], nil
+			},
+			Out: func(v int64) error {
+				statuses = append(statuses, v)
+				if v == 0 {
+					dir = dir.TurnLeft()
+				} else {
+					dir = dir.TurnRight()
+				}
+				return nil
+			},
+		})
+		if errors.Cause(err) != errIntcodeIRDriverDone {
+			t.Fatalf("Execution failed: %v", err)
+		}
+
+		return statuses, res.Steps
+	},
+
+	"17": func(t *testing.T, code []int64) (interface{}, int64) {
+		res := runIntcodeIRDriver(t, IntcodeParams{Code: code})
+		return res.Outputs, res.Steps
+	},
+
+	"19": func(t *testing.T, code []int64) (interface{}, int64) {
+		var inputs [][]int64
+		for y := int64(0); y < 10; y++ {
+			for x := int64(0); x < 10; x++ {
+				inputs = append(inputs, []int64{x, y})
+			}
+		}
+		return runIntcodeIRInputs(t, code, inputs...)
+	},
+}
+
+func runIntcodeIRDriver(t *testing.T, params IntcodeParams) IntcodeResult {
+	params.Code = cloneIntcode(params.Code)
+	res, err := RunIntcode(params)
+	if err != nil {
+		t.Fatalf("Execution failed: %s", err)
+	}
+	return res
+}
+
+// runIntcodeIRInputs runs the program once for every set of inputs and
+// returns all outputs
+func runIntcodeIRInputs(t *testing.T, code []int64, inputs ...[]int64) (interface{}, int64) {
+	var (
+		outputs [][]int64
+		steps   int64
+	)
+
+	for _, in := range inputs {
+		res := runIntcodeIRDriver(t, IntcodeParams{Code: code, Inputs: in})
+		outputs = append(outputs, res.Outputs)
+		steps += res.Steps
+	}
+
+	return outputs, steps
+}
+
+func TestIntcodeIRDifferential(t *testing.T) {
+	var (
+		// Programs the optimizer cannot lower, they are emitted unchanged
+		bail = map[string]string{
+			"02": "entry block writes its own code cells",
+			"05": "patches an opcode",
+			"07": "patches the operand of an indirect jump",
+			"11": "patches immediate operands and data is decoded as code",
+			"13": "executes data as code and writes cell 0",
+			"19": "patches jump targets",
+		}
+		// Days the optimization has to speed up
+		faster = map[string]bool{"09": true, "15": true}
+	)
+
+	for day, run := range intcodeIRDrivers {
+		raw, err := ioutil.ReadFile("day" + day + "_input.txt")
+		if err != nil {
+			t.Fatalf("Unable to read input: %s", err)
+		}
+
+		code, err := ParseIntcode(strings.TrimSpace(string(raw)))
+		if err != nil {
+			t.Fatalf("Unable to parse input: %s", err)
+		}
+
+		opt, stats := optimizeIntcode(code)
+
+		if reason, ok := bail[day]; ok {
+			if stats.Instructions > 0 || !reflect.DeepEqual(opt, code) {
+				t.Errorf("Day %s: expected bail (%s) but program was lowered, remove it from the list", day, reason)
+			}
+			continue
+		}
+
+		if stats.Instructions == 0 {
+			t.Errorf("Day %s: program was not lowered", day)
+			continue
+		}
+
+		exp, expSteps := run(t, code)
+		got, gotSteps := run(t, opt)
+		t.Logf("Day %s: %+v, steps %d -> %d", day, stats, expSteps, gotSteps)
+
+		if !reflect.DeepEqual(exp, got) {
+			t.Errorf("Day %s: optimized program yields different results", day)
+		}
+
+		if gotSteps > expSteps {
+			t.Errorf("Day %s: optimized program executes more instructions: %d > %d", day, gotSteps, expSteps)
+		}
+
+		if faster[day] && (stats.Optimized >= stats.Instructions || gotSteps >= expSteps) {
+			t.Errorf("Day %s: program was not optimized", day)
+		}
+	}
+}
@@ -0,0 +1,566 @@
+package aoc2019
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// ICL is a small C-like language compiled to Intcode. Programs consist
+// of global variables and functions, execution starts in main():
+//
+//	var primes[100];
+//
+//	func square(n) {
+//		return n * n;
+//	}
+//
+//	func main() {
+//		var i = input();
+//		while (i > 0) {
+//			output(square(i));
+//			i = i - 1;
+//		}
+//	}
+//
+// All values are integers. Globals can be arrays of fixed size, local
+// variables and parameters are scalars living in the stack frame of the
+// function. Operators are + - * < <= > >= == != && || ! and unary -,
+// the builtins input() and output(value) read and write one value.
+// Functions return 0 if not returning a value explicitly.
+
+type intcodeLangTokenKind int
+
+const (
+	intcodeLangEOF intcodeLangTokenKind = iota
+	intcodeLangIdent
+	intcodeLangNumber
+	intcodeLangSymbol
+)
+
+type intcodeLangToken struct {
+	Kind  intcodeLangTokenKind
+	Text  string
+	Value int64
+	Line  int
+}
+
+var intcodeLangKeywords = map[string]bool{
+	"break": true, "continue": true, "else": true, "func": true,
+	"if": true, "return": true, "var": true, "while": true,
+}
+
+// tokenizeIntcodeLang splits the source into tokens, "//" starts a
+// comment until the end of the line
+func tokenizeIntcodeLang(src string) ([]intcodeLangToken, error) {
+	var (
+		line   = 1
+		tokens []intcodeLangToken
+		runes  = []rune(src)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == '\n':
+			line++
+			i++
+
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			v, err := strconv.ParseInt(string(runes[start:i]), 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("Line %d: invalid number %q", line, string(runes[start:i]))
+			}
+			tokens = append(tokens, intcodeLangToken{Kind: intcodeLangNumber, Text: string(runes[start:i]), Value: v, Line: line})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, intcodeLangToken{Kind: intcodeLangIdent, Text: string(runes[start:i]), Line: line})
+
+		default:
+			var sym string
+			for _, s := range []string{"==", "!=", "<=", ">=", "&&", "||"} {
+				if strings.HasPrefix(string(runes[i:]), s) {
+					sym = s
+					break
+				}
+			}
+			if sym == "" {
+				if !strings.ContainsRune("(){}[],;=<>+-*!", r) {
+					return nil, errors.Errorf("Line %d: unexpected character %q", line, r)
+				}
+				sym = string(r)
+			}
+			tokens = append(tokens, intcodeLangToken{Kind: intcodeLangSymbol, Text: sym, Line: line})
+			i += len([]rune(sym))
+		}
+	}
+
+	return append(tokens, intcodeLangToken{Kind: intcodeLangEOF, Line: line}), nil
+}
+
+type intcodeLangExprKind int
+
+const (
+	intcodeLangNum intcodeLangExprKind = iota
+	intcodeLangVar
+	intcodeLangIndex
+	intcodeLangCall
+	intcodeLangUnary
+	intcodeLangBinary
+)
+
+type intcodeLangExpr struct {
+	Kind intcodeLangExprKind
+	// Operator, variable or function name
+	Name  string
+	Value int64
+	// Operands, index or call arguments
+	Args []*intcodeLangExpr
+	Line int
+}
+
+type intcodeLangStmtKind int
+
+const (
+	intcodeLangDecl intcodeLangStmtKind = iota
+	intcodeLangAssign
+	intcodeLangIf
+	intcodeLangWhile
+	intcodeLangReturn
+	intcodeLangBreak
+	intcodeLangContinue
+	intcodeLangExprStmt
+)
+
+type intcodeLangStmt struct {
+	Kind intcodeLangStmtKind
+	// Declared or assigned variable
+	Name string
+	// Index of the assigned array element
+	Index *intcodeLangExpr
+	// Assigned value, condition, returned value or expression
+	Value      *intcodeLangExpr
+	Body, Else []*intcodeLangStmt
+	Line       int
+}
+
+type intcodeLangGlobal struct {
+	Name string
+	// Number of elements for arrays, 0 for scalars
+	Size int64
+	Init int64
+	Line int
+}
+
+type intcodeLangFunc struct {
+	Name   string
+	Params []string
+	Body   []*intcodeLangStmt
+	Line   int
+}
+
+type intcodeLangProgram struct {
+	Globals []intcodeLangGlobal
+	Funcs   []*intcodeLangFunc
+}
+
+type intcodeLangParser struct {
+	tokens []intcodeLangToken
+	pos    int
+}
+
+// parseIntcodeLang builds the syntax tree of a program
+func parseIntcodeLang(src string) (*intcodeLangProgram, error) {
+	tokens, err := tokenizeIntcodeLang(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		p    = &intcodeLangParser{tokens: tokens}
+		prog = &intcodeLangProgram{}
+	)
+
+	for p.peek().Kind != intcodeLangEOF {
+		switch {
+		case p.accept("var"):
+			g, err := p.global()
+			if err != nil {
+				return nil, err
+			}
+			prog.Globals = append(prog.Globals, g)
+
+		case p.accept("func"):
+			f, err := p.function()
+			if err != nil {
+				return nil, err
+			}
+			prog.Funcs = append(prog.Funcs, f)
+
+		default:
+			return nil, p.errorf("expected \"var\" or \"func\", found %s", p.peek().describe())
+		}
+	}
+
+	return prog, nil
+}
+
+func (t intcodeLangToken) describe() string {
+	if t.Kind == intcodeLangEOF {
+		return "end of file"
+	}
+	return strconv.Quote(t.Text)
+}
+
+func (p *intcodeLangParser) peek() intcodeLangToken { return p.tokens[p.pos] }
+
+func (p *intcodeLangParser) next() intcodeLangToken {
+	t := p.tokens[p.pos]
+	if t.Kind != intcodeLangEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given symbol or keyword
+func (p *intcodeLangParser) accept(text string) bool {
+	if t := p.peek(); t.Kind != intcodeLangNumber && t.Kind != intcodeLangEOF && t.Text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *intcodeLangParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected %q, found %s", text, p.peek().describe())
+	}
+	return nil
+}
+
+func (p *intcodeLangParser) ident() (string, error) {
+	t := p.peek()
+	if t.Kind != intcodeLangIdent || intcodeLangKeywords[t.Text] {
+		return "", p.errorf("expected name, found %s", t.describe())
+	}
+	p.pos++
+	return t.Text, nil
+}
+
+func (p *intcodeLangParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("Line %d: "+format, append([]interface{}{p.peek().Line}, args...)...)
+}
+
+// number reads an optionally negative integer literal
+func (p *intcodeLangParser) number() (int64, error) {
+	var sign int64 = 1
+	if p.accept("-") {
+		sign = -1
+	}
+
+	t := p.peek()
+	if t.Kind != intcodeLangNumber {
+		return 0, p.errorf("expected number, found %s", t.describe())
+	}
+	p.pos++
+	return sign * t.Value, nil
+}
+
+func (p *intcodeLangParser) global() (intcodeLangGlobal, error) {
+	g := intcodeLangGlobal{Line: p.peek().Line}
+
+	var err error
+	if g.Name, err = p.ident(); err != nil {
+		return g, err
+	}
+
+	if p.accept("[") {
+		if g.Size, err = p.number(); err != nil {
+			return g, err
+		}
+		if g.Size < 1 {
+			return g, errors.Errorf("Line %d: array %q needs at least one element", g.Line, g.Name)
+		}
+		if err = p.expect("]"); err != nil {
+			return g, err
+		}
+	} else if p.accept("=") {
+		if g.Init, err = p.number(); err != nil {
+			return g, err
+		}
+	}
+
+	return g, p.expect(";")
+}
+
+func (p *intcodeLangParser) function() (*intcodeLangFunc, error) {
+	f := &intcodeLangFunc{Line: p.peek().Line}
+
+	var err error
+	if f.Name, err = p.ident(); err != nil {
+		return nil, err
+	}
+
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.accept(")") {
+		if len(f.Params) > 0 {
+			if err = p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		f.Params = append(f.Params, name)
+	}
+
+	f.Body, err = p.block()
+	return f, err
+}
+
+func (p *intcodeLangParser) block() ([]*intcodeLangStmt, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var out []*intcodeLangStmt
+	for !p.accept("}") {
+		if p.peek().Kind == intcodeLangEOF {
+			return nil, p.errorf("expected \"}\", found end of file")
+		}
+
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (p *intcodeLangParser) statement() (*intcodeLangStmt, error) {
+	var (
+		s   = &intcodeLangStmt{Line: p.peek().Line}
+		err error
+	)
+
+	switch {
+	case p.accept("var"):
+		s.Kind = intcodeLangDecl
+		if s.Name, err = p.ident(); err != nil {
+			return nil, err
+		}
+		if p.accept("=") {
+			if s.Value, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+
+	case p.accept("if"):
+		s.Kind = intcodeLangIf
+		if s.Value, err = p.condition(); err != nil {
+			return nil, err
+		}
+		if s.Body, err = p.block(); err != nil {
+			return nil, err
+		}
+		if p.accept("else") {
+			if p.peek().Text == "if" {
+				elseIf, err := p.statement()
+				if err != nil {
+					return nil, err
+				}
+				s.Else = []*intcodeLangStmt{elseIf}
+			} else if s.Else, err = p.block(); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+
+	case p.accept("while"):
+		s.Kind = intcodeLangWhile
+		if s.Value, err = p.condition(); err != nil {
+			return nil, err
+		}
+		s.Body, err = p.block()
+		return s, err
+
+	case p.accept("return"):
+		s.Kind = intcodeLangReturn
+		if p.peek().Text != ";" {
+			if s.Value, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+
+	case p.accept("break"):
+		s.Kind = intcodeLangBreak
+
+	case p.accept("continue"):
+		s.Kind = intcodeLangContinue
+
+	default:
+		if s.Value, err = p.expr(); err != nil {
+			return nil, err
+		}
+		s.Kind = intcodeLangExprStmt
+
+		if p.accept("=") {
+			target := s.Value
+			switch {
+			case target.Kind == intcodeLangVar:
+			case target.Kind == intcodeLangIndex:
+				s.Index = target.Args[0]
+			default:
+				return nil, errors.Errorf("Line %d: cannot assign to expression", s.Line)
+			}
+
+			s.Kind, s.Name = intcodeLangAssign, target.Name
+			if s.Value, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, p.expect(";")
+}
+
+func (p *intcodeLangParser) condition() (*intcodeLangExpr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return e, p.expect(")")
+}
+
+// Binary operators by increasing precedence
+var intcodeLangPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*"},
+}
+
+func (p *intcodeLangParser) expr() (*intcodeLangExpr, error) { return p.binary(0) }
+
+func (p *intcodeLangParser) binary(level int) (*intcodeLangExpr, error) {
+	if level == len(intcodeLangPrecedence) {
+		return p.unary()
+	}
+
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var (
+			t     = p.peek()
+			found bool
+		)
+		for _, op := range intcodeLangPrecedence[level] {
+			found = found || (t.Kind == intcodeLangSymbol && t.Text == op)
+		}
+		if !found {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &intcodeLangExpr{Kind: intcodeLangBinary, Name: t.Text, Args: []*intcodeLangExpr{left, right}, Line: t.Line}
+	}
+}
+
+func (p *intcodeLangParser) unary() (*intcodeLangExpr, error) {
+	t := p.peek()
+	if t.Kind == intcodeLangSymbol && (t.Text == "-" || t.Text == "!") {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if t.Text == "-" && operand.Kind == intcodeLangNum {
+			operand.Value = -operand.Value
+			return operand, nil
+		}
+		return &intcodeLangExpr{Kind: intcodeLangUnary, Name: t.Text, Args: []*intcodeLangExpr{operand}, Line: t.Line}, nil
+	}
+	return p.primary()
+}
+
+func (p *intcodeLangParser) primary() (*intcodeLangExpr, error) {
+	t := p.next()
+
+	switch {
+	case t.Kind == intcodeLangNumber:
+		return &intcodeLangExpr{Kind: intcodeLangNum, Value: t.Value, Line: t.Line}, nil
+
+	case t.Kind == intcodeLangSymbol && t.Text == "(":
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+
+	case t.Kind == intcodeLangIdent && !intcodeLangKeywords[t.Text]:
+		e := &intcodeLangExpr{Kind: intcodeLangVar, Name: t.Text, Line: t.Line}
+
+		switch {
+		case p.accept("["):
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			e.Kind, e.Args = intcodeLangIndex, []*intcodeLangExpr{index}
+			return e, p.expect("]")
+
+		case p.accept("("):
+			e.Kind = intcodeLangCall
+			for !p.accept(")") {
+				if len(e.Args) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				arg, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				e.Args = append(e.Args, arg)
+			}
+		}
+		return e, nil
+	}
+
+	return nil, errors.Errorf("Line %d: expected expression, found %s", t.Line, t.describe())
+}
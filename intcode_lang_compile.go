@@ -0,0 +1,671 @@
+package aoc2019
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Code generation for ICL (see intcode_lang.go). The program starts by
+// moving the relative base behind the global variables and calling
+// main(). Functions use the calling convention of the puzzle programs:
+// the caller stores the return address into [rb+0] and the arguments
+// into [rb+1...] and jumps to the function. The function allocates its
+// frame (return address, parameters, variables, temporary values)
+// using "arb", stores its result into the slot of the first argument
+// and returns through "jz 0, [rb+0]" after releasing the frame.
+//
+// Array elements with a computed index are accessed by patching the
+// address into the parameter of the following instruction.
+
+type intcodeLangOperand struct {
+	Mode  opCodeFlag
+	Value int64
+	// Value is relative to the address of the label
+	Label string
+	// Value is a slot of the current stack frame, converted into an
+	// offset to the relative base when the frame size is known
+	Slot bool
+}
+
+type intcodeLangFixup struct {
+	Pos   int
+	Label string
+}
+
+type intcodeLangLoop struct {
+	brk, cont string
+}
+
+type intcodeLangCompiler struct {
+	code   []int64
+	labels map[string]int64
+	fixups []intcodeLangFixup
+	nLabel int
+
+	globals map[string]intcodeLangGlobal
+	funcs   map[string]*intcodeLangFunc
+
+	// State of the function being compiled
+	scopes    []map[string]int64
+	slots     int64
+	tempBase  int64
+	temps     int64
+	maxTemps  int64
+	frameRefs []int
+	loops     []intcodeLangLoop
+	ret       string
+}
+
+// CompileIntcode translates an ICL program into an Intcode program
+func CompileIntcode(src string) ([]int64, error) {
+	prog, err := parseIntcodeLang(src)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &intcodeLangCompiler{
+		labels:  map[string]int64{},
+		globals: map[string]intcodeLangGlobal{},
+		funcs:   map[string]*intcodeLangFunc{},
+	}
+
+	for _, g := range prog.Globals {
+		if err := c.declare(g.Name, g.Line); err != nil {
+			return nil, err
+		}
+		c.globals[g.Name] = g
+	}
+
+	for _, f := range prog.Funcs {
+		if err := c.declare(f.Name, f.Line); err != nil {
+			return nil, err
+		}
+		c.funcs[f.Name] = f
+	}
+
+	if main, ok := c.funcs["main"]; !ok || len(main.Params) > 0 {
+		return nil, errors.New("Program needs a function main() without parameters")
+	}
+
+	c.emit(opCodeTypeAdjRelBase, intcodeLangAddr("stack"))
+	c.mov(intcodeLangAddr(".halt"), intcodeLangRel(0))
+	c.jump("func:main")
+	c.label(".halt")
+	c.emit(opCodeTypeExit)
+
+	for _, f := range prog.Funcs {
+		if err := c.function(f); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, g := range prog.Globals {
+		c.label("global:" + g.Name)
+		if g.Size == 0 {
+			c.code = append(c.code, g.Init)
+			continue
+		}
+		c.code = append(c.code, make([]int64, g.Size)...)
+	}
+	c.label("stack")
+
+	for _, f := range c.fixups {
+		c.code[f.Pos] += c.labels[f.Label]
+	}
+
+	return c.code, nil
+}
+
+func (c *intcodeLangCompiler) declare(name string, line int) error {
+	if name == "input" || name == "output" {
+		return errors.Errorf("Line %d: %q is a builtin function", line, name)
+	}
+	if _, ok := c.globals[name]; ok {
+		return errors.Errorf("Line %d: %q is already declared", line, name)
+	}
+	if _, ok := c.funcs[name]; ok {
+		return errors.Errorf("Line %d: %q is already declared", line, name)
+	}
+	return nil
+}
+
+func intcodeLangImm(v int64) intcodeLangOperand {
+	return intcodeLangOperand{Mode: opCodeFlagImmediate, Value: v}
+}
+
+func intcodeLangAddr(label string) intcodeLangOperand {
+	return intcodeLangOperand{Mode: opCodeFlagImmediate, Label: label}
+}
+
+func intcodeLangMem(label string, offset int64) intcodeLangOperand {
+	return intcodeLangOperand{Mode: opCodeFlagPosition, Label: label, Value: offset}
+}
+
+func intcodeLangRel(offset int64) intcodeLangOperand {
+	return intcodeLangOperand{Mode: opCodeFlagRelative, Value: offset}
+}
+
+func intcodeLangSlot(slot int64) intcodeLangOperand {
+	return intcodeLangOperand{Mode: opCodeFlagRelative, Value: slot, Slot: true}
+}
+
+// constant reports whether the operand is a known value
+func (o intcodeLangOperand) constant() bool {
+	return o.Mode == opCodeFlagImmediate && o.Label == ""
+}
+
+// emit appends an instruction and returns its address
+func (c *intcodeLangCompiler) emit(op opCodeType, ops ...intcodeLangOperand) int {
+	var (
+		pos    = len(c.code)
+		inst   = int64(op)
+		factor = int64(100)
+	)
+
+	c.code = append(c.code, 0)
+	for _, o := range ops {
+		inst += int64(o.Mode) * factor
+		factor *= 10
+
+		if o.Label != "" {
+			c.fixups = append(c.fixups, intcodeLangFixup{Pos: len(c.code), Label: o.Label})
+		}
+		if o.Slot {
+			c.frameRefs = append(c.frameRefs, len(c.code))
+		}
+		c.code = append(c.code, o.Value)
+	}
+	c.code[pos] = inst
+
+	return pos
+}
+
+func (c *intcodeLangCompiler) mov(src, dst intcodeLangOperand) {
+	c.emit(opCodeTypeAddition, src, intcodeLangImm(0), dst)
+}
+
+func (c *intcodeLangCompiler) jump(label string) {
+	c.emit(opCodeTypeJumpIfTrue, intcodeLangImm(1), intcodeLangAddr(label))
+}
+
+func (c *intcodeLangCompiler) label(name string) { c.labels[name] = int64(len(c.code)) }
+
+func (c *intcodeLangCompiler) newLabel() string {
+	c.nLabel++
+	return fmt.Sprintf(".L%d", c.nLabel)
+}
+
+func (c *intcodeLangCompiler) temp() intcodeLangOperand {
+	t := intcodeLangSlot(c.tempBase + c.temps)
+	c.temps++
+	if c.temps > c.maxTemps {
+		c.maxTemps = c.temps
+	}
+	return t
+}
+
+// countIntcodeLangDecls returns the number of variables declared in
+// the statements including nested blocks
+func countIntcodeLangDecls(stmts []*intcodeLangStmt) int64 {
+	var n int64
+	for _, s := range stmts {
+		if s.Kind == intcodeLangDecl {
+			n++
+		}
+		n += countIntcodeLangDecls(s.Body) + countIntcodeLangDecls(s.Else)
+	}
+	return n
+}
+
+func (c *intcodeLangCompiler) function(f *intcodeLangFunc) error {
+	params := map[string]int64{}
+	for i, p := range f.Params {
+		if _, ok := params[p]; ok {
+			return errors.Errorf("Line %d: duplicate parameter %q", f.Line, p)
+		}
+		params[p] = int64(i) + 1
+	}
+
+	c.scopes = []map[string]int64{params}
+	c.slots = int64(len(f.Params)) + 1
+	c.tempBase = c.slots + countIntcodeLangDecls(f.Body)
+	c.temps, c.maxTemps = 0, 0
+	c.frameRefs = nil
+	c.ret = c.newLabel()
+
+	c.label("func:" + f.Name)
+	prologue := c.emit(opCodeTypeAdjRelBase, intcodeLangImm(0))
+
+	if err := c.block(f.Body); err != nil {
+		return err
+	}
+
+	// Functions without return statement return 0
+	c.mov(intcodeLangImm(0), intcodeLangSlot(1))
+	c.label(c.ret)
+	epilogue := c.emit(opCodeTypeAdjRelBase, intcodeLangImm(0))
+	c.emit(opCodeTypeJumpIfFalse, intcodeLangImm(0), intcodeLangRel(0))
+
+	size := c.tempBase + c.maxTemps
+	c.code[prologue+1], c.code[epilogue+1] = size, -size
+	for _, pos := range c.frameRefs {
+		c.code[pos] -= size
+	}
+
+	return nil
+}
+
+func (c *intcodeLangCompiler) block(stmts []*intcodeLangStmt) error {
+	c.scopes = append(c.scopes, map[string]int64{})
+	defer func() { c.scopes = c.scopes[:len(c.scopes)-1] }()
+
+	for _, s := range stmts {
+		if err := c.statement(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *intcodeLangCompiler) statement(s *intcodeLangStmt) error {
+	// Temporary values only live within a statement
+	c.temps = 0
+
+	switch s.Kind {
+	case intcodeLangDecl:
+		scope := c.scopes[len(c.scopes)-1]
+		if _, ok := scope[s.Name]; ok {
+			return errors.Errorf("Line %d: %q is already declared", s.Line, s.Name)
+		}
+
+		v := intcodeLangImm(0)
+		if s.Value != nil {
+			var err error
+			if v, err = c.expr(s.Value); err != nil {
+				return err
+			}
+		}
+
+		scope[s.Name] = c.slots
+		c.mov(v, intcodeLangSlot(c.slots))
+		c.slots++
+
+	case intcodeLangAssign:
+		return c.assign(s)
+
+	case intcodeLangIf:
+		var (
+			els = c.newLabel()
+			end = els
+		)
+		if err := c.branch(s.Value, els, false); err != nil {
+			return err
+		}
+		if err := c.block(s.Body); err != nil {
+			return err
+		}
+		if len(s.Else) > 0 {
+			end = c.newLabel()
+			c.jump(end)
+			c.label(els)
+			if err := c.block(s.Else); err != nil {
+				return err
+			}
+		}
+		c.label(end)
+
+	case intcodeLangWhile:
+		loop := intcodeLangLoop{brk: c.newLabel(), cont: c.newLabel()}
+		c.label(loop.cont)
+		if err := c.branch(s.Value, loop.brk, false); err != nil {
+			return err
+		}
+
+		c.loops = append(c.loops, loop)
+		err := c.block(s.Body)
+		c.loops = c.loops[:len(c.loops)-1]
+		if err != nil {
+			return err
+		}
+
+		c.jump(loop.cont)
+		c.label(loop.brk)
+
+	case intcodeLangReturn:
+		v := intcodeLangImm(0)
+		if s.Value != nil {
+			var err error
+			if v, err = c.expr(s.Value); err != nil {
+				return err
+			}
+		}
+		c.mov(v, intcodeLangSlot(1))
+		c.jump(c.ret)
+
+	case intcodeLangBreak, intcodeLangContinue:
+		if len(c.loops) == 0 {
+			return errors.Errorf("Line %d: break or continue outside of a loop", s.Line)
+		}
+		loop := c.loops[len(c.loops)-1]
+		if s.Kind == intcodeLangBreak {
+			c.jump(loop.brk)
+		} else {
+			c.jump(loop.cont)
+		}
+
+	case intcodeLangExprStmt:
+		if s.Value.Kind != intcodeLangCall {
+			return errors.Errorf("Line %d: result of expression is not used", s.Line)
+		}
+		_, err := c.expr(s.Value)
+		return err
+	}
+
+	return nil
+}
+
+func (c *intcodeLangCompiler) assign(s *intcodeLangStmt) error {
+	v, err := c.expr(s.Value)
+	if err != nil {
+		return err
+	}
+
+	if s.Index == nil {
+		dst, err := c.variable(s.Name, s.Line)
+		if err != nil {
+			return err
+		}
+		c.mov(v, dst)
+		return nil
+	}
+
+	dst, err := c.element(s.Name, s.Index, s.Line, 3)
+	if err != nil {
+		return err
+	}
+	c.mov(v, dst)
+	return nil
+}
+
+// variable returns the location of a scalar variable
+func (c *intcodeLangCompiler) variable(name string, line int) (intcodeLangOperand, error) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if slot, ok := c.scopes[i][name]; ok {
+			return intcodeLangSlot(slot), nil
+		}
+	}
+
+	g, ok := c.globals[name]
+	switch {
+	case !ok:
+		return intcodeLangOperand{}, errors.Errorf("Line %d: unknown variable %q", line, name)
+	case g.Size > 0:
+		return intcodeLangOperand{}, errors.Errorf("Line %d: array %q needs an index", line, name)
+	}
+	return intcodeLangMem("global:"+name, 0), nil
+}
+
+// element returns the location of an array element for the parameter
+// of the next instruction emitted, computed indexes are patched into
+// that parameter
+func (c *intcodeLangCompiler) element(name string, index *intcodeLangExpr, line int, param int64) (intcodeLangOperand, error) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if _, ok := c.scopes[i][name]; ok {
+			return intcodeLangOperand{}, errors.Errorf("Line %d: %q is not an array", line, name)
+		}
+	}
+
+	g, ok := c.globals[name]
+	switch {
+	case !ok:
+		return intcodeLangOperand{}, errors.Errorf("Line %d: unknown array %q", line, name)
+	case g.Size == 0:
+		return intcodeLangOperand{}, errors.Errorf("Line %d: %q is not an array", line, name)
+	}
+
+	i, err := c.expr(index)
+	if err != nil {
+		return intcodeLangOperand{}, err
+	}
+
+	if i.constant() {
+		if i.Value < 0 || i.Value >= g.Size {
+			return intcodeLangOperand{}, errors.Errorf("Line %d: index %d out of range for %q", line, i.Value, name)
+		}
+		return intcodeLangMem("global:"+name, i.Value), nil
+	}
+
+	next := c.newLabel()
+	c.emit(opCodeTypeAddition, intcodeLangAddr("global:"+name), i, intcodeLangMem(next, param))
+	c.label(next)
+	return intcodeLangOperand{Mode: opCodeFlagPosition}, nil
+}
+
+// expr emits the calculation of the expression and returns the operand
+// containing its value
+func (c *intcodeLangCompiler) expr(e *intcodeLangExpr) (intcodeLangOperand, error) {
+	switch e.Kind {
+	case intcodeLangNum:
+		return intcodeLangImm(e.Value), nil
+
+	case intcodeLangVar:
+		return c.variable(e.Name, e.Line)
+
+	case intcodeLangIndex:
+		src, err := c.element(e.Name, e.Args[0], e.Line, 1)
+		if err != nil {
+			return src, err
+		}
+		t := c.temp()
+		c.mov(src, t)
+		return t, nil
+
+	case intcodeLangCall:
+		return c.call(e)
+
+	case intcodeLangUnary:
+		v, err := c.expr(e.Args[0])
+		if err != nil {
+			return v, err
+		}
+
+		if e.Name == "-" {
+			return c.arith(opCodeTypeMultiplication, v, intcodeLangImm(-1)), nil
+		}
+		return c.arith(opCodeTypeEquals, v, intcodeLangImm(0)), nil
+	}
+
+	switch e.Name {
+	case "&&", "||":
+		var (
+			t   = c.temp()
+			end = c.newLabel()
+		)
+		c.mov(intcodeLangImm(0), t)
+		if err := c.branch(e, end, false); err != nil {
+			return t, err
+		}
+		c.mov(intcodeLangImm(1), t)
+		c.label(end)
+		return t, nil
+
+	case "+", "-", "*":
+		a, err := c.expr(e.Args[0])
+		if err != nil {
+			return a, err
+		}
+		b, err := c.expr(e.Args[1])
+		if err != nil {
+			return b, err
+		}
+
+		switch e.Name {
+		case "-":
+			return c.arith(opCodeTypeAddition, a, c.arith(opCodeTypeMultiplication, b, intcodeLangImm(-1))), nil
+		case "*":
+			return c.arith(opCodeTypeMultiplication, a, b), nil
+		}
+		return c.arith(opCodeTypeAddition, a, b), nil
+	}
+
+	v, negate, err := c.compare(e)
+	if err != nil || !negate {
+		return v, err
+	}
+	return c.arith(opCodeTypeEquals, v, intcodeLangImm(0)), nil
+}
+
+// arith emits the operation into a new temporary value, operations on
+// constants are calculated directly
+func (c *intcodeLangCompiler) arith(op opCodeType, a, b intcodeLangOperand) intcodeLangOperand {
+	if a.constant() && b.constant() {
+		v, _ := foldIntcodeOperation(&intcodeIRInst{Op: op, Args: []intcodeIROperand{
+			{Kind: intcodeIRConst, Value: a.Value},
+			{Kind: intcodeIRConst, Value: b.Value},
+		}})
+		return intcodeLangImm(v)
+	}
+
+	t := c.temp()
+	c.emit(op, a, b, t)
+	return t
+}
+
+// compare emits a comparison and returns its result and whether the
+// result needs to be negated
+func (c *intcodeLangCompiler) compare(e *intcodeLangExpr) (intcodeLangOperand, bool, error) {
+	a, err := c.expr(e.Args[0])
+	if err != nil {
+		return a, false, err
+	}
+	b, err := c.expr(e.Args[1])
+	if err != nil {
+		return b, false, err
+	}
+
+	switch e.Name {
+	case "<":
+		return c.arith(opCodeTypeLessThan, a, b), false, nil
+	case ">":
+		return c.arith(opCodeTypeLessThan, b, a), false, nil
+	case "<=":
+		return c.arith(opCodeTypeLessThan, b, a), true, nil
+	case ">=":
+		return c.arith(opCodeTypeLessThan, a, b), true, nil
+	case "==":
+		return c.arith(opCodeTypeEquals, a, b), false, nil
+	}
+	return c.arith(opCodeTypeEquals, a, b), true, nil
+}
+
+// branch emits a jump to the label taken if the truth of the
+// expression matches when
+func (c *intcodeLangCompiler) branch(e *intcodeLangExpr, label string, when bool) error {
+	switch {
+	case e.Kind == intcodeLangUnary && e.Name == "!":
+		return c.branch(e.Args[0], label, !when)
+
+	case e.Kind == intcodeLangBinary && (e.Name == "&&" || e.Name == "||"):
+		// Both operands jump for false (&&) or true (||), otherwise
+		// the second operand decides
+		if when == (e.Name == "||") {
+			if err := c.branch(e.Args[0], label, when); err != nil {
+				return err
+			}
+			return c.branch(e.Args[1], label, when)
+		}
+
+		skip := c.newLabel()
+		if err := c.branch(e.Args[0], skip, !when); err != nil {
+			return err
+		}
+		if err := c.branch(e.Args[1], label, when); err != nil {
+			return err
+		}
+		c.label(skip)
+		return nil
+	}
+
+	var (
+		v      intcodeLangOperand
+		negate bool
+		err    error
+	)
+	if e.Kind == intcodeLangBinary && strings.ContainsAny(e.Name, "<>=!") {
+		v, negate, err = c.compare(e)
+	} else {
+		v, err = c.expr(e)
+	}
+	if err != nil {
+		return err
+	}
+
+	if when != negate {
+		if !v.constant() || v.Value != 0 {
+			c.emit(opCodeTypeJumpIfTrue, v, intcodeLangAddr(label))
+		}
+		return nil
+	}
+
+	if !v.constant() || v.Value == 0 {
+		c.emit(opCodeTypeJumpIfFalse, v, intcodeLangAddr(label))
+	}
+	return nil
+}
+
+func (c *intcodeLangCompiler) call(e *intcodeLangExpr) (intcodeLangOperand, error) {
+	switch e.Name {
+	case "input":
+		if len(e.Args) != 0 {
+			return intcodeLangOperand{}, errors.Errorf("Line %d: input() takes no arguments", e.Line)
+		}
+		t := c.temp()
+		c.emit(opCodeTypeInput, t)
+		return t, nil
+
+	case "output":
+		if len(e.Args) != 1 {
+			return intcodeLangOperand{}, errors.Errorf("Line %d: output() takes one argument", e.Line)
+		}
+		v, err := c.expr(e.Args[0])
+		if err != nil {
+			return v, err
+		}
+		c.emit(opCodeTypeOutput, v)
+		return intcodeLangImm(0), nil
+	}
+
+	f, ok := c.funcs[e.Name]
+	switch {
+	case !ok:
+		return intcodeLangOperand{}, errors.Errorf("Line %d: unknown function %q", e.Line, e.Name)
+	case len(f.Params) != len(e.Args):
+		return intcodeLangOperand{}, errors.Errorf("Line %d: %s() takes %d arguments, got %d", e.Line, e.Name, len(f.Params), len(e.Args))
+	}
+
+	// Arguments are calculated before filling the slots of the callee
+	// as they might contain calls themselves
+	var args []intcodeLangOperand
+	for _, a := range e.Args {
+		v, err := c.expr(a)
+		if err != nil {
+			return v, err
+		}
+		args = append(args, v)
+	}
+
+	for i, v := range args {
+		c.mov(v, intcodeLangRel(int64(i)+1))
+	}
+
+	ret := c.newLabel()
+	c.mov(intcodeLangAddr(ret), intcodeLangRel(0))
+	c.jump("func:" + e.Name)
+	c.label(ret)
+
+	t := c.temp()
+	c.mov(intcodeLangRel(1), t)
+	return t, nil
+}
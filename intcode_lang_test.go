@@ -0,0 +1,161 @@
+package aoc2019
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func runIntcodeLang(t *testing.T, src string, inputs []int64) []int64 {
+	code, err := CompileIntcode(src)
+	if err != nil {
+		t.Fatalf("Compilation failed: %s", err)
+	}
+
+	res, err := RunIntcode(IntcodeParams{Code: code, Inputs: inputs, MaxSteps: 10000000})
+	if err != nil {
+		t.Fatalf("Execution failed: %s", err)
+	}
+	return res.Outputs
+}
+
+func TestCompileIntcodeExamples(t *testing.T) {
+	for _, tc := range []struct {
+		File            string
+		Inputs, Outputs []int64
+	}{
+		{"fib.icl", []int64{1}, []int64{1}},
+		{"fib.icl", []int64{20}, []int64{6765}},
+		{"primes.icl", []int64{30}, []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+		{"sort.icl", []int64{6, 5, -2, 9, 0, 5, 1}, []int64{-2, 0, 1, 5, 5, 9}},
+		{"gcd.icl", []int64{12, 18, 35, 64, 7, 7, 0}, []int64{6, 1, 7}},
+	} {
+		src, err := ioutil.ReadFile("examples/" + tc.File)
+		if err != nil {
+			t.Fatalf("Unable to read example: %s", err)
+		}
+
+		if out := runIntcodeLang(t, string(src), tc.Inputs); !reflect.DeepEqual(out, tc.Outputs) {
+			t.Errorf("Unexpected outputs for %s with %v: exp=%v got=%v", tc.File, tc.Inputs, tc.Outputs, out)
+		}
+	}
+}
+
+func TestCompileIntcodeLanguage(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Src             string
+		Inputs, Outputs []int64
+	}{
+		"arithmetic": {
+			Src:     "func main() { var a = input(); output(a + 2 * 3); output(a - 10); output(-a * (a - 1)); output(2 - 3 * 4); }",
+			Inputs:  []int64{5},
+			Outputs: []int64{11, -5, -20, -10},
+		},
+		"comparisons": {
+			Src: `func main() {
+				var a = input(); var b = input();
+				output(a < b); output(a <= b); output(a > b); output(a >= b); output(a == b); output(a != b);
+				output(!a); output(a < b && b < 10); output(a > b || b == 7);
+			}`,
+			Inputs:  []int64{3, 7},
+			Outputs: []int64{1, 1, 0, 0, 0, 1, 0, 1, 1},
+		},
+		"short circuit": {
+			Src: `func side(v) { output(v); return v; }
+			func main() {
+				if (side(0) && side(1)) { output(10); }
+				if (side(2) || side(3)) { output(11); }
+				if (!(side(0) || side(4))) { output(12); } else { output(13); }
+			}`,
+			Outputs: []int64{0, 2, 11, 0, 4, 13},
+		},
+		"control flow": {
+			Src: `func classify(v) {
+				if (v < 0) { return -1; } else if (v == 0) { return 0; } else { return 1; }
+			}
+			func main() {
+				var i = 0 - 2;
+				while (1) {
+					i = i + 1;
+					if (i == 1) { continue; }
+					if (i > 3) { break; }
+					output(classify(i));
+				}
+			}`,
+			Outputs: []int64{-1, 0, 1, 1},
+		},
+		"globals and scopes": {
+			Src: `var counter = 40; var data[3];
+			func bump(by) { counter = counter + by; }
+			func main() {
+				var x = 1;
+				if (x) { var x = 5; output(x); }
+				output(x);
+				bump(2);
+				output(counter);
+				data[0] = 7; data[input()] = 8;
+				output(data[0] + data[2]);
+				output(bump(0));
+			}`,
+			Inputs:  []int64{2},
+			Outputs: []int64{5, 1, 42, 15, 0},
+		},
+		"recursion": {
+			Src: `func fact(n) { if (n <= 1) { return 1; } return n * fact(n - 1); }
+			func ackermann(m, n) {
+				if (m == 0) { return n + 1; }
+				if (n == 0) { return ackermann(m - 1, 1); }
+				return ackermann(m - 1, ackermann(m, n - 1));
+			}
+			func main() { output(fact(15)); output(ackermann(2, 3)); }`,
+			Outputs: []int64{1307674368000, 9},
+		},
+	} {
+		if out := runIntcodeLang(t, tc.Src, tc.Inputs); !reflect.DeepEqual(out, tc.Outputs) {
+			t.Errorf("Unexpected outputs for %q: exp=%v got=%v", name, tc.Outputs, out)
+		}
+	}
+}
+
+func TestCompileIntcodeErrors(t *testing.T) {
+	for src, msg := range map[string]string{
+		"func main() { output(x); }":                `Line 1: unknown variable "x"`,
+		"func main() {\n var a = 1;\n var a = 2; }": `Line 3: "a" is already declared`,
+		"func f(a) {} func main() { f(); }":         `f() takes 1 arguments, got 0`,
+		"func main() { g(); }":                      `unknown function "g"`,
+		"var a[2]; func main() { a[2] = 1; }":       `index 2 out of range for "a"`,
+		"var a[2]; func main() { output(a); }":      `array "a" needs an index`,
+		"func main() { var a; a[0] = 1; }":          `"a" is not an array`,
+		"func main() { break; }":                    `break or continue outside of a loop`,
+		"func main() { 1 + 2; }":                    `result of expression is not used`,
+		"func main() { output(1) }":                 `expected ";"`,
+		"func main() { output(1 $ 2); }":            `unexpected character '$'`,
+		"func start() {}":                           `needs a function main()`,
+		"func input() {} func main() {}":            `"input" is a builtin function`,
+		"func main() { if (1) { output(1); }":       `found end of file`,
+		"func main() { (1) = 2; }":                  `cannot assign to expression`,
+	} {
+		_, err := CompileIntcode(src)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("Unexpected error for %q: exp=%q got=%v", src, msg, err)
+		}
+	}
+}
+
+func TestCompileIntcodeDecompiles(t *testing.T) {
+	src, err := ioutil.ReadFile("examples/fib.icl")
+	if err != nil {
+		t.Fatalf("Unable to read example: %s", err)
+	}
+
+	code, err := CompileIntcode(string(src))
+	if err != nil {
+		t.Fatalf("Compilation failed: %s", err)
+	}
+
+	// Calling convention matches the one recognized by the decompiler
+	if out := DecompileIntcode(code); !strings.Contains(out, "(arg1) {") || !strings.Contains(out, "return") {
+		t.Errorf("Functions were not recognized:\n%s", out)
+	}
+}
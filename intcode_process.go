@@ -0,0 +1,80 @@
+package aoc2019
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// IntcodeProcess is a program running in its own goroutine. Whoever
+// starts a process owns it and must either wait for it to exit or stop
+// it, a stopped process never leaves a goroutine blocked on its
+// channels.
+type IntcodeProcess struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	memory []int64
+	err    error
+}
+
+// StartIntcode runs the program described by the params in the
+// background, see IntcodeParams for the ownership of the channels
+func StartIntcode(params IntcodeParams) *IntcodeProcess {
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
+
+	var (
+		ctx, cancel = context.WithCancel(params.Context)
+		p           = &IntcodeProcess{cancel: cancel, done: make(chan struct{})}
+	)
+	params.Context = ctx
+
+	go func() {
+		defer close(p.done)
+		defer cancel()
+		p.memory, p.err = ExecuteIntcodeWithParams(params)
+	}()
+
+	return p
+}
+
+// Done is closed when the program stopped
+func (i *IntcodeProcess) Done() <-chan struct{} { return i.done }
+
+// Wait blocks until the program stopped and returns its final memory
+func (i *IntcodeProcess) Wait() ([]int64, error) {
+	<-i.done
+	return i.memory, i.err
+}
+
+// Stop aborts the program and waits for it to stop. Errors caused by
+// the abort are not reported, errors of a program which stopped before
+// are.
+func (i *IntcodeProcess) Stop() error {
+	select {
+	case <-i.done:
+		return i.err
+	default:
+	}
+
+	i.cancel()
+	<-i.done
+
+	if errors.Cause(i.err) == context.Canceled {
+		return nil
+	}
+	return i.err
+}
+
+// Send passes an input to the program through the channel, fails
+// instead of blocking when the program stopped
+func (i *IntcodeProcess) Send(in chan int64, v int64) error {
+	select {
+	case in <- v:
+		return nil
+	case <-i.done:
+		return errors.New("Program stopped before reading input")
+	}
+}
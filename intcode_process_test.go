@@ -0,0 +1,71 @@
+package aoc2019
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestIntcodeProcessStop(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	// Echoes every input
+	code, _ := ParseIntcode("3,7,4,7,1105,1,0,0")
+
+	for name, send := range map[string]int{
+		"blocked on input":  0,
+		"blocked on output": 1,
+	} {
+		var (
+			in   = make(chan int64)
+			out  = make(chan int64)
+			proc = StartIntcode(IntcodeParams{Code: cloneIntcode(code), In: in, Out: out})
+		)
+
+		for i := 0; i < send; i++ {
+			if err := proc.Send(in, 42); err != nil {
+				t.Fatalf("Sending input failed for %q: %s", name, err)
+			}
+		}
+
+		if err := proc.Stop(); err != nil {
+			t.Errorf("Stop reported error for %q: %s", name, err)
+		}
+
+		if _, ok := <-out; ok {
+			t.Errorf("Output was not closed for %q", name)
+		}
+
+		if err := proc.Send(in, 1); err == nil {
+			t.Errorf("Send to stopped program succeeded for %q", name)
+		}
+	}
+}
+
+func TestIntcodeProcessInputClosed(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	code, _ := ParseIntcode("3,7,4,7,1105,1,0,0")
+	in := make(chan int64)
+	close(in)
+
+	proc := StartIntcode(IntcodeParams{Code: code, In: in})
+	if _, err := proc.Wait(); errors.Cause(err) != ErrIntcodeInputClosed {
+		t.Errorf("Unexpected error for closed input: %v", err)
+	}
+}
+
+func TestIntcodeProcessParentContext(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	code, _ := ParseIntcode("3,7,4,7,1105,1,0,0")
+	proc := StartIntcode(IntcodeParams{Code: code, Context: ctx, In: make(chan int64)})
+
+	cancel()
+	if _, err := proc.Wait(); errors.Cause(err) != context.Canceled {
+		t.Errorf("Unexpected error for cancelled context: %v", err)
+	}
+}
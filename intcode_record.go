@@ -0,0 +1,193 @@
+package aoc2019
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const intcodeRecordingHeader = "# intcode recording v1"
+
+// IntcodeEvent is a single input consumed or output produced by a
+// machine, Step is the number of the instruction within the run
+// (starting at 1) and IP its address
+type IntcodeEvent struct {
+	Step   int64
+	IP     int64
+	Output bool
+	Value  int64
+}
+
+func (i IntcodeEvent) String() string {
+	var kind = "in"
+	if i.Output {
+		kind = "out"
+	}
+	return fmt.Sprintf("%s %d at step %d (ip %d)", kind, i.Value, i.Step, i.IP)
+}
+
+// IntcodeRecorder writes every event of a machine into a recording,
+// assign Record to the OnIO hook of the machine to record a session
+type IntcodeRecorder struct {
+	w             io.Writer
+	headerWritten bool
+}
+
+// NewIntcodeRecorder creates a recorder writing into w, events are
+// written immediately to keep the recording of crashing sessions
+func NewIntcodeRecorder(w io.Writer) *IntcodeRecorder {
+	return &IntcodeRecorder{w: w}
+}
+
+// Record writes an event into the recording
+func (i *IntcodeRecorder) Record(e IntcodeEvent) error {
+	if !i.headerWritten {
+		if _, err := fmt.Fprintln(i.w, intcodeRecordingHeader); err != nil {
+			return errors.Wrap(err, "Unable to write recording header")
+		}
+		i.headerWritten = true
+	}
+
+	var kind = "in"
+	if e.Output {
+		kind = "out"
+	}
+
+	_, err := fmt.Fprintf(i.w, "%d %d %s %d\n", e.Step, e.IP, kind, e.Value)
+	return errors.Wrap(err, "Unable to record event")
+}
+
+// IsIntcodeRecording checks whether the data starts like a recording
+// written by IntcodeRecorder
+func IsIntcodeRecording(r *bufio.Reader) bool {
+	head, _ := r.Peek(len(intcodeRecordingHeader))
+	return string(head) == intcodeRecordingHeader
+}
+
+// ReadIntcodeRecording parses a recording written by IntcodeRecorder
+func ReadIntcodeRecording(r io.Reader) ([]IntcodeEvent, error) {
+	var (
+		events  []IntcodeEvent
+		lineNo  int
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		if lineNo == 1 {
+			if line != intcodeRecordingHeader {
+				return nil, errors.Errorf("Missing recording header, got %q", line)
+			}
+			continue
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var (
+			e    IntcodeEvent
+			kind string
+		)
+
+		if _, err := fmt.Sscanf(line, "%d %d %s %d", &e.Step, &e.IP, &kind, &e.Value); err != nil {
+			return nil, errors.Wrapf(err, "Invalid event in line %d", lineNo)
+		}
+
+		switch kind {
+		case "in":
+		case "out":
+			e.Output = true
+		default:
+			return nil, errors.Errorf("Invalid event kind %q in line %d", kind, lineNo)
+		}
+
+		events = append(events, e)
+	}
+
+	return events, errors.Wrap(scanner.Err(), "Unable to scan recording")
+}
+
+// IntcodeDivergence describes the first difference between a replayed
+// run and its recording. Expected is nil if the program produced more
+// events than recorded, Got is nil if the program exited early.
+type IntcodeDivergence struct {
+	Index    int
+	Expected *IntcodeEvent
+	Got      *IntcodeEvent
+}
+
+func (i IntcodeDivergence) Error() string {
+	var exp, got = "nothing", "nothing"
+	if i.Expected != nil {
+		exp = i.Expected.String()
+	}
+	if i.Got != nil {
+		got = i.Got.String()
+	}
+	return fmt.Sprintf("Replay diverged at event %d: expected %s, got %s", i.Index, exp, got)
+}
+
+// ReplayIntcode runs the machine feeding the inputs of the recording and
+// compares every input and output including the instruction it
+// happened at. The first difference is returned as IntcodeDivergence.
+// The out callback (optional) receives all outputs like in Run.
+func ReplayIntcode(ctx context.Context, m *IntcodeMachine, events []IntcodeEvent, out func(int64) error) error {
+	var (
+		index int
+		onIO  = m.OnIO
+	)
+
+	if out == nil {
+		out = func(int64) error { return nil }
+	}
+
+	m.OnIO = func(e IntcodeEvent) error {
+		if index >= len(events) {
+			return IntcodeDivergence{Index: index, Got: &e}
+		}
+
+		if events[index] != e {
+			return IntcodeDivergence{Index: index, Expected: &events[index], Got: &e}
+		}
+		index++
+
+		if onIO != nil {
+			return onIO(e)
+		}
+		return nil
+	}
+	defer func() { m.OnIO = onIO }()
+
+	err := m.Run(ctx, func() (int64, error) {
+		if index >= len(events) || events[index].Output {
+			// Value of the input is unknown as the recording does not
+			// contain an input at this point
+			var got = IntcodeEvent{Step: m.currentStep(), IP: m.pos}
+			if index >= len(events) {
+				return 0, IntcodeDivergence{Index: index, Got: &got}
+			}
+			return 0, IntcodeDivergence{Index: index, Expected: &events[index], Got: &got}
+		}
+		return events[index].Value, nil
+	}, out)
+
+	switch {
+	case err != nil:
+		if _, ok := errors.Cause(err).(IntcodeDivergence); ok {
+			return errors.Cause(err)
+		}
+		return err
+
+	case index < len(events):
+		return IntcodeDivergence{Index: index, Expected: &events[index]}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package aoc2019
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// Reads numbers and outputs the running sum until reading a zero
+const intcodeRecordTestProgram = intcodeStateTestProgram
+
+func recordIntcodeTestSession(t *testing.T, inputs ...int64) []IntcodeEvent {
+	var buf = new(bytes.Buffer)
+
+	code, _ := ParseIntcode(intcodeRecordTestProgram)
+	m := NewIntcodeMachine(code)
+	m.OnIO = NewIntcodeRecorder(buf).Record
+	m.Feed(inputs...)
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Recorded run failed: %s", err)
+	}
+
+	events, err := ReadIntcodeRecording(buf)
+	if err != nil {
+		t.Fatalf("Unable to read recording: %s", err)
+	}
+
+	return events
+}
+
+func TestIntcodeRecordReplay(t *testing.T) {
+	events := recordIntcodeTestSession(t, 1, 2, 0)
+
+	exp := []IntcodeEvent{
+		{Step: 1, IP: 0, Value: 1},
+		{Step: 3, IP: 6, Output: true, Value: 1},
+		{Step: 5, IP: 0, Value: 2},
+		{Step: 7, IP: 6, Output: true, Value: 3},
+		{Step: 9, IP: 0, Value: 0},
+		{Step: 11, IP: 6, Output: true, Value: 3},
+	}
+
+	if len(events) != len(exp) {
+		t.Fatalf("Unexpected recording: exp=%v got=%v", exp, events)
+	}
+	for i := range exp {
+		if events[i] != exp[i] {
+			t.Errorf("Unexpected event %d: exp=%s got=%s", i, exp[i], events[i])
+		}
+	}
+
+	code, _ := ParseIntcode(intcodeRecordTestProgram)
+	if err := ReplayIntcode(context.Background(), NewIntcodeMachine(code), events, nil); err != nil {
+		t.Errorf("Replay of unchanged program failed: %s", err)
+	}
+}
+
+func TestIntcodeReplayDivergence(t *testing.T) {
+	events := recordIntcodeTestSession(t, 1, 2, 0)
+
+	for name, tc := range map[string]struct {
+		Code  string
+		Index int
+	}{
+		// Outputs the running sum plus one
+		"changed output": {Code: "3,20,1,20,21,21,101,1,21,22,4,22,1005,20,0,99,0,0,0,0,0,0,0", Index: 1},
+		// Adds an unrecorded output before reading
+		"additional output": {Code: "104,7,3,20,1,20,21,21,4,21,1005,20,2,99,0,0,0,0,0,0,0,0", Index: 0},
+		// Exits after the first sum
+		"early exit": {Code: "3,20,1,20,21,21,4,21,99,0,0,0,0,0,0,0,0,0,0,0,0,0", Index: 2},
+	} {
+		code, _ := ParseIntcode(tc.Code)
+
+		err := ReplayIntcode(context.Background(), NewIntcodeMachine(code), events, nil)
+		div, ok := err.(IntcodeDivergence)
+		if !ok {
+			t.Errorf("Replay of %q did not diverge: %v", name, err)
+			continue
+		}
+
+		if div.Index != tc.Index {
+			t.Errorf("Replay of %q diverged at unexpected event: exp=%d got=%s", name, tc.Index, div)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package aoc2019
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Line protocol used to transfer values over a connection: in decimal
+// mode every line holds one value, in ASCII mode values within the ASCII
+// range are transferred as characters (a line of text is a sequence of
+// values including the newline) and other values as decimal lines. The
+// ASCII mode is meant for humans talking to a machine (e.g. through
+// netcat), chained machines should use the decimal mode.
+
+type intcodeLineDecoder struct {
+	ascii   bool
+	pending []int64
+	r       *bufio.Reader
+}
+
+// next returns the next value or io.EOF when the connection was closed
+func (i *intcodeLineDecoder) next() (int64, error) {
+	for len(i.pending) == 0 {
+		line, err := i.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return 0, err
+		}
+
+		if i.ascii {
+			for _, c := range []byte(line) {
+				i.pending = append(i.pending, int64(c))
+			}
+			continue
+		}
+
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+
+		v, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Invalid value %q", line)
+		}
+		return v, nil
+	}
+
+	v := i.pending[0]
+	i.pending = i.pending[1:]
+	return v, nil
+}
+
+func writeIntcodeLineValue(w io.Writer, v int64, ascii bool) error {
+	var err error
+	if ascii && v >= 0 && v < 128 {
+		_, err = w.Write([]byte{byte(v)})
+	} else {
+		_, err = fmt.Fprintf(w, "%d\n", v)
+	}
+	return errors.Wrap(err, "Unable to write value")
+}
+
+// ServeIntcodeConn runs the machine with its input read from and its
+// output written to the connection until the program exits. When the
+// peer closes the connection the machine is paused with
+// ErrIntcodeInputExhausted.
+func ServeIntcodeConn(ctx context.Context, conn io.ReadWriter, m *IntcodeMachine, ascii bool) error {
+	dec := &intcodeLineDecoder{ascii: ascii, r: bufio.NewReader(conn)}
+
+	return m.Run(ctx, func() (int64, error) {
+		v, err := dec.next()
+		if err == io.EOF {
+			return 0, ErrIntcodeInputExhausted
+		}
+		return v, err
+	}, func(v int64) error {
+		return writeIntcodeLineValue(conn, v, ascii)
+	})
+}
+
+// ServeIntcode accepts connections on the listener until the context is
+// cancelled and serves a new machine created through newMachine on
+// every connection. The connection is closed when its program exits.
+func ServeIntcode(ctx context.Context, l net.Listener, newMachine func() (*IntcodeMachine, error), ascii bool) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "Unable to accept connection")
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			// A broken machine must not take down the other connections
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Intcode connection from %s panicked: %v", conn.RemoteAddr(), r)
+				}
+			}()
+
+			connCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			// Unblock reads of the machine when the server is stopped
+			go func() {
+				<-connCtx.Done()
+				conn.Close()
+			}()
+
+			m, err := newMachine()
+			if err == nil {
+				err = ServeIntcodeConn(connCtx, conn, m, ascii)
+			}
+
+			if err != nil && err != ErrIntcodeInputExhausted && ctx.Err() == nil {
+				log.Printf("Intcode connection from %s failed: %s", conn.RemoteAddr(), err)
+			}
+		}(conn)
+	}
+}
+
+// IntcodeRemote is the client side of a machine served through
+// ServeIntcode: values sent are inputs of the remote machine, values
+// received are its outputs
+type IntcodeRemote struct {
+	conn net.Conn
+	dec  *intcodeLineDecoder
+}
+
+// DialIntcode connects to a machine served by ServeIntcode
+func DialIntcode(ctx context.Context, addr string, ascii bool) (*IntcodeRemote, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to connect to remote machine")
+	}
+
+	return NewIntcodeRemote(conn, ascii), nil
+}
+
+// NewIntcodeRemote wraps an existing connection to a remote machine
+func NewIntcodeRemote(conn net.Conn, ascii bool) *IntcodeRemote {
+	return &IntcodeRemote{
+		conn: conn,
+		dec:  &intcodeLineDecoder{ascii: ascii, r: bufio.NewReader(conn)},
+	}
+}
+
+// Close closes the connection to the remote machine
+func (i *IntcodeRemote) Close() error { return i.conn.Close() }
+
+// Receive reads the next output of the remote machine, returns io.EOF
+// after the remote program exited
+func (i *IntcodeRemote) Receive() (int64, error) { return i.dec.next() }
+
+// Send passes an input to the remote machine
+func (i *IntcodeRemote) Send(v int64) error { return writeIntcodeLineValue(i.conn, v, i.dec.ascii) }
+
+// Pipe connects the remote machine to a local chain: values read from in
+// are sent to the remote machine, its outputs are written to out. out
+// is closed after the remote program exited, so the remote machine can
+// be used like the channels of executeIntcode.
+func (i *IntcodeRemote) Pipe(in <-chan int64, out chan<- int64) error {
+	var (
+		done    = make(chan struct{})
+		sendErr = make(chan error, 1)
+	)
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if err := i.Send(v); err != nil {
+					sendErr <- err
+					return
+				}
+			}
+		}
+	}()
+
+	defer close(out)
+	for {
+		v, err := i.Receive()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			select {
+			case sErr := <-sendErr:
+				// Failed send is the cause for the broken connection
+				return errors.Wrap(sErr, "Unable to send value")
+			default:
+				return errors.Wrap(err, "Unable to receive value")
+			}
+		}
+
+		out <- v
+	}
+}
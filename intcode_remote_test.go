@@ -0,0 +1,166 @@
+package aoc2019
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+func serveIntcodeTestProgram(t *testing.T, program string, ascii bool) (string, func()) {
+	code, err := ParseIntcode(program)
+	if err != nil {
+		t.Fatalf("Parsing Intcode failed: %s", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go ServeIntcode(ctx, l, func() (*IntcodeMachine, error) {
+		return NewIntcodeMachine(cloneIntcode(code)), nil
+	}, ascii)
+
+	return l.Addr().String(), cancel
+}
+
+func runRemoteDay07Chain(t *testing.T, addr string, seq []int64, looped bool) int64 {
+	var chans = make([]chan int64, len(seq)+1)
+	for i := range chans {
+		chans[i] = make(chan int64, 2)
+	}
+
+	for i, phase := range seq {
+		remote, err := DialIntcode(context.Background(), addr, false)
+		if err != nil {
+			t.Fatalf("Unable to connect: %s", err)
+		}
+		defer remote.Close()
+
+		if err := remote.Send(phase); err != nil {
+			t.Fatalf("Unable to send phase: %s", err)
+		}
+
+		go func(i int) {
+			if err := remote.Pipe(chans[i], chans[i+1]); err != nil {
+				t.Errorf("Pipe of amplifier %d failed: %s", i, err)
+			}
+		}(i)
+	}
+
+	chans[0] <- 0 // Input signal
+
+	var lastOutput int64
+	for r := range chans[len(seq)] {
+		lastOutput = r
+		if looped {
+			chans[0] <- r
+		}
+	}
+
+	return lastOutput
+}
+
+func TestIntcodeRemoteChain(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	raw, err := ioutil.ReadFile("day07_input.txt")
+	if err != nil {
+		t.Fatalf("Unable to read input: %s", err)
+	}
+	program := strings.TrimSpace(string(raw))
+	code, _ := ParseIntcode(program)
+
+	addr, stop := serveIntcodeTestProgram(t, program, false)
+	defer stop()
+
+	for _, tc := range []struct {
+		Seq    []int64
+		Looped bool
+	}{
+		{Seq: []int64{4, 3, 2, 1, 0}},
+		{Seq: []int64{9, 8, 7, 6, 5}, Looped: true},
+	} {
+		exp, err := day07RunChain(newIntcodeBatch(code), tc.Seq, tc.Looped)
+		if err != nil {
+			t.Fatalf("Local chain %v failed: %s", tc.Seq, err)
+		}
+		if got := runRemoteDay07Chain(t, addr, tc.Seq, tc.Looped); got != exp {
+			t.Errorf("Remote chain %v yield unexpected result: exp=%d got=%d", tc.Seq, exp, got)
+		}
+	}
+}
+
+func TestIntcodeRemoteASCII(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	// Echoes every input
+	addr, stop := serveIntcodeTestProgram(t, "3,7,4,7,1105,1,0,0", true)
+	defer stop()
+
+	remote, err := DialIntcode(context.Background(), addr, true)
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	defer remote.Close()
+
+	for _, c := range "hi\n" {
+		if err := remote.Send(int64(c)); err != nil {
+			t.Fatalf("Unable to send: %s", err)
+		}
+	}
+
+	var echo string
+	for range "hi\n" {
+		v, err := remote.Receive()
+		if err != nil {
+			t.Fatalf("Unable to receive: %s", err)
+		}
+		echo += string(rune(v))
+	}
+
+	if echo != "hi\n" {
+		t.Errorf("Unexpected echo: %q", echo)
+	}
+}
+
+func TestIntcodeRemoteNegativeAddress(t *testing.T) {
+	defer checkGoroutineLeaks(t)()
+
+	// Outputs the cell addressed by the input
+	addr, stop := serveIntcodeTestProgram(t, "3,3,4,0,99", false)
+	defer stop()
+
+	remote, err := DialIntcode(context.Background(), addr, false)
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	defer remote.Close()
+
+	if err := remote.Send(-5); err != nil {
+		t.Fatalf("Unable to send: %s", err)
+	}
+
+	if v, err := remote.Receive(); err != io.EOF {
+		t.Errorf("Connection was not closed: value=%d err=%v", v, err)
+	}
+
+	// The server must still serve new connections
+	remote, err = DialIntcode(context.Background(), addr, false)
+	if err != nil {
+		t.Fatalf("Unable to connect after failed machine: %s", err)
+	}
+	defer remote.Close()
+
+	if err := remote.Send(0); err != nil {
+		t.Fatalf("Unable to send: %s", err)
+	}
+
+	if v, err := remote.Receive(); err != nil || v != 3 {
+		t.Errorf("Unexpected output: exp=3 got=%d err=%v", v, err)
+	}
+}
@@ -0,0 +1,130 @@
+package aoc2019
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// IntcodeHaltReason describes why a program stopped
+type IntcodeHaltReason int
+
+const (
+	// IntcodeHaltExit means the program reached the exit directive
+	IntcodeHaltExit IntcodeHaltReason = iota
+	// IntcodeHaltContext means the context was cancelled
+	IntcodeHaltContext
+	// IntcodeHaltInputExhausted means the program requested more input
+	// than available
+	IntcodeHaltInputExhausted
+	// IntcodeHaltLimit means the instruction limit was reached
+	IntcodeHaltLimit
+	// IntcodeHaltError means the program failed, see returned error
+	IntcodeHaltError
+)
+
+func (i IntcodeHaltReason) String() string {
+	switch i {
+	case IntcodeHaltExit:
+		return "exit"
+	case IntcodeHaltContext:
+		return "context cancelled"
+	case IntcodeHaltInputExhausted:
+		return "input exhausted"
+	case IntcodeHaltLimit:
+		return "limit reached"
+	case IntcodeHaltError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// IntcodeResult describes the state of a program after it stopped
+type IntcodeResult struct {
+	Reason IntcodeHaltReason
+	// Number of instructions executed
+	Steps int64
+	// All outputs of the program
+	Outputs []int64
+	// Address of the next instruction to execute
+	IP           int64
+	RelativeBase int64
+	Memory       []int64
+}
+
+func newIntcodeMachineFromParams(params IntcodeParams) *IntcodeMachine {
+	m := NewIntcodeMachine(params.Code)
+	m.Arithmetic = params.Arithmetic
+	m.OnIO = params.OnIO
+	m.MaxSteps = params.MaxSteps
+	m.Taint = params.Taint
+	m.Feed(params.Inputs...)
+	return m
+}
+
+// RunIntcode runs the program until it stops and returns its final
+// state. Contrary to ExecuteIntcodeWithParams a cancelled context, an
+// exhausted input (In is optional) or a reached limit are no errors but
+// reported as halt reason. Out is optional as all outputs are part of
+// the result. With IntcodeArithmeticBig the final memory must fit into
+// int64.
+func RunIntcode(params IntcodeParams) (IntcodeResult, error) {
+	var (
+		inCB  func() (int64, error)
+		outCB = func(int64) error { return nil }
+		err   error
+	)
+
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
+
+	if params.In != nil {
+		if inCB, err = intcodeInputCallback(params.Context, params.In); err != nil {
+			return IntcodeResult{Reason: IntcodeHaltError}, err
+		}
+	}
+
+	if params.Out != nil {
+		var closeOut func()
+		if outCB, closeOut, err = intcodeOutputCallback(params.Context, params.Out); err != nil {
+			return IntcodeResult{Reason: IntcodeHaltError}, err
+		}
+		defer closeOut()
+	}
+
+	m := newIntcodeMachineFromParams(params)
+	err = m.Run(params.Context, inCB, outCB)
+	if err == nil {
+		err = m.checkInt64Memory()
+	}
+	return intcodeResultOf(params.Context, m, err)
+}
+
+// intcodeResultOf converts the state of a machine and the error returned
+// by its run into a result
+func intcodeResultOf(ctx context.Context, m *IntcodeMachine, err error) (IntcodeResult, error) {
+	res := IntcodeResult{
+		Steps:        m.steps,
+		Outputs:      m.outputs,
+		IP:           m.pos,
+		RelativeBase: m.relativeBase,
+		Memory:       m.memory,
+	}
+
+	switch {
+	case err == nil:
+		res.Reason = IntcodeHaltExit
+	case err == ErrIntcodeInputExhausted:
+		res.Reason = IntcodeHaltInputExhausted
+	case err == ErrIntcodeStepLimit:
+		res.Reason = IntcodeHaltLimit
+	case ctx.Err() != nil && errors.Cause(err) == ctx.Err():
+		res.Reason = IntcodeHaltContext
+	default:
+		res.Reason = IntcodeHaltError
+		return res, err
+	}
+
+	return res, nil
+}
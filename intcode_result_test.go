@@ -0,0 +1,66 @@
+package aoc2019
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRunIntcode(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for name, tc := range map[string]struct {
+		Code   string
+		Params IntcodeParams
+		Reason IntcodeHaltReason
+		Steps  int64
+		IP     int64
+		RB     int64
+		Out    []int64
+	}{
+		"exit": {
+			// Adjusts the relative base, outputs input * 2
+			Code:   "109,5,3,11,1002,11,2,11,4,11,99,0",
+			Params: IntcodeParams{Inputs: []int64{21}},
+			Reason: IntcodeHaltExit, Steps: 5, IP: 10, RB: 5, Out: []int64{42},
+		},
+		"input exhausted": {
+			Code:   "104,1,3,9,99",
+			Reason: IntcodeHaltInputExhausted, Steps: 1, IP: 2, Out: []int64{1},
+		},
+		"limit": {
+			Code:   "1105,1,0",
+			Params: IntcodeParams{MaxSteps: 10},
+			Reason: IntcodeHaltLimit, Steps: 10,
+		},
+		"context": {
+			Code:   "104,1,99",
+			Params: IntcodeParams{Context: cancelled},
+			Reason: IntcodeHaltContext,
+		},
+	} {
+		code, _ := ParseIntcode(tc.Code)
+		tc.Params.Code = code
+
+		res, err := RunIntcode(tc.Params)
+		if err != nil {
+			t.Errorf("Run of %q failed: %s", name, err)
+			continue
+		}
+
+		if res.Reason != tc.Reason || res.Steps != tc.Steps || res.IP != tc.IP || res.RelativeBase != tc.RB {
+			t.Errorf("Unexpected result for %q: reason=%s steps=%d ip=%d rb=%d", name, res.Reason, res.Steps, res.IP, res.RelativeBase)
+		}
+
+		if !reflect.DeepEqual(res.Outputs, tc.Out) {
+			t.Errorf("Unexpected outputs for %q: exp=%v got=%v", name, tc.Out, res.Outputs)
+		}
+	}
+
+	code, _ := ParseIntcode("104,1,42")
+	res, err := RunIntcode(IntcodeParams{Code: code})
+	if err == nil || res.Reason != IntcodeHaltError {
+		t.Errorf("Invalid operation did not fail: reason=%s err=%v", res.Reason, err)
+	}
+}
@@ -0,0 +1,198 @@
+package aoc2019
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// intcodeSearchCandidate describes one point of the input space of a
+// program: memory patches applied before the execution and values fed
+// into the input directives
+type intcodeSearchCandidate struct {
+	Patches map[int64]int64
+	Inputs  []int64
+
+	index int64
+}
+
+type intcodeSearchResult struct {
+	Candidate intcodeSearchCandidate
+	// Error of the evaluation, result is never matched if set
+	Err     error
+	Memory  []int64
+	Outputs []int64
+}
+
+type intcodeSearchParams struct {
+	// Intcode program to search the input space of
+	Code []int64
+	// Context to execute the search in, defaults to background context
+	Context context.Context
+	// Generator for the candidates to test, must stop emitting as soon
+	// as emit returns false
+	Candidates func(emit func(intcodeSearchCandidate) bool)
+	// Evaluator for a candidate, defaults to a single run of the program
+	Evaluate func(code []int64, c intcodeSearchCandidate) intcodeSearchResult
+	// Predicate to select the results to return
+	Match func(intcodeSearchResult) bool
+	// Collect all matches instead of stopping on the first one
+	FindAll bool
+	// Number of parallel evaluations, defaults to number of CPUs
+	Workers int
+}
+
+type intcodeSearchStats struct {
+	Evaluated int64
+	Failed    int64
+	Matched   int64
+	Duration  time.Duration
+}
+
+// Throughput returns the number of evaluated candidates per second
+func (i intcodeSearchStats) Throughput() float64 {
+	if i.Duration == 0 {
+		return 0
+	}
+	return float64(i.Evaluated) / i.Duration.Seconds()
+}
+
+// evaluateIntcodeCandidate runs a copy of the program with patches and
+// inputs of the candidate applied
+func evaluateIntcodeCandidate(code []int64, c intcodeSearchCandidate) intcodeSearchResult {
+	var (
+		inputs = c.Inputs
+		res    = intcodeSearchResult{Candidate: c}
+	)
+
+	code = cloneIntcode(code)
+	for addr, v := range c.Patches {
+		if addr < 0 || addr >= int64(len(code)) {
+			res.Err = errors.Errorf("Patch address %d out of bounds", addr)
+			return res
+		}
+		code[addr] = v
+	}
+
+	res.Memory, res.Err = ExecuteIntcodeWithParams(IntcodeParams{
+		Code:    code,
+		Context: context.Background(),
+		In: func() (int64, error) {
+			if len(inputs) == 0 {
+				return 0, errors.New("Candidate has no more inputs")
+			}
+			v := inputs[0]
+			inputs = inputs[1:]
+			return v, nil
+		},
+		Out: func(v int64) error {
+			res.Outputs = append(res.Outputs, v)
+			return nil
+		},
+	})
+
+	return res
+}
+
+// searchIntcode evaluates all candidates in parallel and returns the
+// results accepted by the Match predicate. Without FindAll the search
+// stops on the first match found, which is not necessarily the first
+// matching candidate in order of generation. With FindAll the results
+// are returned in order of generation.
+func searchIntcode(params intcodeSearchParams) ([]intcodeSearchResult, intcodeSearchStats, error) {
+	var (
+		start = time.Now()
+		stats intcodeSearchStats
+	)
+
+	if params.Candidates == nil || params.Match == nil {
+		return nil, stats, errors.New("Candidates and Match are required")
+	}
+
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
+
+	if params.Evaluate == nil {
+		params.Evaluate = evaluateIntcodeCandidate
+	}
+
+	if params.Workers < 1 {
+		params.Workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(params.Context)
+	defer cancel()
+
+	var (
+		candidates = make(chan intcodeSearchCandidate, params.Workers)
+		matches    []intcodeSearchResult
+		resLock    sync.Mutex
+		wg         sync.WaitGroup
+	)
+
+	for i := 0; i < params.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for c := range candidates {
+				if ctx.Err() != nil {
+					// Search was stopped, drain the candidates
+					continue
+				}
+
+				res := params.Evaluate(params.Code, c)
+				matched := res.Err == nil && params.Match(res)
+
+				resLock.Lock()
+				stats.Evaluated++
+				if res.Err != nil {
+					stats.Failed++
+				}
+				if matched {
+					stats.Matched++
+					matches = append(matches, res)
+					if !params.FindAll {
+						cancel()
+					}
+				}
+				resLock.Unlock()
+			}
+		}()
+	}
+
+	var index int64
+	params.Candidates(func(c intcodeSearchCandidate) bool {
+		c.index = index
+		index++
+
+		select {
+		case <-ctx.Done():
+			return false
+		case candidates <- c:
+			return true
+		}
+	})
+	close(candidates)
+	wg.Wait()
+
+	stats.Duration = time.Since(start)
+
+	if err := params.Context.Err(); err != nil {
+		return nil, stats, errors.Wrap(err, "Search was cancelled")
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Candidate.index < matches[j].Candidate.index })
+
+	if !params.FindAll && len(matches) > 1 {
+		// Workers running in parallel might have found more than one match
+		matches = matches[:1]
+	}
+
+	return matches, stats, nil
+}
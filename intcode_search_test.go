@@ -0,0 +1,85 @@
+package aoc2019
+
+import "testing"
+
+func TestSearchIntcode(t *testing.T) {
+	// Outputs input * 3 + pos_13 where pos_13 is patched by the candidates
+	code, _ := ParseIntcode("3,14,1002,14,3,14,1,14,13,14,4,14,99,0,0")
+
+	candidates := func(emit func(intcodeSearchCandidate) bool) {
+		for in := int64(0); in < 50; in++ {
+			for offset := int64(0); offset < 3; offset++ {
+				if !emit(intcodeSearchCandidate{Patches: map[int64]int64{13: offset}, Inputs: []int64{in}}) {
+					return
+				}
+			}
+		}
+	}
+
+	match := func(res intcodeSearchResult) bool { return res.Outputs[0]%10 == 0 }
+
+	all, stats, err := searchIntcode(intcodeSearchParams{
+		Code:       code,
+		Candidates: candidates,
+		Match:      match,
+		FindAll:    true,
+		Workers:    4,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %s", err)
+	}
+
+	if len(all) != 15 || stats.Matched != 15 {
+		t.Errorf("Unexpected number of matches: exp=15 got=%d (stats=%d)", len(all), stats.Matched)
+	}
+
+	if stats.Evaluated != 150 {
+		t.Errorf("Unexpected number of evaluations: exp=150 got=%d", stats.Evaluated)
+	}
+
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Outputs[0] > all[i].Outputs[0] {
+			t.Errorf("Results are not in order of generation: %d > %d", all[i-1].Outputs[0], all[i].Outputs[0])
+		}
+	}
+
+	first, stats, err := searchIntcode(intcodeSearchParams{
+		Code:       code,
+		Candidates: candidates,
+		Match:      match,
+		Workers:    1,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %s", err)
+	}
+
+	if len(first) != 1 || first[0].Outputs[0] != 0 {
+		t.Errorf("Unexpected first match: %+v", first)
+	}
+
+	if stats.Evaluated >= 150 {
+		t.Errorf("Search did not stop early: evaluated=%d", stats.Evaluated)
+	}
+
+	t.Logf("Search throughput: %.0f candidates/s", stats.Throughput())
+}
+
+func TestSearchIntcodeFailingCandidates(t *testing.T) {
+	// Reads one input more than every candidate provides
+	code, _ := ParseIntcode("3,0,3,0,4,0,99")
+
+	res, stats, err := searchIntcode(intcodeSearchParams{
+		Code: code,
+		Candidates: func(emit func(intcodeSearchCandidate) bool) {
+			emit(intcodeSearchCandidate{Inputs: []int64{1}})
+		},
+		Match: func(intcodeSearchResult) bool { return true },
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %s", err)
+	}
+
+	if len(res) != 0 || stats.Failed != 1 {
+		t.Errorf("Failed candidate was not reported: matches=%d failed=%d", len(res), stats.Failed)
+	}
+}
@@ -0,0 +1,100 @@
+package aoc2019
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// intcodeStateVersion is the current version of the state file format,
+// increase on every incompatible change of intcodeState
+const intcodeStateVersion = 1
+
+type intcodeState struct {
+	Version      int               `json:"version"`
+	Arithmetic   IntcodeArithmetic `json:"arithmetic"`
+	Exited       bool              `json:"exited"`
+	IP           int64             `json:"ip"`
+	RelativeBase int64             `json:"relative_base"`
+	Memory       []int64           `json:"memory"`
+	// Cells exceeding int64 as decimal numbers, the value in Memory is
+	// not used for these cells
+	BigMemory    map[int64]string `json:"big_memory"`
+	PendingInput []int64          `json:"pending_input"`
+	Outputs      []int64          `json:"outputs"`
+	Steps        int64            `json:"steps"`
+}
+
+// LoadIntcodeMachine restores a machine from a state written by SaveState
+func LoadIntcodeMachine(r io.Reader) (*IntcodeMachine, error) {
+	var state intcodeState
+
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, errors.Wrap(err, "Unable to decode state")
+	}
+
+	if state.Version != intcodeStateVersion {
+		return nil, errors.Errorf("Unsupported state version %d (expected %d)", state.Version, intcodeStateVersion)
+	}
+
+	if state.IP < 0 || state.IP >= int64(len(state.Memory)) {
+		return nil, errors.Errorf("Instruction pointer %d outside memory (len=%d)", state.IP, len(state.Memory))
+	}
+
+	var bigCells map[int64]*big.Int
+	for addr, v := range state.BigMemory {
+		if addr < 0 || addr >= int64(len(state.Memory)) {
+			return nil, errors.Errorf("Big memory cell %d outside memory (len=%d)", addr, len(state.Memory))
+		}
+
+		b, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			return nil, errors.Errorf("Invalid value %q of big memory cell %d", v, addr)
+		}
+
+		if bigCells == nil {
+			bigCells = map[int64]*big.Int{}
+		}
+		bigCells[addr] = b
+	}
+
+	return &IntcodeMachine{
+		Arithmetic:   state.Arithmetic,
+		bigCells:     bigCells,
+		exited:       state.Exited,
+		memory:       state.Memory,
+		outputs:      state.Outputs,
+		pendingInput: state.PendingInput,
+		pos:          state.IP,
+		relativeBase: state.RelativeBase,
+		steps:        state.Steps,
+	}, nil
+}
+
+// SaveState writes the full state of the machine in a versioned format.
+// The machine must not be running while the state is saved. Mapped
+// devices are not part of the state and need to be mapped again.
+func (i *IntcodeMachine) SaveState(w io.Writer) error {
+	var bigMemory map[int64]string
+	for addr, v := range i.bigCells {
+		if bigMemory == nil {
+			bigMemory = map[int64]string{}
+		}
+		bigMemory[addr] = v.String()
+	}
+
+	return errors.Wrap(json.NewEncoder(w).Encode(intcodeState{
+		Version:      intcodeStateVersion,
+		Arithmetic:   i.Arithmetic,
+		Exited:       i.exited,
+		IP:           i.pos,
+		RelativeBase: i.relativeBase,
+		Memory:       i.memory,
+		BigMemory:    bigMemory,
+		PendingInput: i.pendingInput,
+		Outputs:      i.outputs,
+		Steps:        i.steps,
+	}), "Unable to encode state")
+}
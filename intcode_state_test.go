@@ -0,0 +1,201 @@
+package aoc2019
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Reads numbers and outputs the running sum until reading a zero
+const intcodeStateTestProgram = "3,20,1,20,21,21,4,21,1005,20,0,99,0,0,0,0,0,0,0,0,0,0"
+
+func TestIntcodeMachineResumeAfterInputExhausted(t *testing.T) {
+	var (
+		exp    = []int64{1, 3, 6, 10, 10}
+		inputs = []int64{1, 2, 3, 4, 0}
+	)
+
+	code, _ := ParseIntcode(intcodeStateTestProgram)
+	m := NewIntcodeMachine(code)
+	m.Feed(inputs...)
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Uninterrupted run failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(m.Outputs(), exp) {
+		t.Fatalf("Uninterrupted run yield unexpected outputs: exp=%v got=%v", exp, m.Outputs())
+	}
+
+	var streamed []int64
+	collect := func(v int64) error {
+		streamed = append(streamed, v)
+		return nil
+	}
+
+	code, _ = ParseIntcode(intcodeStateTestProgram)
+	m = NewIntcodeMachine(code)
+	m.Feed(inputs[:2]...)
+	if err := m.Run(context.Background(), nil, collect); err != ErrIntcodeInputExhausted {
+		t.Fatalf("Machine did not pause on exhausted input: %v", err)
+	}
+
+	var buf = new(bytes.Buffer)
+	if err := m.SaveState(buf); err != nil {
+		t.Fatalf("Unable to save state: %s", err)
+	}
+
+	m, err := LoadIntcodeMachine(buf)
+	if err != nil {
+		t.Fatalf("Unable to load state: %s", err)
+	}
+
+	m.Feed(inputs[2:]...)
+	if err := m.Run(context.Background(), nil, collect); err != nil {
+		t.Fatalf("Resumed run failed: %s", err)
+	}
+
+	if !m.Exited() {
+		t.Error("Resumed machine did not exit")
+	}
+
+	if !reflect.DeepEqual(streamed, exp) {
+		t.Errorf("Interrupted run yield unexpected outputs: exp=%v got=%v", exp, streamed)
+	}
+
+	if !reflect.DeepEqual(m.Outputs(), exp) {
+		t.Errorf("Output history was not restored: exp=%v got=%v", exp, m.Outputs())
+	}
+}
+
+func TestIntcodeMachineResumeAfterCancel(t *testing.T) {
+	code, _ := ParseIntcode(intcodeStateTestProgram)
+	m := NewIntcodeMachine(code)
+	m.Feed(1, 2, 3, 0)
+
+	// Stop the machine after the first output
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := m.Run(ctx, nil, func(int64) error { cancel(); return nil }); err == nil {
+		t.Fatal("Machine did not stop on cancelled context")
+	}
+
+	var buf = new(bytes.Buffer)
+	if err := m.SaveState(buf); err != nil {
+		t.Fatalf("Unable to save state: %s", err)
+	}
+
+	if !strings.Contains(buf.String(), `"pending_input":[2,3,0]`) {
+		t.Errorf("Pending input not stored in state: %s", buf.String())
+	}
+
+	m, err := LoadIntcodeMachine(buf)
+	if err != nil {
+		t.Fatalf("Unable to load state: %s", err)
+	}
+
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Resumed run failed: %s", err)
+	}
+
+	if exp := []int64{1, 3, 6, 6}; !reflect.DeepEqual(m.Outputs(), exp) {
+		t.Errorf("Resumed run yield unexpected outputs: exp=%v got=%v", exp, m.Outputs())
+	}
+}
+
+func TestIntcodeMachineSaveDuringOutput(t *testing.T) {
+	code, _ := ParseIntcode(intcodeStateTestProgram)
+	m := NewIntcodeMachine(code)
+	m.Feed(1, 2, 0)
+
+	// Nobody reads the output channel, the first output is interrupted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	outCB, _, err := intcodeOutputCallback(ctx, make(chan int64))
+	if err != nil {
+		t.Fatalf("Unable to create output callback: %s", err)
+	}
+
+	if err := m.Run(context.Background(), nil, outCB); err == nil {
+		t.Fatal("Machine did not stop on undelivered output")
+	}
+
+	if o := m.Outputs(); len(o) != 0 {
+		t.Errorf("Undelivered output was stored: %v", o)
+	}
+
+	var buf = new(bytes.Buffer)
+	if err := m.SaveState(buf); err != nil {
+		t.Fatalf("Unable to save state: %s", err)
+	}
+
+	if m, err = LoadIntcodeMachine(buf); err != nil {
+		t.Fatalf("Unable to load state: %s", err)
+	}
+
+	var streamed []int64
+	if err := m.Run(context.Background(), nil, func(v int64) error {
+		streamed = append(streamed, v)
+		return nil
+	}); err != nil {
+		t.Fatalf("Resumed run failed: %s", err)
+	}
+
+	if exp := []int64{1, 3, 3}; !reflect.DeepEqual(streamed, exp) {
+		t.Errorf("Resumed run yield unexpected outputs: exp=%v got=%v", exp, streamed)
+	}
+
+	// Input and addition before the interruption, the output and all
+	// following instructions after resuming
+	if s := m.Steps(); s != 13 {
+		t.Errorf("Unexpected number of steps: exp=13 got=%d", s)
+	}
+}
+
+func TestIntcodeMachineStepsAfterCancelledInput(t *testing.T) {
+	code, _ := ParseIntcode(intcodeStateTestProgram)
+	m := NewIntcodeMachine(code)
+	m.Feed(1)
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		in          = make(chan int64)
+	)
+
+	chanCB, err := intcodeInputCallback(ctx, in)
+	if err != nil {
+		t.Fatalf("Unable to create input callback: %s", err)
+	}
+
+	// Cancel while the machine is blocked reading the second input
+	inCB := func() (int64, error) {
+		go cancel()
+		return chanCB()
+	}
+
+	if err := m.Run(ctx, inCB, func(int64) error { return nil }); err == nil {
+		t.Fatal("Machine did not stop on cancelled context")
+	}
+
+	// Input, addition, output and jump were executed, the second input
+	// was interrupted
+	if s := m.Steps(); s != 4 {
+		t.Errorf("Unexpected number of steps: exp=4 got=%d", s)
+	}
+
+	var buf = new(bytes.Buffer)
+	if err := m.SaveState(buf); err != nil {
+		t.Fatalf("Unable to save state: %s", err)
+	}
+
+	if !strings.Contains(buf.String(), `"steps":4`) {
+		t.Errorf("Steps not stored in state: %s", buf.String())
+	}
+}
+
+func TestLoadIntcodeMachineVersion(t *testing.T) {
+	if _, err := LoadIntcodeMachine(strings.NewReader(`{"version":999,"memory":[99]}`)); err == nil {
+		t.Error("State with unsupported version was loaded")
+	}
+}
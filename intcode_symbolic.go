@@ -0,0 +1,688 @@
+package aoc2019
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// errIntcodeSymbolicDependency is returned by the symbolic execution
+// when a symbolic value decides the control flow, an address or an
+// opcode and the program cannot be followed without concrete values
+var errIntcodeSymbolicDependency = errors.New("Value depends on symbols")
+
+type intcodeSymOp int
+
+const (
+	intcodeSymConst intcodeSymOp = iota
+	intcodeSymVar
+	intcodeSymAdd
+	intcodeSymMul
+	intcodeSymLess
+	intcodeSymEquals
+	// Read from a symbolic address, the value is unknown
+	intcodeSymLoad
+)
+
+// intcodeSymExpr is an expression tree built from constants, symbols
+// and the arithmetic and comparison directives. Reads from symbolic
+// addresses yield loads which cannot be evaluated.
+type intcodeSymExpr struct {
+	Op    intcodeSymOp
+	Value int64
+	Name  string
+	A, B  *intcodeSymExpr
+}
+
+func newIntcodeSymConst(v int64) *intcodeSymExpr {
+	return &intcodeSymExpr{Op: intcodeSymConst, Value: v}
+}
+
+// newIntcodeSymBinary combines two expressions and folds constants
+func newIntcodeSymBinary(op intcodeSymOp, a, b *intcodeSymExpr) *intcodeSymExpr {
+	if a.Op == intcodeSymConst && b.Op == intcodeSymConst {
+		return newIntcodeSymConst((&intcodeSymExpr{Op: op, A: a, B: b}).eval(nil))
+	}
+
+	switch op {
+	case intcodeSymAdd:
+		if a.isConst(0) {
+			return b
+		}
+		if b.isConst(0) {
+			return a
+		}
+
+	case intcodeSymMul:
+		if a.isConst(0) || b.isConst(0) {
+			return newIntcodeSymConst(0)
+		}
+		if a.isConst(1) {
+			return b
+		}
+		if b.isConst(1) {
+			return a
+		}
+	}
+
+	return &intcodeSymExpr{Op: op, A: a, B: b}
+}
+
+// hasLoad reports whether the expression depends on an unknown value
+func (i *intcodeSymExpr) hasLoad() bool {
+	switch i.Op {
+	case intcodeSymConst, intcodeSymVar:
+		return false
+	case intcodeSymLoad:
+		return true
+	}
+	return i.A.hasLoad() || i.B.hasLoad()
+}
+
+func (i *intcodeSymExpr) isConst(v int64) bool {
+	return i.Op == intcodeSymConst && i.Value == v
+}
+
+func (i *intcodeSymExpr) eval(vars map[string]int64) int64 {
+	boolInt := func(b bool) int64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	switch i.Op {
+	case intcodeSymConst:
+		return i.Value
+	case intcodeSymVar:
+		return vars[i.Name]
+	case intcodeSymAdd:
+		return i.A.eval(vars) + i.B.eval(vars)
+	case intcodeSymMul:
+		return i.A.eval(vars) * i.B.eval(vars)
+	case intcodeSymLess:
+		return boolInt(i.A.eval(vars) < i.B.eval(vars))
+	case intcodeSymEquals:
+		return boolInt(i.A.eval(vars) == i.B.eval(vars))
+	}
+
+	panic(errors.Errorf("Unexpected expression op %d", i.Op))
+}
+
+// linear converts the expression into a sum of symbols with constant
+// coefficients, fails for products of symbols and comparisons
+func (i *intcodeSymExpr) linear() (intcodeLinearExpr, bool) {
+	switch i.Op {
+
+	case intcodeSymConst:
+		return intcodeLinearExpr{Const: i.Value}, true
+
+	case intcodeSymVar:
+		return intcodeLinearExpr{Coeffs: map[string]int64{i.Name: 1}}, true
+
+	case intcodeSymAdd, intcodeSymMul:
+		a, ok := i.A.linear()
+		if !ok {
+			return a, false
+		}
+		b, ok := i.B.linear()
+		if !ok {
+			return b, false
+		}
+
+		if i.Op == intcodeSymAdd {
+			return a.add(b), true
+		}
+
+		switch {
+		case len(a.Coeffs) == 0:
+			return b.scale(a.Const), true
+		case len(b.Coeffs) == 0:
+			return a.scale(b.Const), true
+		}
+
+	}
+
+	return intcodeLinearExpr{}, false
+}
+
+func (i *intcodeSymExpr) String() string {
+	switch i.Op {
+	case intcodeSymConst:
+		return fmt.Sprintf("%d", i.Value)
+	case intcodeSymVar:
+		return i.Name
+	case intcodeSymAdd:
+		return fmt.Sprintf("(%s + %s)", i.A, i.B)
+	case intcodeSymMul:
+		return fmt.Sprintf("(%s * %s)", i.A, i.B)
+	case intcodeSymLess:
+		return fmt.Sprintf("(%s < %s)", i.A, i.B)
+	case intcodeSymEquals:
+		return fmt.Sprintf("(%s == %s)", i.A, i.B)
+	case intcodeSymLoad:
+		return fmt.Sprintf("code[%s]", i.A)
+	}
+	return "?"
+}
+
+// intcodeLinearExpr represents sum(Coeffs[name] * name) + Const
+type intcodeLinearExpr struct {
+	Coeffs map[string]int64
+	Const  int64
+}
+
+func (i intcodeLinearExpr) add(o intcodeLinearExpr) intcodeLinearExpr {
+	var out = intcodeLinearExpr{Coeffs: map[string]int64{}, Const: i.Const + o.Const}
+	for _, c := range []map[string]int64{i.Coeffs, o.Coeffs} {
+		for n, v := range c {
+			out.Coeffs[n] += v
+			if out.Coeffs[n] == 0 {
+				delete(out.Coeffs, n)
+			}
+		}
+	}
+	return out
+}
+
+func (i intcodeLinearExpr) scale(f int64) intcodeLinearExpr {
+	var out = intcodeLinearExpr{Coeffs: map[string]int64{}, Const: i.Const * f}
+	for n, v := range i.Coeffs {
+		if v*f != 0 {
+			out.Coeffs[n] = v * f
+		}
+	}
+	return out
+}
+
+// format renders the expression with the terms in the given order of
+// symbols, for example "460800*noun + verb + 797870"
+func (i intcodeLinearExpr) format(order []string) string {
+	var out []string
+
+	term := func(s string, negative bool) {
+		switch {
+		case len(out) == 0 && negative:
+			out = append(out, "-"+s)
+		case len(out) == 0:
+			out = append(out, s)
+		case negative:
+			out = append(out, "- "+s)
+		default:
+			out = append(out, "+ "+s)
+		}
+	}
+
+	abs := func(v int64) int64 {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+
+	for _, n := range order {
+		c, ok := i.Coeffs[n]
+		if !ok {
+			continue
+		}
+
+		if abs(c) == 1 {
+			term(n, c < 0)
+		} else {
+			term(fmt.Sprintf("%d*%s", abs(c), n), c < 0)
+		}
+	}
+
+	if i.Const != 0 || len(out) == 0 {
+		term(fmt.Sprintf("%d", abs(i.Const)), i.Const < 0)
+	}
+
+	return strings.Join(out, " ")
+}
+
+// intcodeSymbol marks a memory cell or an input as symbolic. Input
+// symbols are consumed by the input directives in order.
+type intcodeSymbol struct {
+	Name string
+	// Memory cell to replace with the symbol, ignored for inputs
+	Addr int64
+	// Symbol is read through an input directive
+	Input bool
+	// Range of values the symbol may take (inclusive)
+	Min, Max int64
+}
+
+// executeIntcodeSymbolic runs the program with the given symbols and
+// returns the final memory and all outputs as expressions. Arithmetic
+// wraps on overflow.
+func executeIntcodeSymbolic(ctx context.Context, code []int64, symbols []intcodeSymbol) ([]*intcodeSymExpr, []*intcodeSymExpr, error) {
+	var (
+		inputs       []*intcodeSymExpr
+		mem          = make([]*intcodeSymExpr, len(code))
+		outputs      []*intcodeSymExpr
+		pos          int64
+		relativeBase int64
+	)
+
+	for i, v := range code {
+		mem[i] = newIntcodeSymConst(v)
+	}
+
+	for _, s := range symbols {
+		v := &intcodeSymExpr{Op: intcodeSymVar, Name: s.Name}
+		if s.Input {
+			inputs = append(inputs, v)
+			continue
+		}
+
+		if s.Addr < 0 || s.Addr >= int64(len(mem)) {
+			return nil, nil, errors.Errorf("Symbol %q address %d out of bounds", s.Name, s.Addr)
+		}
+		mem[s.Addr] = v
+	}
+
+	cell := func(addr int64) *intcodeSymExpr {
+		if addr >= int64(len(mem)) {
+			return newIntcodeSymConst(0)
+		}
+		return mem[addr]
+	}
+
+	concrete := func(e *intcodeSymExpr, what string) (int64, error) {
+		if e.Op != intcodeSymConst {
+			return 0, errors.Wrapf(errIntcodeSymbolicDependency, "%s at address %d is %s", what, pos, e)
+		}
+		return e.Value, nil
+	}
+
+	transformPos := func(param int64, op opCode) (int64, error) {
+		if op.GetFlag(param) == opCodeFlagImmediate {
+			return pos + param, nil
+		}
+
+		addr, err := concrete(cell(pos+param), "Address")
+		if err != nil {
+			return 0, err
+		}
+
+		if op.GetFlag(param) == opCodeFlagRelative {
+			addr += relativeBase
+		}
+
+		if addr < 0 {
+			return 0, errors.Errorf("Negative address %d at address %d", addr, pos)
+		}
+
+		return addr, nil
+	}
+
+	getParamValue := func(param int64, op opCode) (*intcodeSymExpr, error) {
+		if op.GetFlag(param) == opCodeFlagPosition && cell(pos+param).Op != intcodeSymConst {
+			// Value is unknown but might never be used
+			return &intcodeSymExpr{Op: intcodeSymLoad, A: cell(pos + param)}, nil
+		}
+
+		addr, err := transformPos(param, op)
+		if err != nil {
+			return nil, err
+		}
+		return cell(addr), nil
+	}
+
+	setParamValue := func(param int64, value *intcodeSymExpr, op opCode) error {
+		addr, err := transformPos(param, op)
+		if err != nil {
+			return err
+		}
+
+		for addr >= int64(len(mem)) {
+			// Write outside memory, increase memory
+			mem = append(mem, newIntcodeSymConst(0))
+		}
+
+		mem[addr] = value
+		return nil
+	}
+
+	binaryOp := func(op opCode, symOp intcodeSymOp) error {
+		a, err := getParamValue(1, op)
+		if err != nil {
+			return err
+		}
+
+		b, err := getParamValue(2, op)
+		if err != nil {
+			return err
+		}
+
+		return setParamValue(3, newIntcodeSymBinary(symOp, a, b), op)
+	}
+
+	jumpIf := func(op opCode, onZero bool) error {
+		e, err := getParamValue(1, op)
+		if err != nil {
+			return err
+		}
+
+		v, err := concrete(e, "Jump condition")
+		if err != nil {
+			return err
+		}
+
+		if (v == 0) != onZero {
+			pos += 3
+			return nil
+		}
+
+		if e, err = getParamValue(2, op); err != nil {
+			return err
+		}
+
+		pos, err = concrete(e, "Jump target")
+		return err
+	}
+
+	for {
+		if pos >= int64(len(mem)) {
+			return nil, nil, errors.Errorf("Code position out of bounds: %d (len=%d)", pos, len(mem))
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, nil, errors.Wrap(err, "Context closed")
+		}
+
+		rawOp, err := concrete(mem[pos], "OpCode")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		op := parseOpCode(rawOp)
+
+		switch op.Type {
+
+		case opCodeTypeAddition:
+			err = binaryOp(op, intcodeSymAdd)
+			pos += 4
+
+		case opCodeTypeMultiplication:
+			err = binaryOp(op, intcodeSymMul)
+			pos += 4
+
+		case opCodeTypeInput:
+			if len(inputs) == 0 {
+				return nil, nil, errors.Errorf("Input directive at address %d without input symbol", pos)
+			}
+			err = setParamValue(1, inputs[0], op)
+			inputs = inputs[1:]
+			pos += 2
+
+		case opCodeTypeOutput:
+			var v *intcodeSymExpr
+			if v, err = getParamValue(1, op); err == nil {
+				outputs = append(outputs, v)
+			}
+			pos += 2
+
+		case opCodeTypeJumpIfTrue:
+			err = jumpIf(op, false)
+
+		case opCodeTypeJumpIfFalse:
+			err = jumpIf(op, true)
+
+		case opCodeTypeLessThan:
+			err = binaryOp(op, intcodeSymLess)
+			pos += 4
+
+		case opCodeTypeEquals:
+			err = binaryOp(op, intcodeSymEquals)
+			pos += 4
+
+		case opCodeTypeAdjRelBase:
+			var e *intcodeSymExpr
+			if e, err = getParamValue(1, op); err == nil {
+				var adj int64
+				adj, err = concrete(e, "Relative base adjustment")
+				relativeBase += adj
+			}
+			pos += 2
+
+		case opCodeTypeExit:
+			return mem, outputs, nil
+
+		default:
+			return nil, nil, errors.Errorf("Encountered invalid operation %d (parsed %#v)", rawOp, op)
+
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+type intcodeSolveParams struct {
+	// Intcode program to solve for
+	Code []int64
+	// Context to execute the solver in, defaults to background context
+	Context context.Context
+	// Memory cells and inputs to solve for
+	Symbols []intcodeSymbol
+	// Solve for an output instead of a memory cell
+	TargetOutput bool
+	// Memory address or index of the output to solve for
+	Target int64
+	// Value the target must have
+	Value int64
+}
+
+type intcodeSolveResult struct {
+	// Derived formula for the target, empty when the symbolic execution
+	// was not possible and the input space was searched
+	Formula string
+	// Method used to find the solutions: linear, enumeration or search
+	Method string
+	// Values of the symbols producing the target value, ordered by the
+	// values of the symbols
+	Solutions []map[string]int64
+}
+
+// solveIntcode finds all values of the symbols within their ranges
+// resulting in the target value. Linear formulas are solved directly,
+// other formulas are evaluated for all values of the symbols and if
+// the program cannot be executed symbolically all values are searched
+// by executing the program.
+func solveIntcode(params intcodeSolveParams) (intcodeSolveResult, error) {
+	var (
+		order []string
+		res   intcodeSolveResult
+		seen  = map[string]bool{}
+	)
+
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
+
+	for _, s := range params.Symbols {
+		if seen[s.Name] {
+			return res, errors.Errorf("Duplicate symbol %q", s.Name)
+		}
+		if s.Max < s.Min {
+			return res, errors.Errorf("Symbol %q has empty range %d..%d", s.Name, s.Min, s.Max)
+		}
+		seen[s.Name] = true
+		order = append(order, s.Name)
+	}
+
+	mem, outputs, err := executeIntcodeSymbolic(params.Context, params.Code, params.Symbols)
+	switch {
+	case errors.Cause(err) == errIntcodeSymbolicDependency:
+		return solveIntcodeBySearch(params)
+	case err != nil:
+		return res, errors.Wrap(err, "Symbolic execution failed")
+	}
+
+	var expr *intcodeSymExpr
+	if params.TargetOutput {
+		if params.Target < 0 || params.Target >= int64(len(outputs)) {
+			return res, errors.Errorf("Program wrote %d outputs, output %d requested", len(outputs), params.Target)
+		}
+		expr = outputs[params.Target]
+		res.Formula = fmt.Sprintf("output[%d] = ", params.Target)
+	} else {
+		if params.Target < 0 {
+			return res, errors.Errorf("Invalid target address %d", params.Target)
+		}
+		expr = newIntcodeSymConst(0)
+		if params.Target < int64(len(mem)) {
+			expr = mem[params.Target]
+		}
+		res.Formula = fmt.Sprintf("code[%d] = ", params.Target)
+	}
+
+	if expr.hasLoad() {
+		return solveIntcodeBySearch(params)
+	}
+
+	lin, ok := expr.linear()
+	if !ok {
+		res.Formula += expr.String()
+		res.Method = "enumeration"
+		enumerateIntcodeSymbols(params.Symbols, nil, func(vars map[string]int64) {
+			if expr.eval(vars) == params.Value {
+				res.Solutions = append(res.Solutions, vars)
+			}
+		})
+		return res, nil
+	}
+
+	res.Formula += lin.format(order)
+	res.Method = "linear"
+
+	// Solve for the last symbol with a coefficient and enumerate all others
+	var (
+		free   []intcodeSymbol
+		solved *intcodeSymbol
+	)
+	for i := range params.Symbols {
+		if lin.Coeffs[params.Symbols[i].Name] != 0 {
+			solved = &params.Symbols[i]
+		}
+	}
+	for _, s := range params.Symbols {
+		if solved == nil || s.Name != solved.Name {
+			free = append(free, s)
+		}
+	}
+
+	enumerateIntcodeSymbols(free, nil, func(vars map[string]int64) {
+		var rest = params.Value - lin.Const
+		for n, v := range vars {
+			rest -= lin.Coeffs[n] * v
+		}
+
+		if solved == nil {
+			if rest == 0 {
+				res.Solutions = append(res.Solutions, vars)
+			}
+			return
+		}
+
+		c := lin.Coeffs[solved.Name]
+		if rest%c != 0 || rest/c < solved.Min || rest/c > solved.Max {
+			return
+		}
+
+		vars[solved.Name] = rest / c
+		res.Solutions = append(res.Solutions, vars)
+	})
+
+	return res, nil
+}
+
+// solveIntcodeBySearch executes the program for all values of the
+// symbols in parallel
+func solveIntcodeBySearch(params intcodeSolveParams) (intcodeSolveResult, error) {
+	var res = intcodeSolveResult{Method: "search"}
+
+	matches, _, err := searchIntcode(intcodeSearchParams{
+		Code:    params.Code,
+		Context: params.Context,
+		Candidates: func(emit func(intcodeSearchCandidate) bool) {
+			var stop bool
+			enumerateIntcodeSymbols(params.Symbols, func() bool { return stop }, func(vars map[string]int64) {
+				var c = intcodeSearchCandidate{Patches: map[int64]int64{}}
+				for _, s := range params.Symbols {
+					if s.Input {
+						c.Inputs = append(c.Inputs, vars[s.Name])
+					} else {
+						c.Patches[s.Addr] = vars[s.Name]
+					}
+				}
+				stop = !emit(c)
+			})
+		},
+		Match: func(r intcodeSearchResult) bool {
+			if params.TargetOutput {
+				return params.Target >= 0 && params.Target < int64(len(r.Outputs)) && r.Outputs[params.Target] == params.Value
+			}
+			return params.Target >= 0 && params.Target < int64(len(r.Memory)) && r.Memory[params.Target] == params.Value
+		},
+		FindAll: true,
+	})
+	if err != nil {
+		return res, errors.Wrap(err, "Unable to search inputs")
+	}
+
+	for _, m := range matches {
+		var (
+			inputs = m.Candidate.Inputs
+			vars   = map[string]int64{}
+		)
+
+		for _, s := range params.Symbols {
+			if s.Input {
+				vars[s.Name], inputs = inputs[0], inputs[1:]
+			} else {
+				vars[s.Name] = m.Candidate.Patches[s.Addr]
+			}
+		}
+		res.Solutions = append(res.Solutions, vars)
+	}
+
+	return res, nil
+}
+
+// enumerateIntcodeSymbols calls fn with every combination of values of
+// the symbols in ascending order until stop returns true. The map passed
+// to fn is not reused.
+func enumerateIntcodeSymbols(symbols []intcodeSymbol, stop func() bool, fn func(map[string]int64)) {
+	var walk func(n int, vars map[string]int64) bool
+
+	walk = func(n int, vars map[string]int64) bool {
+		if stop != nil && stop() {
+			return false
+		}
+
+		if n == len(symbols) {
+			var tmp = make(map[string]int64, len(vars)+1)
+			for k, v := range vars {
+				tmp[k] = v
+			}
+			fn(tmp)
+			return true
+		}
+
+		for v := symbols[n].Min; v <= symbols[n].Max; v++ {
+			vars[symbols[n].Name] = v
+			if !walk(n+1, vars) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	walk(0, map[string]int64{})
+}
@@ -0,0 +1,88 @@
+package aoc2019
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSolveIntcode(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Code     string
+		Params   intcodeSolveParams
+		Formula  string
+		Method   string
+		Solution []map[string]int64
+	}{
+		"linear memory": {
+			// code[0] = a * 3 + b + 2
+			Code: "1002,14,3,15,1,15,13,0,1001,0,2,0,99,0,0,0",
+			Params: intcodeSolveParams{
+				Symbols: []intcodeSymbol{
+					{Name: "a", Addr: 14, Min: 0, Max: 9},
+					{Name: "b", Addr: 13, Min: 0, Max: 9},
+				},
+				Value: 14,
+			},
+			Formula:  "code[0] = 3*a + b + 2",
+			Method:   "linear",
+			Solution: []map[string]int64{{"a": 1, "b": 9}, {"a": 2, "b": 6}, {"a": 3, "b": 3}, {"a": 4, "b": 0}},
+		},
+		"linear input": {
+			// output = x * 5
+			Code: "3,9,1002,9,5,9,4,9,99,0",
+			Params: intcodeSolveParams{
+				Symbols:      []intcodeSymbol{{Name: "x", Input: true, Min: -5, Max: 5}},
+				TargetOutput: true,
+				Value:        -10,
+			},
+			Formula:  "output[0] = 5*x",
+			Method:   "linear",
+			Solution: []map[string]int64{{"x": -2}},
+		},
+		"product": {
+			// code[0] = a * b
+			Code: "2,5,6,0,99,0,0",
+			Params: intcodeSolveParams{
+				Symbols: []intcodeSymbol{
+					{Name: "a", Addr: 5, Min: 1, Max: 6},
+					{Name: "b", Addr: 6, Min: 1, Max: 6},
+				},
+				Value: 12,
+			},
+			Formula:  "code[0] = (a * b)",
+			Method:   "enumeration",
+			Solution: []map[string]int64{{"a": 2, "b": 6}, {"a": 3, "b": 4}, {"a": 4, "b": 3}, {"a": 6, "b": 2}},
+		},
+		"symbolic jump": {
+			// Outputs -1 for a zero input, the doubled input otherwise
+			Code: "3,15,1005,15,7,104,-1,1002,15,2,15,4,15,99,0,0",
+			Params: intcodeSolveParams{
+				Symbols:      []intcodeSymbol{{Name: "x", Input: true, Min: 0, Max: 9}},
+				TargetOutput: true,
+				Value:        8,
+			},
+			Method:   "search",
+			Solution: []map[string]int64{{"x": 4}},
+		},
+	} {
+		code, err := ParseIntcode(tc.Code)
+		if err != nil {
+			t.Fatalf("Parsing Intcode failed: %s", err)
+		}
+
+		tc.Params.Code = code
+		res, err := solveIntcode(tc.Params)
+		if err != nil {
+			t.Errorf("Solving %q failed: %s", name, err)
+			continue
+		}
+
+		if res.Formula != tc.Formula || res.Method != tc.Method {
+			t.Errorf("Unexpected formula for %q: exp=%q (%s) got=%q (%s)", name, tc.Formula, tc.Method, res.Formula, res.Method)
+		}
+
+		if !reflect.DeepEqual(res.Solutions, tc.Solution) {
+			t.Errorf("Unexpected solutions for %q: exp=%v got=%v", name, tc.Solution, res.Solutions)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package aoc2019
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const intcodeTaintMaxLabels = 64
+
+// intcodeTaint is the set of labels a value was influenced by: bit n
+// refers to the n-th label registered in the tracker
+type intcodeTaint uint64
+
+// IntcodeTaintedOutput is an output of the program together with the
+// labels its value depends on
+type IntcodeTaintedOutput struct {
+	Value  int64
+	Labels []string
+}
+
+// IntcodeTaintTracker keeps a shadow memory holding the labels every
+// memory cell depends on. It is attached to a machine through its Taint
+// field. Labels are assigned to inputs and initial memory cells and
+// spread through arithmetic, comparisons and memory writes including
+// the addresses used to read or write a value.
+// Control flow (jumps) and relative base adjustments are not tracked.
+type IntcodeTaintTracker struct {
+	// Name for the n-th input of the program, defaults to input[n]
+	InputLabel func(n int) string
+
+	inputs  int
+	labels  []string
+	outputs []IntcodeTaintedOutput
+	shadow  []intcodeTaint
+}
+
+// NewIntcodeTaintTracker creates a tracker labelling the inputs as
+// input[n]
+func NewIntcodeTaintTracker() *IntcodeTaintTracker {
+	return &IntcodeTaintTracker{
+		InputLabel: func(n int) string { return fmt.Sprintf("input[%d]", n) },
+	}
+}
+
+// LabelMemory marks the initial value of a memory cell with a label,
+// must be called before the program runs
+func (i *IntcodeTaintTracker) LabelMemory(addr int64, label string) error {
+	t, err := i.register(label)
+	if err != nil {
+		return err
+	}
+
+	i.grow(addr + 1)
+	i.shadow[addr] |= t
+	return nil
+}
+
+// MemoryLabels returns the labels the current value of the memory cell
+// depends on sorted by name
+func (i *IntcodeTaintTracker) MemoryLabels(addr int64) []string {
+	if addr >= int64(len(i.shadow)) {
+		return nil
+	}
+	return i.names(i.shadow[addr])
+}
+
+// TaintedOutputs returns all outputs with the labels they depend on
+func (i *IntcodeTaintTracker) TaintedOutputs() []IntcodeTaintedOutput { return i.outputs }
+
+func (i *IntcodeTaintTracker) grow(size int64) {
+	if size <= int64(len(i.shadow)) {
+		return
+	}
+
+	var tmp = make([]intcodeTaint, size)
+	copy(tmp, i.shadow)
+	i.shadow = tmp
+}
+
+// input labels the destination of an input directive after the value
+// was read
+func (i *IntcodeTaintTracker) input(m *IntcodeMachine, op opCode) error {
+	t, err := i.register(i.InputLabel(i.inputs))
+	if err != nil {
+		return err
+	}
+	i.inputs++
+
+	i.write(m, 1, op, t)
+	return nil
+}
+
+// names returns the names of the labels in the set sorted by name to
+// not depend on the order the labels were registered in
+func (i *IntcodeTaintTracker) names(t intcodeTaint) []string {
+	var out []string
+	for n, l := range i.labels {
+		if t&(1<<uint(n)) != 0 {
+			out = append(out, l)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// output records the labels of an output value
+func (i *IntcodeTaintTracker) output(m *IntcodeMachine, op opCode, v int64) {
+	i.outputs = append(i.outputs, IntcodeTaintedOutput{
+		Value:  v,
+		Labels: i.names(i.read(m, 1, op)),
+	})
+}
+
+// propagate spreads the labels of both operands of an arithmetic or
+// comparison directive to its destination, must be called before the
+// directive is executed
+func (i *IntcodeTaintTracker) propagate(m *IntcodeMachine, op opCode) {
+	i.write(m, 3, op, i.read(m, 1, op)|i.read(m, 2, op))
+}
+
+// read returns the labels of a parameter value: the value itself and
+// the address it was read from
+func (i *IntcodeTaintTracker) read(m *IntcodeMachine, param int64, op opCode) intcodeTaint {
+	i.grow(int64(len(m.memory)))
+
+	var addr = m.transformPos(param, op, false)
+	if addr >= int64(len(i.shadow)) {
+		return i.shadow[m.pos+param]
+	}
+
+	return i.shadow[m.pos+param] | i.shadow[addr]
+}
+
+func (i *IntcodeTaintTracker) register(label string) (intcodeTaint, error) {
+	for n, l := range i.labels {
+		if l == label {
+			return 1 << uint(n), nil
+		}
+	}
+
+	if len(i.labels) == intcodeTaintMaxLabels {
+		return 0, errors.Errorf("Unable to register label %q: only %d labels supported", label, intcodeTaintMaxLabels)
+	}
+
+	i.labels = append(i.labels, label)
+	return 1 << uint(len(i.labels)-1), nil
+}
+
+// write sets the labels of the destination parameter including the
+// labels of the address written to
+func (i *IntcodeTaintTracker) write(m *IntcodeMachine, param int64, op opCode, t intcodeTaint) {
+	i.grow(int64(len(m.memory)))
+
+	var addr = m.transformPos(param, op, false)
+	t |= i.shadow[m.pos+param]
+
+	i.grow(addr + 1)
+	i.shadow[addr] = t
+}
@@ -0,0 +1,79 @@
+package aoc2019
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIntcodeTaintTracking(t *testing.T) {
+	// a = in, b = in, c = in (unused)
+	// out a * b, out 5 + 7, out a < b, out c
+	code, _ := ParseIntcode("3,30,3,31,3,32,2,30,31,33,1101,5,7,34,7,30,31,35,4,33,4,34,4,35,4,32,99,0,0,0,0,0,0,0,0,0")
+
+	m := NewIntcodeMachine(code)
+	m.Taint = NewIntcodeTaintTracker()
+	m.Taint.InputLabel = func(n int) string { return []string{"a", "b", "c"}[n] }
+	m.Feed(3, 4, 5)
+
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	exp := []IntcodeTaintedOutput{
+		{Value: 12, Labels: []string{"a", "b"}},
+		{Value: 12, Labels: nil},
+		{Value: 1, Labels: []string{"a", "b"}},
+		{Value: 5, Labels: []string{"c"}},
+	}
+
+	if out := m.Taint.TaintedOutputs(); !reflect.DeepEqual(out, exp) {
+		t.Errorf("Unexpected tainted outputs: exp=%+v got=%+v", exp, out)
+	}
+}
+
+func TestIntcodeTaintTrackingAddress(t *testing.T) {
+	// Value read through a labeled address depends on the address
+	code, _ := ParseIntcode("1,9,10,0,4,0,99,0,0,7,8")
+
+	m := NewIntcodeMachine(code)
+	m.Taint = NewIntcodeTaintTracker()
+	if err := m.Taint.LabelMemory(9, "ptr"); err != nil {
+		t.Fatalf("Unable to label memory: %s", err)
+	}
+
+	if err := m.Run(context.Background(), nil, func(int64) error { return nil }); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if l := m.Taint.MemoryLabels(0); !reflect.DeepEqual(l, []string{"ptr"}) {
+		t.Errorf("Unexpected labels for address 0: %v", l)
+	}
+
+	if l := m.Taint.MemoryLabels(10); l != nil {
+		t.Errorf("Unexpected labels for untouched address 10: %v", l)
+	}
+}
+
+func TestIntcodeTaintLabelOrder(t *testing.T) {
+	// code[0] = code[5] + code[6]
+	code, _ := ParseIntcode("1,5,6,0,99,1,2")
+
+	m := NewIntcodeMachine(code)
+	m.Taint = NewIntcodeTaintTracker()
+	// Map order decides the registration order, labels are reported
+	// sorted by name regardless
+	for addr, label := range map[int64]string{6: "a", 5: "z"} {
+		if err := m.Taint.LabelMemory(addr, label); err != nil {
+			t.Fatalf("Unable to label memory: %s", err)
+		}
+	}
+
+	if err := m.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Intcode execution failed: %s", err)
+	}
+
+	if l := m.Taint.MemoryLabels(0); !reflect.DeepEqual(l, []string{"a", "z"}) {
+		t.Errorf("Unexpected labels for address 0: %v", l)
+	}
+}
@@ -17,7 +17,7 @@ func TestParseOpCode(t *testing.T) {
 }
 
 func TestExecuteIntcodeIO(t *testing.T) {
-	code, _ := parseIntcode("3,0,4,0,99")
+	code, _ := ParseIntcode("3,0,4,0,99")
 
 	var (
 		exp int64 = 25
@@ -41,7 +41,7 @@ func TestExecuteIntcodeImmediateFlag(t *testing.T) {
 	// 4,0       = Output pos_0
 	// 99        = Exit
 	// 3         = pos_7
-	code, _ := parseIntcode("102,4,7,0,4,0,99,3")
+	code, _ := ParseIntcode("102,4,7,0,4,0,99,3")
 
 	var (
 		exp int64 = 12
@@ -74,7 +74,7 @@ func TestExecuteIntcodeEquals(t *testing.T) {
 				out = make(chan int64, 10)
 			)
 
-			code, _ := parseIntcode(codeStr)
+			code, _ := ParseIntcode(codeStr)
 			in <- input
 
 			if _, err := executeIntcode(code, in, out); err != nil {
@@ -107,7 +107,7 @@ func TestExecuteIntcodeLessThan(t *testing.T) {
 				out = make(chan int64, 10)
 			)
 
-			code, _ := parseIntcode(codeStr)
+			code, _ := ParseIntcode(codeStr)
 			in <- input
 
 			if _, err := executeIntcode(code, in, out); err != nil {
@@ -138,7 +138,7 @@ func TestExecuteIntcodeJump(t *testing.T) {
 				out = make(chan int64, 10)
 			)
 
-			code, _ := parseIntcode(codeStr)
+			code, _ := ParseIntcode(codeStr)
 			in <- input
 
 			if _, err := executeIntcode(code, in, out); err != nil {
@@ -155,7 +155,9 @@ func TestExecuteIntcodeJump(t *testing.T) {
 }
 
 func TestExecuteIntcodeRelativeBase(t *testing.T) {
-	code, _ := parseIntcode("109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99")
+	defer checkGoroutineLeaks(t)()
+
+	code, _ := ParseIntcode("109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99")
 
 	var (
 		codeCopy []int64
@@ -164,7 +166,7 @@ func TestExecuteIntcodeRelativeBase(t *testing.T) {
 
 	go func() {
 		if _, err := executeIntcode(code, nil, out); err != nil {
-			t.Fatalf("Intcode execution failed: %s", err)
+			t.Errorf("Intcode execution failed: %s", err)
 		}
 	}()
 
@@ -182,7 +184,7 @@ func TestExecuteIntcodeLargeNumber(t *testing.T) {
 		"1102,34915192,34915192,7,4,7,99,0": 1219070632396864,
 		"104,1125899906842624,99":           1125899906842624,
 	} {
-		code, _ := parseIntcode(codeStr)
+		code, _ := ParseIntcode(codeStr)
 		var out = make(chan int64, 1)
 
 		if _, err := executeIntcode(code, nil, out); err != nil {
@@ -0,0 +1,203 @@
+package aoc2019
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AnswerKind describes which field of an Answer is set
+type AnswerKind int
+
+const (
+	// AnswerNumber is an integer stored in Number
+	AnswerNumber AnswerKind = iota
+	// AnswerText is a string stored in Text
+	AnswerText
+	// AnswerImage is a drawing stored in Image
+	AnswerImage
+)
+
+// Answer is the result of one part of a day
+type Answer struct {
+	Kind   AnswerKind
+	Number int64
+	Text   string
+	// Image answers are letters drawn by light pixels
+	Image image.Image
+}
+
+// NumberAnswer creates an answer holding an integer
+func NumberAnswer(v int64) Answer { return Answer{Kind: AnswerNumber, Number: v} }
+
+// TextAnswer creates an answer holding a string
+func TextAnswer(s string) Answer { return Answer{Kind: AnswerText, Text: s} }
+
+// ImageAnswer creates an answer holding a drawing of letters
+func ImageAnswer(img image.Image) Answer { return Answer{Kind: AnswerImage, Image: img} }
+
+// String formats the answer for output, images are drawn with '#' for
+// light and ' ' for dark or transparent pixels, one line per row and
+// cropped to the light pixels
+func (a Answer) String() string {
+	switch a.Kind {
+	case AnswerText:
+		return a.Text
+
+	case AnswerImage:
+		var (
+			b     = a.Image.Bounds()
+			lines []string
+		)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			var line []byte
+			for x := b.Min.X; x < b.Max.X; x++ {
+				var (
+					_, _, _, alpha = a.Image.At(x, y).RGBA()
+					gray           = color.GrayModel.Convert(a.Image.At(x, y)).(color.Gray)
+				)
+				if alpha > 0 && gray.Y >= 0x80 {
+					line = append(line, '#')
+				} else {
+					line = append(line, ' ')
+				}
+			}
+			lines = append(lines, strings.TrimRight(string(line), " "))
+		}
+		return cropAnswerLines(lines)
+	}
+
+	return fmt.Sprintf("%d", a.Number)
+}
+
+// cropAnswerLines removes empty lines around the drawing and the indent
+// common to all lines
+func cropAnswerLines(lines []string) string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var indent = -1
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+		if n := len(l) - len(strings.TrimLeft(l, " ")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = l[indent:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Solver solves both parts of a day from its puzzle input
+type Solver interface {
+	Part1(r io.Reader) (Answer, error)
+	Part2(r io.Reader) (Answer, error)
+}
+
+// solverParts implements Solver through one function per part
+type solverParts struct {
+	part1, part2 func(r io.Reader) (Answer, error)
+}
+
+func (s solverParts) Part1(r io.Reader) (Answer, error) { return s.part1(r) }
+func (s solverParts) Part2(r io.Reader) (Answer, error) { return s.part2(r) }
+
+func intPart(fn func(io.Reader) (int, error)) func(io.Reader) (Answer, error) {
+	return func(r io.Reader) (Answer, error) {
+		v, err := fn(r)
+		return NumberAnswer(int64(v)), err
+	}
+}
+
+func int64Part(fn func(io.Reader) (int64, error)) func(io.Reader) (Answer, error) {
+	return func(r io.Reader) (Answer, error) {
+		v, err := fn(r)
+		return NumberAnswer(v), err
+	}
+}
+
+func textPart(fn func(io.Reader) (string, error)) func(io.Reader) (Answer, error) {
+	return func(r io.Reader) (Answer, error) {
+		s, err := fn(r)
+		return TextAnswer(s), err
+	}
+}
+
+func imagePart(fn func(io.Reader) (image.Image, error)) func(io.Reader) (Answer, error) {
+	return func(r io.Reader) (Answer, error) {
+		img, err := fn(r)
+		return ImageAnswer(img), err
+	}
+}
+
+var solvers = map[int]Solver{}
+
+// registerSolver adds the solver of a day to the registry, days are
+// registered from the init function of their file
+func registerSolver(day int, s Solver) {
+	if _, ok := solvers[day]; ok {
+		panic(fmt.Sprintf("Solver for day %d registered twice", day))
+	}
+	solvers[day] = s
+}
+
+// GetSolver returns the solver registered for the day
+func GetSolver(day int) (Solver, bool) {
+	s, ok := solvers[day]
+	return s, ok
+}
+
+// SolvedDays returns the days having a solver in ascending order
+func SolvedDays() []int {
+	var days []int
+	for day := range solvers {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+	return days
+}
+
+// InputFile returns the name of the puzzle input of the day shipped
+// with the repository
+func InputFile(day int) string { return fmt.Sprintf("day%02d_input.txt", day) }
+
+// Solve runs the solver for the part (1 or 2) of the day on the puzzle
+// input
+func Solve(day, part int, r io.Reader) (Answer, error) {
+	s, ok := solvers[day]
+	if !ok {
+		return Answer{}, errors.Errorf("No solver for day %d", day)
+	}
+
+	var (
+		answer Answer
+		err    error
+	)
+
+	switch part {
+	case 1:
+		answer, err = s.Part1(r)
+	case 2:
+		answer, err = s.Part2(r)
+	default:
+		return Answer{}, errors.Errorf("Invalid part %d", part)
+	}
+
+	return answer, errors.Wrapf(err, "Day %d part %d failed", day, part)
+}
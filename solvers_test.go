@@ -0,0 +1,70 @@
+package aoc2019
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSolve(t *testing.T) {
+	answer, err := Solve(1, 2, readInput(t, InputFile(1)))
+	if err != nil {
+		t.Fatalf("Solver failed: %s", err)
+	}
+
+	if answer != NumberAnswer(4687331) {
+		t.Errorf("Unexpected answer for day 1 part 2: %#v", answer)
+	}
+
+	if _, err = Solve(18, 1, strings.NewReader("")); err == nil {
+		t.Error("Solving unknown day did not fail")
+	}
+
+	if _, err = Solve(1, 3, strings.NewReader("12")); err == nil {
+		t.Error("Solving unknown part did not fail")
+	}
+
+	if _, err = Solve(2, 1, strings.NewReader("1,0,0,foo")); err == nil {
+		t.Error("Solving invalid input did not fail")
+	}
+}
+
+func TestSolversRegistered(t *testing.T) {
+	inputs, err := filepath.Glob("day*_input.txt")
+	if err != nil {
+		t.Fatalf("Unable to list inputs: %s", err)
+	}
+
+	var days = SolvedDays()
+	if len(days) != len(inputs) {
+		t.Errorf("Registered days %v do not match inputs %v", days, inputs)
+	}
+
+	for _, day := range days {
+		if _, err := os.Stat(InputFile(day)); err != nil {
+			t.Errorf("Day %d has no input: %s", day, err)
+		}
+	}
+}
+
+func TestAnswerString(t *testing.T) {
+	img := image.NewRGBA(image.Rect(-2, 0, 3, 4))
+	img.Set(-1, 1, color.White)
+	img.Set(1, 1, color.White)
+	img.Set(0, 2, color.Black)
+	img.Set(1, 2, color.White)
+
+	for exp, a := range map[string]Answer{
+		"42":       NumberAnswer(42),
+		"-7":       NumberAnswer(-7),
+		"01234567": TextAnswer("01234567"),
+		"# #\n  #": ImageAnswer(img),
+	} {
+		if s := a.String(); s != exp {
+			t.Errorf("Unexpected string for %#v: exp=%q got=%q", a, exp, s)
+		}
+	}
+}